@@ -4,6 +4,7 @@ import (
 	"log"
 
 	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
 	"github.com/hugolol/gamblefights/pkg/models"
 )
 
@@ -14,10 +15,38 @@ func main() {
 	err := db.DB.AutoMigrate(
 		&models.User{},
 		&models.Wallet{},
+		&models.Match{},
+		&models.Transaction{},
+		&models.ServerSeed{},
+		&models.MerkleRoot{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.IdempotencyRecord{},
+		&models.ChainCursor{},
+		&models.Withdrawal{},
+		&models.TreasuryProposal{},
 	)
 	if err != nil {
 		log.Fatal("Migration failed:", err)
 	}
 
+	// Matches from before seed pairs were committed were never sealed
+	if err := db.RevealLegacySeeds(); err != nil {
+		log.Fatal("Backfill of legacy seeds failed:", err)
+	}
+
+	// Test fights from before the flag have the player on both sides
+	if err := db.FlagLegacyTestFights(); err != nil {
+		log.Fatal("Backfill of test fights failed:", err)
+	}
+
+	// Balances from before the ledger need postings to reconcile
+	opened, err := ledger.OpenLegacyBalances()
+	if err != nil {
+		log.Fatal("Backfill of opening balances failed:", err)
+	}
+	log.Printf("Opened %d legacy wallet balances in the ledger", opened)
+
 	log.Println("✅ Migrations completed successfully")
 }
@@ -0,0 +1,117 @@
+// Command seedkeys manages the master keys that seal unrevealed server seeds.
+//
+//	go run ./cmd/seedkeys generate <id>   print a new id:base64key entry
+//	go run ./cmd/seedkeys rewrap          re-wrap every unrevealed seed under the primary key
+//
+// To rotate, prepend the new entry to SEED_MASTER_KEYS (keeping the old one),
+// deploy, run rewrap, then drop the old entry.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gorm.io/gorm"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/seedvault"
+)
+
+const batchSize = 500
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "generate":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		key, err := seedvault.GenerateKey()
+		if err != nil {
+			log.Fatal("Failed to generate key:", err)
+		}
+		fmt.Printf("%s:%s\n", os.Args[2], key)
+	case "rewrap":
+		rewrap()
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: seedkeys generate <id> | seedkeys rewrap")
+	os.Exit(2)
+}
+
+func rewrap() {
+	loaded, err := seedvault.LoadFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load seed master keys:", err)
+	}
+	if !loaded {
+		log.Fatal("SEED_MASTER_KEYS or SEED_MASTER_KEY_FILE must be set")
+	}
+	db.Connect()
+
+	// Legacy matches hold plaintext seeds that are already public
+	if err := db.RevealLegacySeeds(); err != nil {
+		log.Fatal("Failed to mark legacy seeds revealed:", err)
+	}
+
+	log.Printf("Re-wrapping unrevealed seeds under key %q...", seedvault.Default().Primary())
+
+	var seedCount int
+	var seedRows []models.ServerSeed
+	err = db.DB.Where("status <> ?", models.SeedStatusRevealed).
+		FindInBatches(&seedRows, batchSize, func(tx *gorm.DB, batch int) error {
+			for _, s := range seedRows {
+				value, changed, err := seedvault.Rewrap(s.Seed)
+				if err != nil {
+					return fmt.Errorf("seed %d: %w", s.ID, err)
+				}
+				if !changed {
+					continue
+				}
+				if err := db.DB.Model(&s).Update("seed", value).Error; err != nil {
+					return err
+				}
+				seedCount++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		log.Fatal("Failed to re-wrap server seeds:", err)
+	}
+
+	var matchCount int
+	var matchRows []models.Match
+	// Only sealed match seeds: a plaintext one is public and must stay so
+	err = db.DB.Select("id", "server_seed").
+		Where("seed_revealed = ? AND server_seed LIKE ?", false, seedvault.Prefix+"%").
+		FindInBatches(&matchRows, batchSize, func(tx *gorm.DB, batch int) error {
+			for _, m := range matchRows {
+				value, changed, err := seedvault.Rewrap(m.ServerSeed)
+				if err != nil {
+					return fmt.Errorf("match %s: %w", m.ID, err)
+				}
+				if !changed {
+					continue
+				}
+				if err := db.DB.Model(&m).Update("server_seed", value).Error; err != nil {
+					return err
+				}
+				matchCount++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		log.Fatal("Failed to re-wrap match seeds:", err)
+	}
+
+	log.Printf("✅ Re-wrapped %d server seeds and %d match seeds", seedCount, matchCount)
+}
@@ -0,0 +1,255 @@
+// Command verify re-checks a GambleFights match offline.
+//
+// It accepts either the JSON returned by GET /api/matches/:id or a portable
+// proof bundle (see fairness.Proof), and prints a pass/fail line per check:
+//
+//	go run ./cmd/verify match.json
+//	curl -s .../api/matches/<id> | go run ./cmd/verify -
+//
+// With -receipt-key, the signed settlement receipt in a match export is
+// checked against a public key from /.well-known/gamblefights-keys.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/hugolol/gamblefights/pkg/fairness"
+	"github.com/hugolol/gamblefights/pkg/receipt"
+)
+
+// matchExport mirrors the fields of handlers.MatchResponse needed to verify
+type matchExport struct {
+	ID               string          `json:"id"`
+	PlayerA          string          `json:"playerA"`
+	PlayerB          string          `json:"playerB"`
+	IsTest           bool            `json:"isTest"`
+	Winner           *string         `json:"winner"`
+	Payout           int64           `json:"payout"`
+	ServerSeed       string          `json:"serverSeed"`
+	ServerSeedHashed string          `json:"serverSeedHashed"`
+	ClientSeedA      string          `json:"clientSeedA"`
+	ClientSeedB      string          `json:"clientSeedB"`
+	Nonce            int64           `json:"nonce"`
+	FairnessVersion  int             `json:"fairnessVersion"`
+	BeaconRound      uint64          `json:"beaconRound"`
+	BeaconRandomness string          `json:"beaconRandomness"`
+	FightScript      *string         `json:"fightScript"`
+	Receipt          *receipt.Signed `json:"receipt"`
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	receiptKey := flag.String("receipt-key", "", "base58 public key to check the settlement receipt against")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: verify [-json] [-receipt-key key] <match.json | proof.json | ->\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := readInput(flag.Arg(0))
+	if err != nil {
+		log.Fatal("Failed to read input:", err)
+	}
+
+	proof, export, err := parseProof(data)
+	if err != nil {
+		log.Fatal("Failed to parse input:", err)
+	}
+
+	report := fairness.Verify(proof)
+	if export != nil && report.DerivedWinner != "" {
+		report.Checks = append(report.Checks, checkRecordedWinner(report, export))
+		report.Passed = report.Passed && report.Checks[len(report.Checks)-1].Passed
+	}
+	if *receiptKey != "" {
+		report.Checks = append(report.Checks, checkReceipt(*receiptKey, proof, export))
+		report.Passed = report.Passed && report.Checks[len(report.Checks)-1].Passed
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printReport(report)
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// parseProof accepts either a proof bundle or a match export, returned too
+// when given. Only match exports carry a signed receipt.
+func parseProof(data []byte) (fairness.Proof, *matchExport, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return fairness.Proof{}, nil, err
+	}
+
+	if _, ok := probe["matchId"]; ok {
+		var proof fairness.Proof
+		err := json.Unmarshal(data, &proof)
+		return proof, nil, err
+	}
+
+	var m matchExport
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fairness.Proof{}, nil, err
+	}
+	if m.ID == "" {
+		return fairness.Proof{}, nil, fmt.Errorf("input is neither a match export nor a proof bundle")
+	}
+
+	proof := fairness.Proof{
+		MatchID:          m.ID,
+		FairnessVersion:  m.FairnessVersion,
+		ServerSeed:       m.ServerSeed,
+		ServerSeedHashed: m.ServerSeedHashed,
+		ClientSeedA:      m.ClientSeedA,
+		ClientSeedB:      m.ClientSeedB,
+		Nonce:            m.Nonce,
+		BeaconRound:      m.BeaconRound,
+		BeaconRandomness: m.BeaconRandomness,
+	}
+	if proof.FairnessVersion == 0 {
+		proof.FairnessVersion = 1 // Exports from before versioning
+	}
+
+	if m.FightScript != nil && *m.FightScript != "" {
+		proof.FightScript = json.RawMessage(*m.FightScript)
+	}
+	proof.Winner, _ = recordedSide(&m)
+
+	return proof, &m, nil
+}
+
+// recordedSide maps the export's winner user ID to the side that won. In a
+// test fight the player is playerA and any other winner is the bot, which
+// has no user, as playerB.
+func recordedSide(m *matchExport) (string, string) {
+	switch {
+	case m.Winner == nil:
+		return "", "match has no recorded winner"
+	case m.IsTest && *m.Winner == m.PlayerA:
+		return "playerA", fmt.Sprintf("test fight won by the player %s", *m.Winner)
+	case m.IsTest:
+		return "playerB", fmt.Sprintf("test fight won by the bot %s", *m.Winner)
+	case *m.Winner == m.PlayerA:
+		return "playerA", fmt.Sprintf("winner %s is player A", *m.Winner)
+	case *m.Winner == m.PlayerB:
+		return "playerB", fmt.Sprintf("winner %s is player B", *m.Winner)
+	}
+	return "", fmt.Sprintf("winner %s is neither player", *m.Winner)
+}
+
+// checkRecordedWinner compares the winner the match records with the one
+// its seeds derive
+func checkRecordedWinner(r fairness.Report, m *matchExport) fairness.Check {
+	side, detail := recordedSide(m)
+	check := fairness.Check{Name: "recorded winner", Passed: side != "" && side == r.DerivedWinner}
+	if r.DerivedWinner == "" {
+		check.Detail = detail + "; no winner derived"
+		return check
+	}
+	check.Detail = fmt.Sprintf("%s, derived %s", detail, r.DerivedWinner)
+	return check
+}
+
+// checkReceipt verifies the receipt signature and that it covers this match
+// with the same winner, payout, seeds, nonce and fairness version
+func checkReceipt(publicKey string, proof fairness.Proof, m *matchExport) fairness.Check {
+	check := fairness.Check{Name: "receipt"}
+	if m == nil || m.Receipt == nil {
+		check.Detail = "input has no signed receipt"
+		return check
+	}
+	signed := *m.Receipt
+	pub, err := base58.Decode(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		check.Detail = "receipt key is not a base58 ed25519 public key"
+		return check
+	}
+	if err := receipt.Verify(pub, signed); err != nil {
+		check.Detail = fmt.Sprintf("signature by key %s does not verify", signed.KeyID)
+		return check
+	}
+
+	var r receipt.Receipt
+	if err := json.Unmarshal(signed.Receipt, &r); err != nil || r.MatchID != proof.MatchID {
+		check.Detail = "signed receipt is for a different match"
+		return check
+	}
+	winner := ""
+	if m.Winner != nil {
+		winner = *m.Winner
+	}
+	for _, f := range []struct {
+		name             string
+		signed, recorded interface{}
+	}{
+		{"winner", r.Winner, winner},
+		{"payout", r.Payout, m.Payout},
+		{"serverSeedHashed", r.ServerSeedHashed, proof.ServerSeedHashed},
+		{"clientSeedA", r.ClientSeedA, proof.ClientSeedA},
+		{"clientSeedB", r.ClientSeedB, proof.ClientSeedB},
+		{"beaconRandomness", r.BeaconRandomness, proof.BeaconRandomness},
+		{"nonce", r.Nonce, proof.Nonce},
+		{"fairnessVersion", r.FairnessVersion, proof.FairnessVersion},
+	} {
+		if f.signed != f.recorded {
+			check.Detail = fmt.Sprintf("signed %s %v does not match the match's %v", f.name, f.signed, f.recorded)
+			return check
+		}
+	}
+	check.Passed = true
+	check.Detail = fmt.Sprintf("signed by key %s: winner %s, payout %d %s", signed.KeyID, r.Winner, r.Payout, r.Currency)
+	return check
+}
+
+func printReport(r fairness.Report) {
+	fmt.Printf("Match %s (fairness v%d)\n", r.MatchID, r.FairnessVersion)
+	fmt.Printf("  message:     %s\n", r.Message)
+	if r.OutcomeHash != "" {
+		fmt.Printf("  hash:        %s\n", r.OutcomeHash)
+		fmt.Printf("  decValue:    %d\n", r.DecValue)
+		fmt.Printf("  winner:      %s\n", r.DerivedWinner)
+	}
+	fmt.Println()
+
+	for _, c := range r.Checks {
+		status := "FAIL"
+		if c.Skipped {
+			status = "SKIP"
+		} else if c.Passed {
+			status = "PASS"
+		}
+		fmt.Printf("  [%s] %-18s %s\n", status, c.Name, c.Detail)
+	}
+	fmt.Println()
+
+	if r.Passed {
+		fmt.Println("Result: PASS")
+	} else {
+		fmt.Println("Result: FAIL")
+	}
+}
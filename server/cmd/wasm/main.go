@@ -0,0 +1,147 @@
+//go:build js && wasm
+
+// Command wasm exposes pkg/fairness to the browser so the web verifier runs
+// exactly the server's derivation code. Build with `make wasm`.
+//
+// The module registers a global `gamblefightsFairness` object whose
+// functions take and return JSON strings:
+//
+//	hashServerSeed(seed)            -> hex string
+//	outcome(inputJSON)              -> {"message","hash","decValue","isPlayerAWin","version"}
+//	fightScript(inputJSON)          -> FightScript JSON
+//	verify(proofJSON)               -> Report JSON
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/hugolol/gamblefights/pkg/fairness"
+)
+
+// input mirrors the JSON accepted by outcome and fightScript
+type input struct {
+	Version     int                 `json:"version"`
+	ServerSeed  string              `json:"serverSeed"`
+	ClientSeedA string              `json:"clientSeedA"`
+	ClientSeedB string              `json:"clientSeedB"`
+	ClientSeed  string              `json:"clientSeed,omitempty"` // Already combined, overrides A/B
+	Nonce       int64               `json:"nonce"`
+	Beacon      string              `json:"beacon,omitempty"`
+	MatchID     string              `json:"matchId,omitempty"`
+	PlayerA     fairness.PlayerInfo `json:"playerA"`
+	PlayerB     fairness.PlayerInfo `json:"playerB"`
+	Winner      string              `json:"winner,omitempty"`
+}
+
+type outcomeResult struct {
+	Version      int    `json:"version"`
+	Message      string `json:"message"`
+	Hash         string `json:"hash"`
+	DecValue     uint64 `json:"decValue"`
+	IsPlayerAWin bool   `json:"isPlayerAWin"`
+}
+
+func main() {
+	js.Global().Set("gamblefightsFairness", js.ValueOf(map[string]interface{}{
+		"version":        fairness.CurrentVersion,
+		"hashServerSeed": js.FuncOf(hashServerSeed),
+		"outcome":        js.FuncOf(jsonFunc(outcome)),
+		"fightScript":    js.FuncOf(jsonFunc(fightScript)),
+		"verify":         js.FuncOf(jsonFunc(verify)),
+	}))
+
+	// Keep the Go runtime alive for callbacks
+	select {}
+}
+
+func hashServerSeed(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return js.ValueOf(map[string]interface{}{"error": "expected 1 argument"})
+	}
+	return fairness.HashServerSeed(args[0].String())
+}
+
+// jsonFunc adapts a JSON-in/JSON-out function to a JS callback. Errors are
+// returned as {"error": "..."} so callers never see a Go panic.
+func jsonFunc(fn func([]byte) (interface{}, error)) func(js.Value, []js.Value) interface{} {
+	return func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return errorJSON("expected 1 argument")
+		}
+		result, err := fn([]byte(args[0].String()))
+		if err != nil {
+			return errorJSON(err.Error())
+		}
+		out, err := json.Marshal(result)
+		if err != nil {
+			return errorJSON(err.Error())
+		}
+		return string(out)
+	}
+}
+
+func errorJSON(message string) string {
+	out, _ := json.Marshal(map[string]string{"error": message})
+	return string(out)
+}
+
+func parseInput(data []byte) (input, fairness.Algorithm, fairness.Input, error) {
+	var in input
+	if err := json.Unmarshal(data, &in); err != nil {
+		return in, fairness.Algorithm{}, fairness.Input{}, err
+	}
+	algo, err := fairness.Lookup(in.Version)
+	if err != nil {
+		return in, fairness.Algorithm{}, fairness.Input{}, err
+	}
+	clientSeed := in.ClientSeed
+	if clientSeed == "" {
+		clientSeed = fairness.CombineClientSeeds(in.ClientSeedA, in.ClientSeedB)
+	}
+	return in, algo, fairness.Input{
+		ServerSeed: in.ServerSeed,
+		ClientSeed: clientSeed,
+		Nonce:      in.Nonce,
+		Beacon:     in.Beacon,
+	}, nil
+}
+
+func outcome(data []byte) (interface{}, error) {
+	_, algo, in, err := parseInput(data)
+	if err != nil {
+		return nil, err
+	}
+	playerAWins, hash := algo.Outcome(in)
+	return outcomeResult{
+		Version:      algo.Version,
+		Message:      algo.Message(in),
+		Hash:         hash,
+		DecValue:     fairness.DecValue(hash),
+		IsPlayerAWin: playerAWins,
+	}, nil
+}
+
+func fightScript(data []byte) (interface{}, error) {
+	raw, algo, in, err := parseInput(data)
+	if err != nil {
+		return nil, err
+	}
+	winner := raw.Winner
+	if winner == "" {
+		playerAWins, _ := algo.Outcome(in)
+		winner = "playerB"
+		if playerAWins {
+			winner = "playerA"
+		}
+	}
+	return algo.FightScript(in, raw.MatchID, raw.PlayerA, raw.PlayerB, winner), nil
+}
+
+func verify(data []byte) (interface{}, error) {
+	var proof fairness.Proof
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return nil, err
+	}
+	return fairness.Verify(proof), nil
+}
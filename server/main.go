@@ -1,18 +1,35 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
+	"github.com/hugolol/gamblefights/pkg/audit"
 	"github.com/hugolol/gamblefights/pkg/auth"
+	"github.com/hugolol/gamblefights/pkg/beacon"
+	"github.com/hugolol/gamblefights/pkg/chain/solana"
+	"github.com/hugolol/gamblefights/pkg/chain/ton"
+	"github.com/hugolol/gamblefights/pkg/currency"
 	"github.com/hugolol/gamblefights/pkg/db"
 	"github.com/hugolol/gamblefights/pkg/game"
 	"github.com/hugolol/gamblefights/pkg/handlers"
+	"github.com/hugolol/gamblefights/pkg/idempotency"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/merkle"
+	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/rake"
+	"github.com/hugolol/gamblefights/pkg/receipt"
+	"github.com/hugolol/gamblefights/pkg/reconcile"
+	"github.com/hugolol/gamblefights/pkg/seedvault"
+	"github.com/hugolol/gamblefights/pkg/treasury"
+	"github.com/hugolol/gamblefights/pkg/withdrawal"
 )
 
 func main() {
@@ -21,6 +38,49 @@ func main() {
 		log.Println("No .env file found, using system env")
 	}
 
+	// Load master keys for sealing unrevealed server seeds
+	loaded, err := seedvault.LoadFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load seed master keys:", err)
+	}
+	if !loaded {
+		log.Println("WARNING: SEED_MASTER_KEYS not set, server seeds are stored unencrypted")
+	}
+
+	// Load the key that signs match settlement receipts
+	if err := receipt.LoadFromEnv(); err != nil {
+		log.Fatal("Failed to load receipt signing key:", err)
+	}
+
+	// Load the master seed for per-user Solana deposit addresses
+	hdLoaded, err := solana.LoadFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load Solana deposit seed:", err)
+	}
+	if !hdLoaded {
+		log.Println("WARNING: SOLANA_DEPOSIT_SEED not set, deposits go to the hot wallet with a memo")
+	}
+
+	// Load currency wager limits and disabled operations
+	if err := currency.LoadFromEnv(); err != nil {
+		log.Fatal("Failed to load currency settings:", err)
+	}
+
+	// Load the house rake schedules
+	if err := rake.LoadFromEnv(); err != nil {
+		log.Fatal("Failed to load rake schedules:", err)
+	}
+
+	// Load withdrawal review thresholds
+	if err := withdrawal.LoadFromEnv(); err != nil {
+		log.Fatal("Failed to load withdrawal review thresholds:", err)
+	}
+
+	// Load the hot wallet target bands
+	if err := treasury.LoadFromEnv(); err != nil {
+		log.Fatal("Failed to load treasury bands:", err)
+	}
+
 	// Connect to Database
 	db.Connect()
 
@@ -31,9 +91,10 @@ func main() {
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"http://localhost:3000", "*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, "Authorization"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+		AllowOrigins:  []string{"http://localhost:3000", "*"},
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, "Authorization", idempotency.HeaderKey},
+		ExposeHeaders: []string{idempotency.HeaderReplayed},
+		AllowMethods:  []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
 	}))
 
 	// Initialize Game Hub
@@ -42,6 +103,126 @@ func main() {
 
 	// Initialize Matchmaker
 	mm := game.NewMatchmaker(hub)
+	if b := beacon.NewFromEnv(); b != nil {
+		mm.Beacon = b
+		log.Println("Randomness beacon enabled for match outcomes")
+	}
+
+	// Refund matches orphaned mid-fight, e.g. by a restart, so their seeds
+	// can rotate again
+	go game.SweepStaleMatches(5 * time.Minute)
+
+	// Start statistical fairness audit
+	auditInterval, err := time.ParseDuration(os.Getenv("FAIRNESS_AUDIT_INTERVAL"))
+	if err != nil || auditInterval <= 0 {
+		auditInterval = time.Hour
+	}
+	go audit.Run(auditInterval)
+
+	// Commit daily Merkle roots over match outcomes
+	go merkle.Run()
+
+	// Reconcile wallet balances against transactions and the ledger, once
+	// balances from before the ledger have opening entries
+	if opened, err := ledger.OpenLegacyBalances(); err != nil {
+		log.Printf("Failed to open legacy balances in the ledger: %v", err)
+	} else if opened > 0 {
+		log.Printf("Opened %d legacy wallet balances in the ledger", opened)
+	}
+	reconcileInterval, err := time.ParseDuration(os.Getenv("RECONCILE_INTERVAL"))
+	if err != nil || reconcileInterval <= 0 {
+		reconcileInterval = 15 * time.Minute
+	}
+	go reconcile.Run(reconcileInterval)
+
+	// Credit on-chain SOL and SPL token deposits
+	solanaRPC := solana.RPCFromEnv()
+	solTokens, err := solana.Tokens()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if w := solana.WatcherFromEnv(solanaRPC); w != nil {
+		w.Tokens = solTokens
+		go w.Run(context.Background())
+		log.Printf("Watching for Solana deposits (%d confirmations, %d tokens)", w.Confirmations, len(solTokens))
+	} else {
+		log.Println("WARNING: Solana RPC or deposit addresses not configured, on-chain deposits are disabled")
+	}
+
+	// Credit on-chain TON deposits
+	tonClient := ton.ClientFromEnv()
+	tonWatcher, err := ton.WatcherFromEnv(tonClient)
+	if err != nil {
+		log.Fatal("Invalid TON_HOT_WALLET:", err)
+	}
+	if tonWatcher != nil {
+		go tonWatcher.Run(context.Background())
+		log.Println("Watching for TON deposits")
+	} else {
+		log.Println("WARNING: TON API or hot wallet not configured, TON deposits are disabled")
+	}
+
+	// Pay out withdrawals from the hot wallet
+	solPayouts, err := solana.PayoutsFromEnv(solanaRPC)
+	if err != nil {
+		log.Fatal("Failed to load Solana hot wallet keypair:", err)
+	}
+	if solPayouts != nil {
+		withdrawal.Register(models.CurrencySOL, solPayouts)
+		for _, token := range solTokens {
+			withdrawal.Register(token.Currency, solPayouts.ForToken(token))
+		}
+	} else {
+		log.Println("WARNING: SOLANA_HOT_WALLET_KEYPAIR not set, SOL and token withdrawals are disabled")
+	}
+
+	// Sweep deposit addresses into the hot wallet, which pays token sweep fees
+	if s := solana.SweeperFromEnv(solanaRPC); s != nil {
+		if solPayouts != nil {
+			s.Tokens, s.FeePayer = solTokens, solPayouts.Signer
+		}
+		go s.Run(context.Background())
+	}
+	tonPayouts, err := ton.PayoutsFromEnv(tonClient)
+	if err != nil {
+		log.Fatal("Failed to load TON hot wallet key:", err)
+	}
+	if tonPayouts != nil {
+		withdrawal.Register(models.CurrencyTON, tonPayouts)
+	} else {
+		log.Println("WARNING: TON_HOT_WALLET_KEY not set, TON withdrawals are disabled")
+	}
+	withdrawalInterval, err := time.ParseDuration(os.Getenv("WITHDRAWAL_POLL_INTERVAL"))
+	if err != nil || withdrawalInterval <= 0 {
+		withdrawalInterval = 15 * time.Second
+	}
+	processor := withdrawal.NewProcessor(withdrawalInterval)
+	processor.Paused = treasury.Paused
+	go processor.Run(context.Background())
+
+	// Keep the hot wallet within its band; withdrawals pause when it runs dry
+	solHot, err := solana.HotWalletFromEnv(solanaRPC)
+	if err != nil {
+		log.Fatal("Invalid SOLANA_HOT_WALLET:", err)
+	}
+	if solHot != nil {
+		treasury.Register(models.CurrencySOL, solHot, solana.ColdWallet())
+		for _, token := range solTokens {
+			treasury.Register(token.Currency, &solana.TokenAccount{RPC: solanaRPC, Owner: solHot.Key, Token: token}, solana.ColdWallet())
+		}
+	}
+	tonHot, err := ton.HotWalletFromEnv(tonClient)
+	if err != nil {
+		log.Fatal("Invalid TON_HOT_WALLET:", err)
+	}
+	if tonHot != nil {
+		treasury.Register(models.CurrencyTON, tonHot, ton.ColdWallet())
+	}
+	treasuryInterval, err := time.ParseDuration(os.Getenv("TREASURY_INTERVAL"))
+	if err != nil || treasuryInterval <= 0 {
+		treasuryInterval = time.Minute
+	}
+	go treasury.Run(treasuryInterval)
 
 	// ==================
 	// Public Routes
@@ -66,6 +247,9 @@ func main() {
 		return game.ServeWs(hub, mm, c)
 	})
 
+	// Public keys for verifying settlement receipts
+	e.GET("/.well-known/gamblefights-keys", handlers.GetReceiptKeys)
+
 	// ==================
 	// Auth Routes
 	// ==================
@@ -80,18 +264,45 @@ func main() {
 	// User endpoints
 	api.GET("/user/profile", handlers.GetProfile)
 	api.PUT("/user/client-seed", handlers.UpdateClientSeed)
+	api.GET("/user/server-seed", handlers.GetServerSeeds)
+	api.POST("/user/server-seed/rotate", handlers.RotateServerSeed)
 
 	// Wallet endpoints
 	api.GET("/wallet/balance", handlers.GetBalances)
 	api.POST("/wallet/deposit-address", handlers.GetDepositAddress)
-	api.POST("/wallet/test-deposit", handlers.AddTestBalance) // Dev only
+	api.POST("/wallet/withdraw", handlers.Withdraw, idempotency.Middleware())
+	api.GET("/wallet/withdrawals", handlers.GetWithdrawals)
+	api.POST("/wallet/test-deposit", handlers.AddTestBalance, idempotency.Middleware()) // Dev only
+
+	// Admin endpoints
+	admin := api.Group("/admin", auth.RequireRole(string(models.RoleAdmin)))
+	admin.GET("/reconciliation", handlers.GetReconciliation)
+	admin.POST("/reconciliation/run", handlers.RunReconciliation)
+	admin.GET("/withdrawals", handlers.GetAdminWithdrawals)
+	admin.POST("/withdrawals/:id/approve", handlers.ApproveWithdrawal)
+	admin.POST("/withdrawals/:id/reject", handlers.RejectWithdrawal)
+	admin.GET("/revenue", handlers.GetRevenue)
+	admin.GET("/treasury", handlers.GetTreasury)
+	admin.POST("/treasury/run", handlers.RunTreasury)
+	admin.POST("/treasury/proposals/:id/resolve", handlers.ResolveTreasuryProposal)
 
 	// Match endpoints
 	api.GET("/matches/history", handlers.GetMatchHistory)
 	api.GET("/matches/:id", handlers.GetMatch)
+	api.POST("/matches/:id/reveal-seed", handlers.RevealMatchSeed)
 
 	// Public match endpoints (no auth required)
 	e.GET("/api/matches/live", handlers.GetLiveMatches)
+	e.GET("/api/matches/:id/verify", handlers.VerifyMatch)
+	e.GET("/api/matches/:id/inclusion-proof", handlers.GetInclusionProof)
+
+	// Public currency list (no auth required)
+	e.GET("/api/currencies", handlers.GetCurrencies)
+
+	// Public fairness endpoints
+	e.GET("/api/fairness/audit", handlers.GetFairnessAudit)
+	e.GET("/api/fairness/roots", handlers.GetMerkleRoots)
+	e.GET("/api/fairness/roots/:day", handlers.GetMerkleRoot)
 
 	// Test endpoint - simulate a fight (dev only)
 	api.POST("/test/fight", handlers.TestFight)
@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/fairness"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// decValueBuckets splits the 32-bit decValue range for the uniformity test
+const decValueBuckets = 16
+
+// Report is the published result of a statistical fairness audit
+type Report struct {
+	GeneratedAt time.Time          `json:"generatedAt"`
+	SampleSize  int                `json:"sampleSize"`
+	WinSplit    WinSplitResult     `json:"winSplit"`
+	Runs        RunsResult         `json:"runs"`
+	DecValues   DistributionResult `json:"decValues"`
+}
+
+// WinSplitResult is a chi-square test of playerA vs playerB wins against 50/50
+type WinSplitResult struct {
+	PlayerAWins int     `json:"playerAWins"`
+	PlayerBWins int     `json:"playerBWins"`
+	ChiSquare   float64 `json:"chiSquare"`
+	PValue      float64 `json:"pValue"`
+}
+
+// RunsResult is a Wald-Wolfowitz runs test over the ordered winner sequence
+type RunsResult struct {
+	Runs          int     `json:"runs"`
+	ExpectedRuns  float64 `json:"expectedRuns"`
+	ZScore        float64 `json:"zScore"`
+	PValue        float64 `json:"pValue"`
+	LongestStreak int     `json:"longestStreak"`
+}
+
+// DistributionResult is a chi-square uniformity test of decValue over the
+// 32-bit range. Only matches with revealed seeds can be recomputed.
+type DistributionResult struct {
+	SampleSize       int     `json:"sampleSize"`
+	Buckets          []int   `json:"buckets"`
+	ChiSquare        float64 `json:"chiSquare"`
+	DegreesOfFreedom int     `json:"degreesOfFreedom"`
+	PValue           float64 `json:"pValue"`
+}
+
+var (
+	latest   *Report
+	latestMu sync.RWMutex
+)
+
+// Latest returns the most recent audit report, or nil before the first run.
+func Latest() *Report {
+	latestMu.RLock()
+	defer latestMu.RUnlock()
+	return latest
+}
+
+// Run audits completed matches immediately and then on every interval.
+func Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := Generate()
+		if err != nil {
+			log.Printf("Fairness audit failed: %v", err)
+		} else {
+			latestMu.Lock()
+			latest = report
+			latestMu.Unlock()
+			log.Printf("Fairness audit: %d matches, win split p=%.4f, runs p=%.4f, decValue p=%.4f",
+				report.SampleSize, report.WinSplit.PValue, report.Runs.PValue, report.DecValues.PValue)
+		}
+		<-ticker.C
+	}
+}
+
+// Generate scans every completed match and runs the statistical tests. Test
+// fights are left out.
+func Generate() (*Report, error) {
+	var matches []models.Match
+	err := db.DB.
+		Select("id", "player_a_id", "winner_id", "server_seed", "client_seed_a", "client_seed_b", "nonce", "fairness_version", "beacon_randomness", "seed_revealed").
+		Where("status = ? AND winner_id IS NOT NULL AND is_test = ?", models.MatchStatusCompleted, false).
+		Order("finished_at ASC").
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return Analyze(matches), nil
+}
+
+// Analyze runs the statistical tests over matches in play order.
+func Analyze(matches []models.Match) *Report {
+	report := &Report{
+		GeneratedAt: time.Now().UTC(),
+		SampleSize:  len(matches),
+	}
+
+	sequence := make([]bool, 0, len(matches))
+	buckets := make([]int, decValueBuckets)
+	for _, m := range matches {
+		playerAWon := *m.WinnerID == m.PlayerAID
+		sequence = append(sequence, playerAWon)
+		if playerAWon {
+			report.WinSplit.PlayerAWins++
+		} else {
+			report.WinSplit.PlayerBWins++
+		}
+
+		if !m.SeedRevealed {
+			continue
+		}
+		algo, err := fairness.Lookup(m.FairnessVersion)
+		if err != nil {
+			continue
+		}
+		_, hash := algo.Outcome(fairness.Input{
+			ServerSeed: m.ServerSeed,
+			ClientSeed: fairness.CombineClientSeeds(m.ClientSeedA, m.ClientSeedB),
+			Nonce:      m.Nonce,
+			Beacon:     m.BeaconRandomness,
+		})
+		buckets[fairness.DecValue(hash)*decValueBuckets>>32]++
+		report.DecValues.SampleSize++
+	}
+
+	// Win split against 50/50
+	half := float64(len(matches)) / 2
+	report.WinSplit.ChiSquare = ChiSquare(
+		[]int{report.WinSplit.PlayerAWins, report.WinSplit.PlayerBWins},
+		[]float64{half, half},
+	)
+	report.WinSplit.PValue = ChiSquarePValue(report.WinSplit.ChiSquare, 1)
+
+	// Streaks
+	runs, expected, z, p := RunsTest(sequence)
+	report.Runs = RunsResult{
+		Runs:          runs,
+		ExpectedRuns:  expected,
+		ZScore:        z,
+		PValue:        p,
+		LongestStreak: LongestStreak(sequence),
+	}
+
+	// decValue uniformity
+	perBucket := float64(report.DecValues.SampleSize) / decValueBuckets
+	expectedBuckets := make([]float64, decValueBuckets)
+	for i := range expectedBuckets {
+		expectedBuckets[i] = perBucket
+	}
+	report.DecValues.Buckets = buckets
+	report.DecValues.DegreesOfFreedom = decValueBuckets - 1
+	report.DecValues.ChiSquare = ChiSquare(buckets, expectedBuckets)
+	report.DecValues.PValue = ChiSquarePValue(report.DecValues.ChiSquare, report.DecValues.DegreesOfFreedom)
+
+	return report
+}
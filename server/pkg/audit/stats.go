@@ -0,0 +1,138 @@
+package audit
+
+import "math"
+
+// ChiSquare returns the Pearson chi-square statistic for observed counts
+// against expected counts.
+func ChiSquare(observed []int, expected []float64) float64 {
+	var sum float64
+	for i, o := range observed {
+		if expected[i] == 0 {
+			continue
+		}
+		d := float64(o) - expected[i]
+		sum += d * d / expected[i]
+	}
+	return sum
+}
+
+// ChiSquarePValue returns P(X >= x) for a chi-square distribution with
+// dof degrees of freedom.
+func ChiSquarePValue(x float64, dof int) float64 {
+	if x <= 0 || dof <= 0 {
+		return 1
+	}
+	return gammaQ(float64(dof)/2, x/2)
+}
+
+// RunsTest runs a Wald-Wolfowitz test on a sequence of outcomes. Too few
+// runs means streaks are longer than chance allows, too many means the
+// outcomes alternate suspiciously. Returns the run count, expected runs,
+// z-score and two-sided p-value.
+func RunsTest(seq []bool) (runs int, expected float64, z float64, p float64) {
+	if len(seq) == 0 {
+		return 0, 0, 0, 1
+	}
+
+	var n1, n2 float64
+	runs = 1
+	for i, v := range seq {
+		if v {
+			n1++
+		} else {
+			n2++
+		}
+		if i > 0 && v != seq[i-1] {
+			runs++
+		}
+	}
+
+	n := n1 + n2
+	expected = 2*n1*n2/n + 1
+	variance := 2 * n1 * n2 * (2*n1*n2 - n) / (n * n * (n - 1))
+	if n1 == 0 || n2 == 0 || variance <= 0 {
+		return runs, expected, 0, 1
+	}
+
+	z = (float64(runs) - expected) / math.Sqrt(variance)
+	return runs, expected, z, NormalTwoSidedPValue(z)
+}
+
+// NormalTwoSidedPValue returns P(|Z| >= |z|) for a standard normal Z.
+func NormalTwoSidedPValue(z float64) float64 {
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// LongestStreak returns the longest run of identical outcomes.
+func LongestStreak(seq []bool) int {
+	longest, current := 0, 0
+	for i, v := range seq {
+		if i > 0 && v == seq[i-1] {
+			current++
+		} else {
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+// gammaQ is the regularized upper incomplete gamma function Q(a, x),
+// using a series expansion below a+1 and a continued fraction above.
+func gammaQ(a, x float64) float64 {
+	if x < a+1 {
+		return 1 - gammaSeries(a, x)
+	}
+	return gammaContinuedFraction(a, x)
+}
+
+const (
+	gammaMaxIterations = 500
+	gammaEpsilon       = 3e-14
+	gammaTiny          = 1e-300
+)
+
+func gammaSeries(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	ap := a
+	sum := 1 / a
+	del := sum
+	for i := 0; i < gammaMaxIterations; i++ {
+		ap++
+		del *= x / ap
+		sum += del
+		if math.Abs(del) < math.Abs(sum)*gammaEpsilon {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lgamma)
+}
+
+func gammaContinuedFraction(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / gammaTiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= gammaMaxIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < gammaTiny {
+			d = gammaTiny
+		}
+		c = b + an/c
+		if math.Abs(c) < gammaTiny {
+			c = gammaTiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < gammaEpsilon {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lgamma) * h
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChiSquarePValue(t *testing.T) {
+	// Critical values at the 5% level
+	cases := []struct {
+		x   float64
+		dof int
+	}{
+		{3.841, 1},
+		{24.996, 15},
+	}
+	for _, c := range cases {
+		p := ChiSquarePValue(c.x, c.dof)
+		if math.Abs(p-0.05) > 0.001 {
+			t.Errorf("ChiSquarePValue(%v, %d) = %v, expected 0.05", c.x, c.dof, p)
+		}
+	}
+
+	if p := ChiSquarePValue(0, 1); p != 1 {
+		t.Errorf("Expected p=1 for no deviation, got %v", p)
+	}
+}
+
+func TestRunsTest(t *testing.T) {
+	// Perfect alternation has far too many runs
+	alternating := make([]bool, 100)
+	for i := range alternating {
+		alternating[i] = i%2 == 0
+	}
+	runs, _, z, p := RunsTest(alternating)
+	if runs != 100 || z <= 0 || p > 0.001 {
+		t.Errorf("Alternating sequence: runs=%d z=%v p=%v", runs, z, p)
+	}
+
+	// Two long streaks have far too few runs
+	streaks := make([]bool, 100)
+	for i := 0; i < 50; i++ {
+		streaks[i] = true
+	}
+	runs, _, z, p = RunsTest(streaks)
+	if runs != 2 || z >= 0 || p > 0.001 {
+		t.Errorf("Streak sequence: runs=%d z=%v p=%v", runs, z, p)
+	}
+
+	if LongestStreak(streaks) != 50 {
+		t.Errorf("Expected longest streak 50, got %d", LongestStreak(streaks))
+	}
+}
@@ -35,3 +35,19 @@ func Middleware() echo.MiddlewareFunc {
 		}
 	}
 }
+
+// RequireRole rejects requests whose token does not carry one of the roles.
+// It must run after Middleware.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("role").(string)
+			for _, r := range roles {
+				if role == r {
+					return next(c)
+				}
+			}
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "insufficient role"})
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotPublished is returned for rounds the beacon has not produced yet
+var ErrNotPublished = errors.New("beacon: round not published yet")
+
+// Round is a single published beacon value
+type Round struct {
+	Number     uint64 `json:"round"`
+	Randomness string `json:"randomness"` // Hex encoded
+	Signature  string `json:"signature,omitempty"`
+}
+
+// Client fetches rounds from a public randomness beacon (drand-style).
+type Client interface {
+	// Latest returns the most recently published round
+	Latest(ctx context.Context) (Round, error)
+
+	// Round returns a specific round, or ErrNotPublished if it is in the future
+	Round(ctx context.Context, number uint64) (Round, error)
+}
+
+// Next waits for the first round published after the call. Because the
+// round did not exist when Next was called, nobody could have known its
+// value when the match inputs were fixed.
+func Next(ctx context.Context, c Client, poll time.Duration) (Round, error) {
+	latest, err := c.Latest(ctx)
+	if err != nil {
+		return Round{}, err
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		round, err := c.Round(ctx, latest.Number+1)
+		if err == nil {
+			return round, nil
+		}
+		if !errors.Is(err, ErrNotPublished) {
+			return Round{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return Round{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// NewFromEnv builds the beacon client configured by FAIRNESS_BEACON_URL.
+// A "file://" URL selects the local file stand-in. Returns nil when the
+// beacon mode is disabled.
+func NewFromEnv() Client {
+	url := os.Getenv("FAIRNESS_BEACON_URL")
+	if url == "" {
+		return nil
+	}
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		return NewFileClient(path)
+	}
+	return NewHTTPClient(url)
+}
@@ -0,0 +1,54 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRounds(t *testing.T, path string, rounds []Round) {
+	data, _ := json.Marshal(rounds)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNextWaitsForFutureRound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beacon.json")
+	published := []Round{{Number: 1, Randomness: "aa"}, {Number: 2, Randomness: "bb"}}
+	writeRounds(t, path, published)
+
+	client := NewFileClient(path)
+
+	// Publish round 3 shortly after Next starts waiting
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		writeRounds(t, path, append(published, Round{Number: 3, Randomness: "cc"}))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	round, err := Next(ctx, client, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if round.Number != 3 || round.Randomness != "cc" {
+		t.Errorf("Expected round 3, got %+v", round)
+	}
+}
+
+func TestNextTimesOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beacon.json")
+	writeRounds(t, path, []Round{{Number: 1, Randomness: "aa"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := Next(ctx, NewFileClient(path), 10*time.Millisecond); err == nil {
+		t.Error("Expected timeout waiting for an unpublished round")
+	}
+}
@@ -0,0 +1,61 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// FileClient is a local stand-in beacon that reads rounds from a JSON file
+// holding an array of rounds. The file is re-read on every call, so tests
+// can "publish" a round by appending to it.
+type FileClient struct {
+	Path string
+}
+
+// NewFileClient creates a beacon backed by the JSON file at path.
+func NewFileClient(path string) *FileClient {
+	return &FileClient{Path: path}
+}
+
+func (c *FileClient) Latest(ctx context.Context) (Round, error) {
+	rounds, err := c.load()
+	if err != nil {
+		return Round{}, err
+	}
+	if len(rounds) == 0 {
+		return Round{}, errors.New("beacon: no rounds in file")
+	}
+
+	latest := rounds[0]
+	for _, r := range rounds[1:] {
+		if r.Number > latest.Number {
+			latest = r
+		}
+	}
+	return latest, nil
+}
+
+func (c *FileClient) Round(ctx context.Context, number uint64) (Round, error) {
+	rounds, err := c.load()
+	if err != nil {
+		return Round{}, err
+	}
+	for _, r := range rounds {
+		if r.Number == number {
+			return r, nil
+		}
+	}
+	return Round{}, ErrNotPublished
+}
+
+func (c *FileClient) load() ([]Round, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	var rounds []Round
+	err = json.Unmarshal(data, &rounds)
+	return rounds, err
+}
@@ -0,0 +1,79 @@
+package beacon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPClient talks to a drand-compatible HTTP API, e.g.
+// https://api.drand.sh/<chain-hash>
+type HTTPClient struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewHTTPClient creates a client for the beacon chain at baseURL.
+func NewHTTPClient(baseURL string) *HTTPClient {
+	return &HTTPClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *HTTPClient) Latest(ctx context.Context) (Round, error) {
+	return c.get(ctx, "/public/latest")
+}
+
+func (c *HTTPClient) Round(ctx context.Context, number uint64) (Round, error) {
+	return c.get(ctx, fmt.Sprintf("/public/%d", number))
+}
+
+func (c *HTTPClient) get(ctx context.Context, path string) (Round, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return Round{}, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return Round{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusTooEarly:
+		return Round{}, ErrNotPublished
+	default:
+		return Round{}, fmt.Errorf("beacon: unexpected status %d", resp.StatusCode)
+	}
+
+	var round Round
+	if err := json.NewDecoder(resp.Body).Decode(&round); err != nil {
+		return Round{}, err
+	}
+	if err := checkRandomness(round); err != nil {
+		return Round{}, err
+	}
+	return round, nil
+}
+
+// checkRandomness confirms randomness = SHA256(signature) as drand defines it.
+// Verifying the BLS signature itself is left to the public beacon's tooling.
+func checkRandomness(r Round) error {
+	sig, err := hex.DecodeString(r.Signature)
+	if err != nil || len(sig) == 0 {
+		return fmt.Errorf("beacon: round %d has an invalid signature", r.Number)
+	}
+	sum := sha256.Sum256(sig)
+	if hex.EncodeToString(sum[:]) != r.Randomness {
+		return fmt.Errorf("beacon: round %d randomness does not match its signature", r.Number)
+	}
+	return nil
+}
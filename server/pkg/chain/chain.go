@@ -0,0 +1,55 @@
+// Package chain defines what the server needs from a blockchain to pay out
+// withdrawals. Each supported chain implements Payouts in its own package.
+package chain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBusy means the hot wallet cannot prepare another payout until an
+// earlier one lands or expires. Callers should retry later.
+var ErrBusy = errors.New("chain: hot wallet busy with an earlier payout")
+
+// Prepared is a signed payout that has not been sent yet
+type Prepared struct {
+	TxHash string // Known before sending, so it can be persisted first
+	Raw    []byte // Signed transaction, plus whatever the chain needs to track it
+}
+
+// Status is what the chain knows about a payout
+type Status struct {
+	Found     bool // The chain has seen the transaction
+	Failed    bool // It executed with an error; nothing was sent
+	Confirmed bool // It is deep enough to be considered final
+}
+
+// Payouts sends funds from the hot wallet to external addresses.
+type Payouts interface {
+	// ValidateAddress rejects destinations funds could not be sent to
+	ValidateAddress(address string) error
+
+	// MinAmount is the smallest payout the chain will accept
+	MinAmount() int64
+
+	// Prepare builds and signs a payout of amount to destination
+	Prepare(ctx context.Context, destination string, amount int64) (*Prepared, error)
+
+	// Broadcast sends a prepared payout. Sending the same one again is safe.
+	Broadcast(ctx context.Context, raw []byte) error
+
+	// Status reports on a prepared payout
+	Status(ctx context.Context, p *Prepared) (Status, error)
+
+	// Expired reports whether a prepared payout can no longer land. Checked
+	// before Status, an expired payout the chain has not seen never will be,
+	// and only then is refunding it safe.
+	Expired(ctx context.Context, p *Prepared) (bool, error)
+}
+
+// HotWallet is the custodial wallet deposits are swept into and payouts are
+// sent from.
+type HotWallet interface {
+	Address() string
+	Balance(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,35 @@
+package solana
+
+import "context"
+
+// Account reads the balance of one address. It implements chain.HotWallet.
+type Account struct {
+	RPC RPC
+	Key PublicKey
+}
+
+func (a *Account) Address() string {
+	return a.Key.String()
+}
+
+func (a *Account) Balance(ctx context.Context) (int64, error) {
+	lamports, err := a.RPC.Balance(ctx, a.Key.String())
+	return int64(lamports), err
+}
+
+// TokenAccount reads an owner's balance of a token, held in their associated
+// token account. It implements chain.HotWallet.
+type TokenAccount struct {
+	RPC   RPC
+	Owner PublicKey
+	Token Token
+}
+
+func (a *TokenAccount) Address() string {
+	return a.Owner.String()
+}
+
+func (a *TokenAccount) Balance(ctx context.Context) (int64, error) {
+	amount, err := a.RPC.TokenBalance(ctx, a.Token.AccountOf(a.Owner).String())
+	return int64(amount), err
+}
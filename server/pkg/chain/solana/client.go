@@ -0,0 +1,310 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPClient talks to a Solana JSON-RPC node, e.g.
+// https://api.mainnet-beta.solana.com
+type HTTPClient struct {
+	URL  string
+	HTTP *http.Client
+}
+
+// NewHTTPClient creates a client for the node at url.
+func NewHTTPClient(url string) *HTTPClient {
+	return &HTTPClient{
+		URL:  url,
+		HTTP: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("solana: rpc error %d: %s", e.Code, e.Message)
+}
+
+// call performs one JSON-RPC request and decodes its result into out
+func (c *HTTPClient) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("solana: unexpected status %d from %s", resp.StatusCode, method)
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return err
+	}
+	if envelope.Error != nil {
+		return envelope.Error
+	}
+	return json.Unmarshal(envelope.Result, out)
+}
+
+func (c *HTTPClient) SignaturesForAddress(ctx context.Context, address, before, until string, limit int) ([]SignatureInfo, error) {
+	opts := map[string]interface{}{"limit": limit, "commitment": "confirmed"}
+	if before != "" {
+		opts["before"] = before
+	}
+	if until != "" {
+		opts["until"] = until
+	}
+
+	var result []struct {
+		Signature string          `json:"signature"`
+		Slot      uint64          `json:"slot"`
+		Err       json.RawMessage `json:"err"`
+	}
+	if err := c.call(ctx, "getSignaturesForAddress", []interface{}{address, opts}, &result); err != nil {
+		return nil, err
+	}
+
+	infos := make([]SignatureInfo, len(result))
+	for i, r := range result {
+		infos[i] = SignatureInfo{Signature: r.Signature, Slot: r.Slot, Failed: isErr(r.Err)}
+	}
+	return infos, nil
+}
+
+// instruction is a jsonParsed instruction. parsed is a string for the memo
+// program and an object for the system and token programs.
+type instruction struct {
+	Program string          `json:"program"`
+	Parsed  json.RawMessage `json:"parsed"`
+}
+
+func (c *HTTPClient) Transaction(ctx context.Context, signature string) (*Transaction, error) {
+	var result *struct {
+		Slot uint64 `json:"slot"`
+		Meta struct {
+			Err               json.RawMessage `json:"err"`
+			InnerInstructions []struct {
+				Instructions []instruction `json:"instructions"`
+			} `json:"innerInstructions"`
+		} `json:"meta"`
+		Transaction struct {
+			Message struct {
+				Instructions []instruction `json:"instructions"`
+			} `json:"message"`
+		} `json:"transaction"`
+	}
+	opts := map[string]interface{}{
+		"encoding":                       "jsonParsed",
+		"commitment":                     "confirmed",
+		"maxSupportedTransactionVersion": 0,
+	}
+	if err := c.call(ctx, "getTransaction", []interface{}{signature, opts}, &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrNotFound
+	}
+
+	tx := &Transaction{
+		Signature: signature,
+		Slot:      result.Slot,
+		Failed:    isErr(result.Meta.Err),
+	}
+	instructions := result.Transaction.Message.Instructions
+	for _, inner := range result.Meta.InnerInstructions {
+		instructions = append(instructions, inner.Instructions...)
+	}
+	for _, ix := range instructions {
+		switch ix.Program {
+		case "spl-memo":
+			var memo string
+			if json.Unmarshal(ix.Parsed, &memo) == nil {
+				tx.Memos = append(tx.Memos, memo)
+			}
+		case "system":
+			var parsed struct {
+				Type string `json:"type"`
+				Info struct {
+					Source      string `json:"source"`
+					Destination string `json:"destination"`
+					Lamports    uint64 `json:"lamports"`
+				} `json:"info"`
+			}
+			if json.Unmarshal(ix.Parsed, &parsed) == nil && parsed.Type == "transfer" {
+				tx.Transfers = append(tx.Transfers, Transfer{
+					From:     parsed.Info.Source,
+					To:       parsed.Info.Destination,
+					Lamports: parsed.Info.Lamports,
+				})
+			}
+		case "spl-token":
+			var parsed struct {
+				Type string `json:"type"`
+				Info struct {
+					Source      string `json:"source"`
+					Destination string `json:"destination"`
+					Mint        string `json:"mint"`
+					Amount      string `json:"amount"` // transfer
+					TokenAmount struct {
+						Amount string `json:"amount"`
+					} `json:"tokenAmount"` // transferChecked
+				} `json:"info"`
+			}
+			if json.Unmarshal(ix.Parsed, &parsed) != nil {
+				continue
+			}
+			amount := parsed.Info.Amount
+			switch parsed.Type {
+			case "transfer":
+			case "transferChecked":
+				amount = parsed.Info.TokenAmount.Amount
+			default:
+				continue
+			}
+			if n, err := strconv.ParseUint(amount, 10, 64); err == nil {
+				tx.TokenTransfers = append(tx.TokenTransfers, TokenTransfer{
+					From:   parsed.Info.Source,
+					To:     parsed.Info.Destination,
+					Mint:   parsed.Info.Mint,
+					Amount: n,
+				})
+			}
+		}
+	}
+	return tx, nil
+}
+
+func (c *HTTPClient) SignatureStatuses(ctx context.Context, signatures []string) ([]*SignatureStatus, error) {
+	var result struct {
+		Value []*struct {
+			Slot               uint64          `json:"slot"`
+			Confirmations      *uint64         `json:"confirmations"` // null once rooted
+			Err                json.RawMessage `json:"err"`
+			ConfirmationStatus string          `json:"confirmationStatus"`
+		} `json:"value"`
+	}
+	opts := map[string]interface{}{"searchTransactionHistory": true}
+	if err := c.call(ctx, "getSignatureStatuses", []interface{}{signatures, opts}, &result); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*SignatureStatus, len(signatures))
+	for i, v := range result.Value {
+		if i >= len(statuses) || v == nil {
+			continue
+		}
+		status := &SignatureStatus{
+			Slot:      v.Slot,
+			Finalized: v.Confirmations == nil || v.ConfirmationStatus == "finalized",
+			Failed:    isErr(v.Err),
+		}
+		if v.Confirmations != nil {
+			status.Confirmations = *v.Confirmations
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+func (c *HTTPClient) Balance(ctx context.Context, address string) (uint64, error) {
+	var result struct {
+		Value uint64 `json:"value"`
+	}
+	opts := map[string]interface{}{"commitment": "finalized"}
+	if err := c.call(ctx, "getBalance", []interface{}{address, opts}, &result); err != nil {
+		return 0, err
+	}
+	return result.Value, nil
+}
+
+func (c *HTTPClient) TokenBalance(ctx context.Context, account string) (uint64, error) {
+	var result struct {
+		Value *struct {
+			Data struct {
+				Parsed struct {
+					Info struct {
+						TokenAmount struct {
+							Amount string `json:"amount"`
+						} `json:"tokenAmount"`
+					} `json:"info"`
+				} `json:"parsed"`
+			} `json:"data"`
+		} `json:"value"`
+	}
+	opts := map[string]interface{}{"commitment": "finalized", "encoding": "jsonParsed"}
+	if err := c.call(ctx, "getAccountInfo", []interface{}{account, opts}, &result); err != nil {
+		return 0, err
+	}
+	if result.Value == nil {
+		return 0, nil
+	}
+	return strconv.ParseUint(result.Value.Data.Parsed.Info.TokenAmount.Amount, 10, 64)
+}
+
+func (c *HTTPClient) LatestBlockhash(ctx context.Context) (PublicKey, error) {
+	var result struct {
+		Value struct {
+			Blockhash string `json:"blockhash"`
+		} `json:"value"`
+	}
+	opts := map[string]interface{}{"commitment": "finalized"}
+	if err := c.call(ctx, "getLatestBlockhash", []interface{}{opts}, &result); err != nil {
+		return PublicKey{}, err
+	}
+	return ParsePublicKey(result.Value.Blockhash)
+}
+
+func (c *HTTPClient) BlockhashValid(ctx context.Context, blockhash PublicKey) (bool, error) {
+	var result struct {
+		Value bool `json:"value"`
+	}
+	opts := map[string]interface{}{"commitment": "confirmed"}
+	if err := c.call(ctx, "isBlockhashValid", []interface{}{blockhash.String(), opts}, &result); err != nil {
+		return false, err
+	}
+	return result.Value, nil
+}
+
+func (c *HTTPClient) SendTransaction(ctx context.Context, tx *SignedTransaction) (string, error) {
+	var signature string
+	encoded := base64.StdEncoding.EncodeToString(tx.Serialize())
+	opts := map[string]interface{}{"encoding": "base64", "preflightCommitment": "confirmed"}
+	if err := c.call(ctx, "sendTransaction", []interface{}{encoded, opts}, &signature); err != nil {
+		return "", err
+	}
+	return signature, nil
+}
+
+// isErr reports whether a transaction error field is set
+func isErr(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null"
+}
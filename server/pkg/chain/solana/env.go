@@ -0,0 +1,170 @@
+package solana
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	depositWallet *HDWallet
+	depositMu     sync.RWMutex
+)
+
+// HotWallet is the custodial wallet deposits are swept into, from
+// SOLANA_HOT_WALLET. Users without a per-user address send SOL here with
+// their user ID as memo. Empty when not configured.
+func HotWallet() string {
+	return os.Getenv("SOLANA_HOT_WALLET")
+}
+
+// ColdWallet is the offline wallet excess hot wallet funds are proposed to
+// move to, from SOLANA_COLD_WALLET. Empty when not configured.
+func ColdWallet() string {
+	return os.Getenv("SOLANA_COLD_WALLET")
+}
+
+// LoadFromEnv loads the master seed for per-user deposit addresses from
+// SOLANA_DEPOSIT_SEED_FILE, or from SOLANA_DEPOSIT_SEED when no file is
+// configured. Both hold the seed as hex. Returns false when neither is set,
+// in which case deposits fall back to the hot wallet with a memo.
+func LoadFromEnv() (bool, error) {
+	encoded := os.Getenv("SOLANA_DEPOSIT_SEED")
+	if path := os.Getenv("SOLANA_DEPOSIT_SEED_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("solana: reading deposit seed file: %w", err)
+		}
+		encoded = string(data)
+	}
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return false, nil
+	}
+
+	seed, err := hex.DecodeString(encoded)
+	if err != nil {
+		return false, fmt.Errorf("solana: deposit seed must be hex: %w", err)
+	}
+	w, err := NewHDWallet(seed)
+	if err != nil {
+		return false, err
+	}
+	SetDepositWallet(w)
+	return true, nil
+}
+
+// SetDepositWallet replaces the master node deposit addresses derive from.
+func SetDepositWallet(w *HDWallet) {
+	depositMu.Lock()
+	depositWallet = w
+	depositMu.Unlock()
+}
+
+// DepositWallet returns the loaded master node, or nil if per-user deposit
+// addresses are disabled.
+func DepositWallet() *HDWallet {
+	depositMu.RLock()
+	defer depositMu.RUnlock()
+	return depositWallet
+}
+
+// Tokens returns the SPL tokens accepted besides SOL. USDT is enabled by
+// SOLANA_USDT_MINT, normally USDTMint; test clusters have their own mints.
+func Tokens() ([]Token, error) {
+	mint := strings.TrimSpace(os.Getenv("SOLANA_USDT_MINT"))
+	if mint == "" {
+		return nil, nil
+	}
+	key, err := ParsePublicKey(mint)
+	if err != nil {
+		return nil, fmt.Errorf("solana: invalid SOLANA_USDT_MINT: %w", err)
+	}
+	usdt := USDT
+	usdt.Mint = key
+	return []Token{usdt}, nil
+}
+
+// RPCFromEnv returns a client for SOLANA_RPC_URL, or nil when unset.
+func RPCFromEnv() RPC {
+	url := os.Getenv("SOLANA_RPC_URL")
+	if url == "" {
+		return nil
+	}
+	return NewHTTPClient(url)
+}
+
+// WatcherFromEnv builds the deposit watcher. Returns nil without an RPC
+// node or without anything to watch.
+func WatcherFromEnv(rpc RPC) *Watcher {
+	if rpc == nil || (HotWallet() == "" && DepositWallet() == nil) {
+		return nil
+	}
+
+	w := NewWatcher(rpc, HotWallet())
+	if n, err := strconv.ParseUint(os.Getenv("SOLANA_DEPOSIT_CONFIRMATIONS"), 10, 64); err == nil && n > 0 {
+		w.Confirmations = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("SOLANA_POLL_INTERVAL")); err == nil && d > 0 {
+		w.Poll = d
+	}
+	return w
+}
+
+// SweeperFromEnv builds the sweeper that consolidates deposit addresses
+// into the hot wallet. Returns nil unless both are configured.
+func SweeperFromEnv(rpc RPC) *Sweeper {
+	if rpc == nil || HotWallet() == "" || DepositWallet() == nil {
+		return nil
+	}
+
+	s := NewSweeper(rpc, DepositWallet(), HotWallet())
+	if n, err := strconv.ParseUint(os.Getenv("SOLANA_SWEEP_MIN_LAMPORTS"), 10, 64); err == nil {
+		s.MinLamports = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("SOLANA_SWEEP_INTERVAL")); err == nil && d > 0 {
+		s.Interval = d
+	}
+	return s
+}
+
+// PayoutsFromEnv builds SOL payouts signed with the hot wallet keypair in
+// SOLANA_HOT_WALLET_KEYPAIR, a Solana CLI keypair file. Returns nil when no
+// keypair is configured; the key must match SOLANA_HOT_WALLET if both are
+// set.
+func PayoutsFromEnv(rpc RPC) (*Payouts, error) {
+	path := os.Getenv("SOLANA_HOT_WALLET_KEYPAIR")
+	if rpc == nil || path == "" {
+		return nil, nil
+	}
+	signer, err := LoadKeypairFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if hot := HotWallet(); hot != "" && signer.PublicKey().String() != hot {
+		return nil, fmt.Errorf("solana: hot wallet keypair is for %s, not SOLANA_HOT_WALLET %s", signer.PublicKey(), hot)
+	}
+
+	p := NewPayouts(rpc, signer)
+	if n, err := strconv.ParseUint(os.Getenv("SOLANA_WITHDRAWAL_CONFIRMATIONS"), 10, 64); err == nil && n > 0 {
+		p.Confirmations = n
+	}
+	return p, nil
+}
+
+// HotWalletFromEnv returns the SOLANA_HOT_WALLET account, or nil without an
+// RPC node or hot wallet.
+func HotWalletFromEnv(rpc RPC) (*Account, error) {
+	if rpc == nil || HotWallet() == "" {
+		return nil, nil
+	}
+	key, err := ParsePublicKey(HotWallet())
+	if err != nil {
+		return nil, err
+	}
+	return &Account{RPC: rpc, Key: key}, nil
+}
@@ -0,0 +1,379 @@
+package solana
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mr-tron/base58"
+)
+
+// finalityDepth is how many confirmations the fake needs to root a slot
+const finalityDepth = 32
+
+// Fake is an in-memory chain implementing RPC. Transfers land in a new slot;
+// Advance produces empty slots on top of them and Drop forks one away.
+// Balances are tracked for every address; funds sent with Transfer come from
+// outside and are not checked, while SendTransaction enforces signatures,
+// balances and fees like the real runtime.
+type Fake struct {
+	mu       sync.Mutex
+	slot     uint64
+	txs      []*Transaction // In slot order
+	balances map[string]uint64
+	tokens   map[string]fakeTokenAccount
+}
+
+// fakeTokenAccount is an SPL token account
+type fakeTokenAccount struct {
+	Owner  PublicKey
+	Mint   PublicKey
+	Amount uint64
+}
+
+// NewFake returns an empty chain at slot 1.
+func NewFake() *Fake {
+	return &Fake{slot: 1, balances: map[string]uint64{}, tokens: map[string]fakeTokenAccount{}}
+}
+
+// Transfer lands a SOL transfer from outside the system with an optional
+// memo and returns its signature.
+func (f *Fake) Transfer(from, to string, lamports uint64, memo string) string {
+	f.mu.Lock()
+	f.balances[to] += lamports
+	f.mu.Unlock()
+
+	return f.land(&Transaction{
+		Memos:     memos(memo),
+		Transfers: []Transfer{{From: from, To: to, Lamports: lamports}},
+	})
+}
+
+// BatchTransfer lands one transaction paying each recipient from outside
+// the system, like an exchange batching withdrawals, and returns its
+// signature.
+func (f *Fake) BatchTransfer(from string, lamports map[string]uint64) string {
+	tx := &Transaction{}
+	f.mu.Lock()
+	for to, amount := range lamports {
+		f.balances[to] += amount
+		tx.Transfers = append(tx.Transfers, Transfer{From: from, To: to, Lamports: amount})
+	}
+	f.mu.Unlock()
+	return f.land(tx)
+}
+
+// TransferToken lands a token transfer from outside the system into owner's
+// associated token account, creating it, and returns its signature.
+func (f *Fake) TransferToken(from string, owner, mint PublicKey, amount uint64, memo string) string {
+	to := AssociatedTokenAddress(owner, mint)
+	f.mu.Lock()
+	account := f.tokens[to.String()]
+	account.Owner, account.Mint = owner, mint
+	account.Amount += amount
+	f.tokens[to.String()] = account
+	f.mu.Unlock()
+
+	return f.land(&Transaction{
+		Memos:          memos(memo),
+		TokenTransfers: []TokenTransfer{{From: from, To: to.String(), Mint: mint.String(), Amount: amount}},
+	})
+}
+
+// FailedTransfer lands a transfer that executed with an error.
+func (f *Fake) FailedTransfer(from, to string, lamports uint64, memo string) string {
+	return f.land(&Transaction{
+		Failed:    true,
+		Memos:     memos(memo),
+		Transfers: []Transfer{{From: from, To: to, Lamports: lamports}},
+	})
+}
+
+func (f *Fake) land(tx *Transaction) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.landLocked(tx)
+}
+
+func (f *Fake) landLocked(tx *Transaction) string {
+	f.slot++
+	tx.Slot = f.slot
+	if tx.Signature == "" {
+		tx.Signature = newSignature()
+	}
+	f.txs = append(f.txs, tx)
+	return tx.Signature
+}
+
+// Advance produces n empty slots.
+func (f *Fake) Advance(n uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.slot += n
+}
+
+// Drop removes a transaction as if its fork was abandoned. Balances are
+// left as they are.
+func (f *Fake) Drop(signature string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, tx := range f.txs {
+		if tx.Signature == signature {
+			f.txs = append(f.txs[:i], f.txs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *Fake) SignaturesForAddress(ctx context.Context, address, before, until string, limit int) ([]SignatureInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var infos []SignatureInfo
+	started := before == ""
+	for i := len(f.txs) - 1; i >= 0 && len(infos) < limit; i-- {
+		tx := f.txs[i]
+		if tx.Signature == until {
+			break
+		}
+		if !started {
+			started = tx.Signature == before
+			continue
+		}
+		if touches(tx, address) {
+			infos = append(infos, SignatureInfo{Signature: tx.Signature, Slot: tx.Slot, Failed: tx.Failed})
+		}
+	}
+	return infos, nil
+}
+
+func (f *Fake) Transaction(ctx context.Context, signature string) (*Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if tx := f.find(signature); tx != nil {
+		copied := *tx
+		return &copied, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (f *Fake) SignatureStatuses(ctx context.Context, signatures []string) ([]*SignatureStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	statuses := make([]*SignatureStatus, len(signatures))
+	for i, sig := range signatures {
+		tx := f.find(sig)
+		if tx == nil {
+			continue
+		}
+		confirmations := f.slot - tx.Slot
+		statuses[i] = &SignatureStatus{
+			Slot:          tx.Slot,
+			Confirmations: confirmations,
+			Finalized:     confirmations >= finalityDepth,
+			Failed:        tx.Failed,
+		}
+	}
+	return statuses, nil
+}
+
+func (f *Fake) Balance(ctx context.Context, address string) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.balances[address], nil
+}
+
+func (f *Fake) TokenBalance(ctx context.Context, account string) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tokens[account].Amount, nil
+}
+
+func (f *Fake) LatestBlockhash(ctx context.Context) (PublicKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return fakeBlockhash(f.slot), nil
+}
+
+// SendTransaction executes system transfers, associated token account
+// creation and checked token transfers. Like preflight on a real node it
+// rejects, without landing, transactions that would fail.
+func (f *Fake) SendTransaction(ctx context.Context, signed *SignedTransaction) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(signed.Signatures) == 0 || !signed.Verify() {
+		return "", errors.New("solana: fake: signature verification failed")
+	}
+	m := signed.Message
+	if !f.recentBlockhash(m.Blockhash) {
+		return "", errors.New("solana: fake: blockhash not found")
+	}
+	if f.find(signed.Signature()) != nil {
+		return "", errors.New("solana: fake: transaction already processed")
+	}
+
+	// Apply to a copy so a failing instruction leaves no trace
+	balances := map[string]uint64{}
+	get := func(addr string) uint64 {
+		if b, ok := balances[addr]; ok {
+			return b
+		}
+		return f.balances[addr]
+	}
+	debit := func(addr string, amount uint64) error {
+		if get(addr) < amount {
+			return fmt.Errorf("solana: fake: insufficient funds in %s", addr)
+		}
+		balances[addr] = get(addr) - amount
+		return nil
+	}
+
+	tokens := map[string]fakeTokenAccount{}
+	token := func(addr string) (fakeTokenAccount, bool) {
+		if a, ok := tokens[addr]; ok {
+			return a, true
+		}
+		a, ok := f.tokens[addr]
+		return a, ok
+	}
+
+	payer := m.Accounts[0].String()
+	if err := debit(payer, FeePerSignature*uint64(len(signed.Signatures))); err != nil {
+		return "", err
+	}
+
+	tx := &Transaction{Signature: signed.Signature()}
+	for _, ix := range m.Instructions {
+		switch m.Accounts[ix.ProgramIndex] {
+		case SystemProgram:
+			if len(ix.Data) != 12 || binary.LittleEndian.Uint32(ix.Data) != 2 || len(ix.Accounts) != 2 {
+				return "", errors.New("solana: fake: unsupported system instruction")
+			}
+			from, to := int(ix.Accounts[0]), int(ix.Accounts[1])
+			if from >= int(m.RequiredSignatures) || !m.Writable(from) || !m.Writable(to) {
+				return "", errors.New("solana: fake: transfer accounts have the wrong permissions")
+			}
+			lamports := binary.LittleEndian.Uint64(ix.Data[4:])
+			fromAddr, toAddr := m.Accounts[from].String(), m.Accounts[to].String()
+			if err := debit(fromAddr, lamports); err != nil {
+				return "", err
+			}
+			balances[toAddr] = get(toAddr) + lamports
+			tx.Transfers = append(tx.Transfers, Transfer{From: fromAddr, To: toAddr, Lamports: lamports})
+		case AssociatedTokenProgram:
+			if len(ix.Data) != 1 || ix.Data[0] != 1 || len(ix.Accounts) != 6 {
+				return "", errors.New("solana: fake: unsupported associated token instruction")
+			}
+			payerIx, ata, owner, mint := m.Accounts[ix.Accounts[0]], m.Accounts[ix.Accounts[1]], m.Accounts[ix.Accounts[2]], m.Accounts[ix.Accounts[3]]
+			if ata != AssociatedTokenAddress(owner, mint) {
+				return "", errors.New("solana: fake: wrong associated token address")
+			}
+			if _, exists := token(ata.String()); exists {
+				continue
+			}
+			if err := debit(payerIx.String(), TokenAccountRent); err != nil {
+				return "", err
+			}
+			tokens[ata.String()] = fakeTokenAccount{Owner: owner, Mint: mint}
+		case TokenProgram:
+			if len(ix.Data) != 10 || ix.Data[0] != 12 || len(ix.Accounts) != 4 {
+				return "", errors.New("solana: fake: unsupported token instruction")
+			}
+			authority := int(ix.Accounts[3])
+			from, mint, to := m.Accounts[ix.Accounts[0]].String(), m.Accounts[ix.Accounts[1]], m.Accounts[ix.Accounts[2]].String()
+			source, ok := token(from)
+			dest, destOK := token(to)
+			switch {
+			case !ok || !destOK:
+				return "", errors.New("solana: fake: token account not found")
+			case source.Mint != mint || dest.Mint != mint:
+				return "", errors.New("solana: fake: token mint mismatch")
+			case authority >= int(m.RequiredSignatures) || m.Accounts[authority] != source.Owner:
+				return "", errors.New("solana: fake: token transfer not signed by the owner")
+			}
+			amount := binary.LittleEndian.Uint64(ix.Data[1:9])
+			if source.Amount < amount {
+				return "", fmt.Errorf("solana: fake: insufficient tokens in %s", from)
+			}
+			source.Amount -= amount
+			tokens[from] = source
+			dest, _ = token(to)
+			dest.Amount += amount
+			tokens[to] = dest
+			tx.TokenTransfers = append(tx.TokenTransfers, TokenTransfer{From: from, To: to, Mint: mint.String(), Amount: amount})
+		default:
+			return "", fmt.Errorf("solana: fake: unsupported program %s", m.Accounts[ix.ProgramIndex])
+		}
+	}
+
+	for addr, b := range balances {
+		f.balances[addr] = b
+	}
+	for addr, a := range tokens {
+		f.tokens[addr] = a
+	}
+	return f.landLocked(tx), nil
+}
+
+func (f *Fake) BlockhashValid(ctx context.Context, blockhash PublicKey) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.recentBlockhash(blockhash), nil
+}
+
+// recentBlockhash accepts hashes from the last 150 slots, like the runtime
+func (f *Fake) recentBlockhash(hash PublicKey) bool {
+	for s := f.slot; s > 0 && f.slot-s <= 150; s-- {
+		if fakeBlockhash(s) == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func fakeBlockhash(slot uint64) PublicKey {
+	return sha256.Sum256(binary.LittleEndian.AppendUint64([]byte("fake-blockhash"), slot))
+}
+
+func (f *Fake) find(signature string) *Transaction {
+	for _, tx := range f.txs {
+		if tx.Signature == signature {
+			return tx
+		}
+	}
+	return nil
+}
+
+func touches(tx *Transaction, address string) bool {
+	for _, t := range tx.Transfers {
+		if t.From == address || t.To == address {
+			return true
+		}
+	}
+	for _, t := range tx.TokenTransfers {
+		if t.From == address || t.To == address {
+			return true
+		}
+	}
+	return false
+}
+
+func memos(memo string) []string {
+	if memo == "" {
+		return nil
+	}
+	return []string{memo}
+}
+
+func newSignature() string {
+	b := make([]byte, 64)
+	rand.Read(b)
+	return base58.Encode(b)
+}
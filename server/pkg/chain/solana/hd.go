@@ -0,0 +1,74 @@
+package solana
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/mr-tron/base58"
+)
+
+// hardened marks a hardened SLIP-0010 index; ed25519 only supports these
+const hardened = 0x80000000
+
+// HDWallet derives deposit keys from a master seed with SLIP-0010 ed25519
+// derivation, on the path Solana wallets use: m/44'/501'/index'/0'.
+type HDWallet struct {
+	key       [32]byte
+	chainCode [32]byte
+}
+
+// NewHDWallet creates the master node for a seed of 16 to 64 bytes.
+func NewHDWallet(seed []byte) (*HDWallet, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("solana: HD seed must be 16 to 64 bytes, got %d", len(seed))
+	}
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	return node(mac.Sum(nil)), nil
+}
+
+func node(i []byte) *HDWallet {
+	w := &HDWallet{}
+	copy(w.key[:], i[:32])
+	copy(w.chainCode[:], i[32:])
+	return w
+}
+
+// Child derives the hardened child at index (the hardened bit is implied).
+func (w *HDWallet) Child(index uint32) *HDWallet {
+	data := make([]byte, 0, 37)
+	data = append(data, 0)
+	data = append(data, w.key[:]...)
+	data = binary.BigEndian.AppendUint32(data, index|hardened)
+
+	mac := hmac.New(sha512.New, w.chainCode[:])
+	mac.Write(data)
+	return node(mac.Sum(nil))
+}
+
+// Derive walks a path of hardened indexes from this node.
+func (w *HDWallet) Derive(path ...uint32) *HDWallet {
+	n := w
+	for _, index := range path {
+		n = n.Child(index)
+	}
+	return n
+}
+
+// PrivateKey returns the node's ed25519 key.
+func (w *HDWallet) PrivateKey() ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(w.key[:])
+}
+
+// DepositKey returns the key of deposit account index.
+func (w *HDWallet) DepositKey(index uint32) ed25519.PrivateKey {
+	return w.Derive(44, 501, index, 0).PrivateKey()
+}
+
+// DepositAddress returns the base58 address of deposit account index.
+func (w *HDWallet) DepositAddress(index uint32) string {
+	return base58.Encode(w.DepositKey(index).Public().(ed25519.PublicKey))
+}
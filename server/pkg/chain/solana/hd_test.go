@@ -0,0 +1,54 @@
+package solana
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+// SLIP-0010 test vector 1 for ed25519
+func TestHDWalletSLIP10Vector(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewHDWallet(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path      []uint32
+		chainCode string
+		key       string
+	}{
+		{nil, "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{[]uint32{0}, "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+	}
+	for _, tt := range tests {
+		n := master.Derive(tt.path...)
+		if got := hex.EncodeToString(n.chainCode[:]); got != tt.chainCode {
+			t.Errorf("m%v chain code = %s, want %s", tt.path, got, tt.chainCode)
+		}
+		if got := hex.EncodeToString(n.key[:]); got != tt.key {
+			t.Errorf("m%v key = %s, want %s", tt.path, got, tt.key)
+		}
+	}
+}
+
+func TestDepositAddressesAreDistinct(t *testing.T) {
+	master, _ := NewHDWallet([]byte("0123456789abcdef0123456789abcdef"))
+	seen := map[string]bool{}
+	for i := uint32(0); i < 100; i++ {
+		addr := master.DepositAddress(i)
+		if seen[addr] {
+			t.Fatalf("address %s repeated at index %d", addr, i)
+		}
+		seen[addr] = true
+	}
+
+	// The address is the public half of the deposit key
+	key := master.DepositKey(7)
+	if got := master.DepositAddress(7); got != base58.Encode(key.Public().(ed25519.PublicKey)) {
+		t.Errorf("address %s does not match key", got)
+	}
+}
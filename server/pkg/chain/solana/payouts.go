@@ -0,0 +1,166 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hugolol/gamblefights/pkg/chain"
+)
+
+// MinWithdrawalLamports keeps payouts above the rent-exempt minimum
+// (890,880 lamports), below which a transfer to a new account fails.
+const MinWithdrawalLamports = 1_000_000
+
+// Builder builds unsigned payout transactions
+type Builder interface {
+	Transfer(ctx context.Context, from, to PublicKey, lamports uint64) (Message, error)
+}
+
+// TransferBuilder builds plain system transfers on a recent blockhash
+type TransferBuilder struct {
+	RPC RPC
+}
+
+func (b TransferBuilder) Transfer(ctx context.Context, from, to PublicKey, lamports uint64) (Message, error) {
+	blockhash, err := b.RPC.LatestBlockhash(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	return NewMessage(from, blockhash, SystemTransfer(from, to, lamports)), nil
+}
+
+// TokenTransferBuilder builds checked transfers of an SPL token between the
+// owners' associated token accounts, creating the destination's if needed
+// at the sender's expense
+type TokenTransferBuilder struct {
+	RPC   RPC
+	Token Token
+}
+
+func (b TokenTransferBuilder) Transfer(ctx context.Context, from, to PublicKey, amount uint64) (Message, error) {
+	blockhash, err := b.RPC.LatestBlockhash(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	return NewMessage(from, blockhash,
+		CreateAssociatedTokenAccount(from, to, b.Token.Mint),
+		TransferChecked(b.Token.AccountOf(from), b.Token.Mint, b.Token.AccountOf(to), from, amount, b.Token.Decimals),
+	), nil
+}
+
+// Payouts pays SOL or SPL token withdrawals from the hot wallet. It
+// implements chain.Payouts.
+type Payouts struct {
+	RPC           RPC
+	Builder       Builder
+	Signer        Signer // The hot wallet
+	Token         *Token // Nil for SOL
+	Confirmations uint64
+}
+
+// NewPayouts creates payouts from the signer's account.
+func NewPayouts(rpc RPC, signer Signer) *Payouts {
+	return &Payouts{
+		RPC:           rpc,
+		Builder:       TransferBuilder{RPC: rpc},
+		Signer:        signer,
+		Confirmations: finalityDepth,
+	}
+}
+
+// NewTokenPayouts creates payouts of token from the signer's associated
+// token account. Fees, and the rent of destination token accounts that do
+// not exist yet, are paid in SOL by the signer.
+func NewTokenPayouts(rpc RPC, signer Signer, token Token) *Payouts {
+	p := NewPayouts(rpc, signer)
+	p.Builder = TokenTransferBuilder{RPC: rpc, Token: token}
+	p.Token = &token
+	return p
+}
+
+// ForToken returns payouts of token signed by the same hot wallet, with
+// the same confirmation depth.
+func (p *Payouts) ForToken(token Token) *Payouts {
+	t := NewTokenPayouts(p.RPC, p.Signer, token)
+	t.Confirmations = p.Confirmations
+	return t
+}
+
+// ValidateAddress accepts wallets. Token payouts go to the wallet's
+// associated token account, so addresses off the curve, such as token
+// accounts themselves, are refused.
+func (p *Payouts) ValidateAddress(address string) error {
+	key, err := ParsePublicKey(address)
+	if err != nil {
+		return err
+	}
+	if key == SystemProgram || key == p.Signer.PublicKey() {
+		return fmt.Errorf("solana: cannot withdraw to %s", address)
+	}
+	if p.Token != nil && !OnCurve(key) {
+		return fmt.Errorf("solana: %s is not a wallet address", address)
+	}
+	return nil
+}
+
+func (p *Payouts) MinAmount() int64 {
+	if p.Token != nil {
+		return int64(p.Token.MinWithdrawal)
+	}
+	return MinWithdrawalLamports
+}
+
+func (p *Payouts) Prepare(ctx context.Context, destination string, amount int64) (*chain.Prepared, error) {
+	to, err := ParsePublicKey(destination)
+	if err != nil {
+		return nil, err
+	}
+	if amount < p.MinAmount() {
+		return nil, fmt.Errorf("solana: payout of %d is below the minimum", amount)
+	}
+
+	msg, err := p.Builder.Transfer(ctx, p.Signer.PublicKey(), to, uint64(amount))
+	if err != nil {
+		return nil, err
+	}
+	tx, err := SignWith(ctx, msg, p.Signer)
+	if err != nil {
+		return nil, err
+	}
+	return &chain.Prepared{TxHash: tx.Signature(), Raw: tx.Serialize()}, nil
+}
+
+func (p *Payouts) Broadcast(ctx context.Context, raw []byte) error {
+	tx, err := DecodeTransaction(raw)
+	if err != nil {
+		return err
+	}
+	_, err = p.RPC.SendTransaction(ctx, tx)
+	return err
+}
+
+func (p *Payouts) Status(ctx context.Context, prepared *chain.Prepared) (chain.Status, error) {
+	statuses, err := p.RPC.SignatureStatuses(ctx, []string{prepared.TxHash})
+	if err != nil {
+		return chain.Status{}, err
+	}
+	if len(statuses) == 0 || statuses[0] == nil {
+		return chain.Status{}, nil
+	}
+	s := statuses[0]
+	return chain.Status{
+		Found:     true,
+		Failed:    s.Failed,
+		Confirmed: !s.Failed && (s.Finalized || s.Confirmations >= p.Confirmations),
+	}, nil
+}
+
+// Expired reports whether the payout's blockhash is too old to land.
+func (p *Payouts) Expired(ctx context.Context, prepared *chain.Prepared) (bool, error) {
+	tx, err := DecodeTransaction(prepared.Raw)
+	if err != nil {
+		return false, err
+	}
+	valid, err := p.RPC.BlockhashValid(ctx, tx.Message.Blockhash)
+	return !valid, err
+}
@@ -0,0 +1,133 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"testing"
+)
+
+// payee is a valid external address
+var payee = PublicKeyOf(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))).String()
+
+func TestPayoutsLifecycle(t *testing.T) {
+	ctx := context.Background()
+	chain := NewFake()
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	signer := NewKeypairSigner(key)
+	hot := signer.PublicKey().String()
+	chain.Transfer(sender, hot, 2*LamportsPerSOL, "")
+
+	p := NewPayouts(chain, signer)
+	p.Confirmations = 3
+
+	if err := p.ValidateAddress("not-an-address"); err == nil {
+		t.Error("accepted an invalid address")
+	}
+	if err := p.ValidateAddress(hot); err == nil {
+		t.Error("accepted the hot wallet as destination")
+	}
+	if err := p.ValidateAddress(payee); err != nil {
+		t.Errorf("rejected a valid address: %v", err)
+	}
+
+	prepared, err := p.Prepare(ctx, payee, LamportsPerSOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Known before broadcasting, and unseen until then
+	status, err := p.Status(ctx, prepared)
+	if err != nil || status.Found {
+		t.Fatalf("status before broadcast = %+v, %v", status, err)
+	}
+
+	if err := p.Broadcast(ctx, prepared.Raw); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = p.Status(ctx, prepared)
+	if !status.Found || status.Confirmed || status.Failed {
+		t.Fatalf("status after broadcast = %+v, want found and unconfirmed", status)
+	}
+
+	chain.Advance(3)
+	status, _ = p.Status(ctx, prepared)
+	if !status.Confirmed {
+		t.Fatalf("status after 3 slots = %+v, want confirmed", status)
+	}
+
+	balance, _ := chain.Balance(ctx, hot)
+	received, _ := chain.Balance(ctx, payee)
+	if balance != LamportsPerSOL-FeePerSignature || received != LamportsPerSOL {
+		t.Errorf("hot wallet balance = %d, payee = %d", balance, received)
+	}
+}
+
+func TestPayoutsExpire(t *testing.T) {
+	ctx := context.Background()
+	chain := NewFake()
+	signer := NewKeypairSigner(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+	p := NewPayouts(chain, signer)
+
+	// The hot wallet is empty, so the payout cannot land
+	prepared, err := p.Prepare(ctx, payee, LamportsPerSOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Broadcast(ctx, prepared.Raw); err == nil {
+		t.Fatal("broadcast from an empty wallet succeeded")
+	}
+
+	if expired, _ := p.Expired(ctx, prepared); expired {
+		t.Fatal("expired right after preparing")
+	}
+	chain.Advance(151)
+	if expired, _ := p.Expired(ctx, prepared); !expired {
+		t.Fatal("not expired after 151 slots")
+	}
+	if status, _ := p.Status(ctx, prepared); status.Found {
+		t.Fatal("expired payout was found")
+	}
+}
+
+func TestTokenPayouts(t *testing.T) {
+	ctx := context.Background()
+	chain := NewFake()
+	signer := NewKeypairSigner(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+	hot := signer.PublicKey()
+	chain.Transfer(sender, hot.String(), LamportsPerSOL, "")
+	chain.TransferToken(sender, hot, USDTMint, 10_000_000, "")
+
+	p := NewTokenPayouts(chain, signer, USDT)
+	p.Confirmations = 1
+
+	to, _ := ParsePublicKey(payee)
+	if err := p.ValidateAddress(USDT.AccountOf(to).String()); err == nil {
+		t.Error("accepted a token account as destination")
+	}
+	if _, err := p.Prepare(ctx, payee, 999_999); err == nil {
+		t.Error("prepared a payout below the minimum")
+	}
+
+	prepared, err := p.Prepare(ctx, payee, 4_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Broadcast(ctx, prepared.Raw); err != nil {
+		t.Fatal(err)
+	}
+	chain.Advance(1)
+	if status, _ := p.Status(ctx, prepared); !status.Confirmed {
+		t.Fatalf("status = %+v, want confirmed", status)
+	}
+
+	received, _ := chain.TokenBalance(ctx, USDT.AccountOf(to).String())
+	left, _ := (&TokenAccount{RPC: chain, Owner: hot, Token: USDT}).Balance(ctx)
+	lamports, _ := chain.Balance(ctx, hot.String())
+	if received != 4_000_000 || left != 6_000_000 {
+		t.Errorf("payee received %d, hot wallet holds %d", received, left)
+	}
+	if lamports != LamportsPerSOL-FeePerSignature-TokenAccountRent {
+		t.Errorf("hot wallet lamports = %d, want fee and rent deducted", lamports)
+	}
+}
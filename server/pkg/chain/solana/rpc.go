@@ -0,0 +1,87 @@
+// Package solana watches the Solana chain for SOL and SPL token deposits
+// and credits them to user wallets through the ledger.
+//
+// All chain access goes through the RPC interface. HTTPClient talks to a
+// JSON-RPC node; Fake is an in-memory chain for tests and local development.
+package solana
+
+import (
+	"context"
+	"errors"
+)
+
+// LamportsPerSOL is the number of lamports in one SOL
+const LamportsPerSOL = 1_000_000_000
+
+// ErrNotFound is returned for a transaction the node does not know (yet)
+var ErrNotFound = errors.New("solana: transaction not found")
+
+// SignatureInfo is one entry of an address's transaction history
+type SignatureInfo struct {
+	Signature string
+	Slot      uint64
+	Failed    bool
+}
+
+// Transfer is a native SOL transfer inside a transaction
+type Transfer struct {
+	From     string
+	To       string
+	Lamports uint64
+}
+
+// TokenTransfer is an SPL token transfer inside a transaction, between
+// token accounts rather than their owners
+type TokenTransfer struct {
+	From   string
+	To     string
+	Mint   string // Empty for plain transfers, which do not name it
+	Amount uint64
+}
+
+// Transaction is the part of a confirmed transaction deposits care about
+type Transaction struct {
+	Signature      string
+	Slot           uint64
+	Failed         bool
+	Memos          []string
+	Transfers      []Transfer
+	TokenTransfers []TokenTransfer
+}
+
+// SignatureStatus is how deeply a transaction is confirmed
+type SignatureStatus struct {
+	Slot          uint64
+	Confirmations uint64 // Blocks confirmed on top of Slot
+	Finalized     bool   // Rooted; can no longer be rolled back
+	Failed        bool
+}
+
+// RPC is the subset of the Solana JSON-RPC API the server uses.
+type RPC interface {
+	// SignaturesForAddress returns transactions touching address, newest
+	// first. before and until, when set, bound the page exclusively.
+	SignaturesForAddress(ctx context.Context, address, before, until string, limit int) ([]SignatureInfo, error)
+
+	// Transaction returns a confirmed transaction, or ErrNotFound
+	Transaction(ctx context.Context, signature string) (*Transaction, error)
+
+	// SignatureStatuses returns one status per signature, nil when unknown
+	SignatureStatuses(ctx context.Context, signatures []string) ([]*SignatureStatus, error)
+
+	// Balance returns an account's finalized balance in lamports
+	Balance(ctx context.Context, address string) (uint64, error)
+
+	// TokenBalance returns a token account's finalized balance in base
+	// units, 0 if it does not exist
+	TokenBalance(ctx context.Context, account string) (uint64, error)
+
+	// LatestBlockhash returns a recent blockhash to build transactions with
+	LatestBlockhash(ctx context.Context) (PublicKey, error)
+
+	// BlockhashValid reports whether transactions using blockhash can still land
+	BlockhashValid(ctx context.Context, blockhash PublicKey) (bool, error)
+
+	// SendTransaction submits a signed transaction and returns its signature
+	SendTransaction(ctx context.Context, tx *SignedTransaction) (string, error)
+}
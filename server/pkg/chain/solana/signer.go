@@ -0,0 +1,87 @@
+package solana
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Signer signs transaction messages for one account. KeypairSigner keeps
+// the key in process; a remote signer (KMS, HSM) can stand in for it.
+type Signer interface {
+	PublicKey() PublicKey
+	SignMessage(ctx context.Context, message []byte) ([]byte, error)
+}
+
+// KeypairSigner signs with a local ed25519 key
+type KeypairSigner struct {
+	key ed25519.PrivateKey
+}
+
+// NewKeypairSigner wraps a private key.
+func NewKeypairSigner(key ed25519.PrivateKey) *KeypairSigner {
+	return &KeypairSigner{key: key}
+}
+
+// LoadKeypairFile reads a Solana CLI keypair file: a JSON array of the 64
+// secret key bytes.
+func LoadKeypairFile(path string) (*KeypairSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("solana: reading keypair: %w", err)
+	}
+	var ints []int
+	if err := json.Unmarshal(data, &ints); err != nil {
+		return nil, fmt.Errorf("solana: keypair file must be a JSON byte array: %w", err)
+	}
+	if len(ints) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("solana: keypair has %d bytes, want %d", len(ints), ed25519.PrivateKeySize)
+	}
+	key := make(ed25519.PrivateKey, len(ints))
+	for i, v := range ints {
+		if v < 0 || v > 255 {
+			return nil, fmt.Errorf("solana: keypair byte %d out of range", i)
+		}
+		key[i] = byte(v)
+	}
+
+	// The file repeats the public key; make sure it belongs to the secret
+	if !ed25519.PublicKey(key[32:]).Equal(ed25519.NewKeyFromSeed(key[:32]).Public()) {
+		return nil, fmt.Errorf("solana: keypair public key does not match its secret")
+	}
+	return NewKeypairSigner(key), nil
+}
+
+func (s *KeypairSigner) PublicKey() PublicKey {
+	return PublicKeyOf(s.key)
+}
+
+func (s *KeypairSigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, message), nil
+}
+
+// SignWith signs a message with signers, which must cover every required
+// signer of the message.
+func SignWith(ctx context.Context, m Message, signers ...Signer) (*SignedTransaction, error) {
+	bySigner := map[PublicKey]Signer{}
+	for _, s := range signers {
+		bySigner[s.PublicKey()] = s
+	}
+
+	msg := m.Serialize()
+	tx := &SignedTransaction{Message: m}
+	for _, key := range m.Signers() {
+		s, ok := bySigner[key]
+		if !ok {
+			return nil, fmt.Errorf("solana: missing signer for %s", key)
+		}
+		sig, err := s.SignMessage(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		tx.Signatures = append(tx.Signatures, sig)
+	}
+	return tx, nil
+}
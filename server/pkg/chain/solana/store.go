@@ -0,0 +1,149 @@
+package solana
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// chainName keys this chain's cursors
+const chainName = "solana"
+
+// chainCurrencies are deposited on this chain
+var chainCurrencies = []models.Currency{models.CurrencySOL, models.CurrencyUSDT}
+
+// ErrNoDepositSeed is returned when per-user deposit addresses are disabled
+var ErrNoDepositSeed = errors.New("solana: no deposit seed configured")
+
+// AssignDepositAddress returns the user's deposit address, deriving one at
+// the next free HD index on first use. The address is stored on the user's
+// SOL wallet, which is created if needed.
+func AssignDepositAddress(userID uuid.UUID) (string, error) {
+	hd := DepositWallet()
+	if hd == nil {
+		return "", ErrNoDepositSeed
+	}
+
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		var address string
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			var wallet models.Wallet
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where(models.Wallet{UserID: userID, Currency: models.CurrencySOL}).
+				FirstOrCreate(&wallet).Error
+			if err != nil {
+				return err
+			}
+			if wallet.DepositIndex != nil {
+				address = wallet.DepositAddress
+				return nil
+			}
+
+			var next int64
+			if err := tx.Model(&models.Wallet{}).Select("COALESCE(MAX(deposit_index) + 1, 0)").Scan(&next).Error; err != nil {
+				return err
+			}
+			if next >= hardened {
+				return fmt.Errorf("solana: deposit indexes exhausted")
+			}
+			address = hd.DepositAddress(uint32(next))
+			return tx.Model(&wallet).Updates(map[string]interface{}{
+				"deposit_index":   next,
+				"deposit_address": address,
+			}).Error
+		})
+		// Another user took the same index concurrently; try the next one
+		if !uniqueViolation(err) {
+			return address, err
+		}
+	}
+	return "", err
+}
+
+// uniqueViolation reports a duplicate key error
+func uniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// DBStore keeps cursors in chain_cursors and deposits in the ledger.
+type DBStore struct{}
+
+func (DBStore) Cursor(address string) (string, error) {
+	var cursor models.ChainCursor
+	err := db.DB.First(&cursor, "chain = ? AND address = ?", chainName, address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return cursor.Signature, err
+}
+
+func (DBStore) SaveCursor(address, signature string) error {
+	return db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain"}, {Name: "address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"signature", "updated_at"}),
+	}).Create(&models.ChainCursor{
+		Chain:     chainName,
+		Address:   address,
+		Signature: signature,
+		UpdatedAt: time.Now(),
+	}).Error
+}
+
+func (DBStore) DepositAccounts() ([]DepositAccount, error) {
+	var wallets []models.Wallet
+	err := db.DB.
+		Select("user_id", "deposit_address", "deposit_index").
+		Where("currency = ? AND deposit_index IS NOT NULL", models.CurrencySOL).
+		Order("deposit_index ASC").
+		Find(&wallets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]DepositAccount, len(wallets))
+	for i, w := range wallets {
+		accounts[i] = DepositAccount{Address: w.DepositAddress, UserID: w.UserID, Index: uint32(*w.DepositIndex)}
+	}
+	return accounts, nil
+}
+
+func (DBStore) Announce(userID uuid.UUID, currency models.Currency, amount int64, signature string) error {
+	var count int64
+	if err := db.DB.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUnknownUser
+	}
+	_, err := ledger.AnnounceDeposit(userID, currency, amount, signature)
+	return err
+}
+
+func (DBStore) Pending() ([]models.Transaction, error) {
+	var pending []models.Transaction
+	err := db.DB.
+		Where("type = ? AND status = ? AND currency IN ? AND tx_hash <> ''",
+			models.TxTypeDeposit, models.TxStatusPending, chainCurrencies).
+		Order("created_at ASC").
+		Find(&pending).Error
+	return pending, err
+}
+
+func (DBStore) Confirm(deposit *models.Transaction) error {
+	return ledger.ConfirmDeposit(deposit)
+}
+
+func (DBStore) Fail(deposit *models.Transaction) error {
+	return ledger.FailDeposit(deposit)
+}
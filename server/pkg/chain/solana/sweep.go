@@ -0,0 +1,161 @@
+package solana
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Sweeper moves funds from per-user deposit addresses into the hot wallet,
+// so withdrawals can be paid from one place. User balances are unaffected:
+// deposits were credited when they confirmed.
+type Sweeper struct {
+	RPC       RPC
+	Store     Store
+	HD        *HDWallet
+	HotWallet string
+
+	// MinLamports skips addresses holding less, so fees do not eat dust
+	MinLamports uint64
+	Interval    time.Duration
+
+	// Tokens are swept from deposit addresses' token accounts when
+	// holding at least their minimum withdrawal. Deposit addresses usually
+	// hold no SOL, so FeePayer, the hot wallet, pays; without it tokens
+	// are left in place.
+	Tokens   []Token
+	FeePayer Signer
+}
+
+// NewSweeper creates a sweeper into hotWallet backed by the database.
+func NewSweeper(rpc RPC, hd *HDWallet, hotWallet string) *Sweeper {
+	return &Sweeper{
+		RPC:         rpc,
+		Store:       DBStore{},
+		HD:          hd,
+		HotWallet:   hotWallet,
+		MinLamports: LamportsPerSOL / 100,
+		Interval:    10 * time.Minute,
+	}
+}
+
+// Run sweeps on every interval until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.Sweep(ctx); err != nil {
+			log.Printf("Solana sweep: %v", err)
+		}
+	}
+}
+
+// Sweep empties every deposit address above MinLamports into the hot
+// wallet, each paying its own fee, then sweeps tokens. It returns the
+// signatures sent.
+func (s *Sweeper) Sweep(ctx context.Context) ([]string, error) {
+	hot, err := ParsePublicKey(s.HotWallet)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.Store.DepositAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	sent, err := s.sweepTokens(ctx, hot, accounts)
+	if err != nil {
+		return sent, err
+	}
+	for _, account := range accounts {
+		balance, err := s.RPC.Balance(ctx, account.Address)
+		if err != nil {
+			return sent, err
+		}
+		if balance <= FeePerSignature || balance < s.MinLamports {
+			continue
+		}
+
+		key := s.HD.DepositKey(account.Index)
+		from := PublicKeyOf(key)
+		if from.String() != account.Address {
+			log.Printf("Solana sweep: %s does not derive from index %d, skipping", account.Address, account.Index)
+			continue
+		}
+
+		blockhash, err := s.RPC.LatestBlockhash(ctx)
+		if err != nil {
+			return sent, err
+		}
+		// Empty the account entirely; a partial balance below the rent
+		// exemption minimum would be rejected.
+		amount := balance - FeePerSignature
+		tx, err := Sign(NewMessage(from, blockhash, SystemTransfer(from, hot, amount)), key)
+		if err != nil {
+			return sent, err
+		}
+		signature, err := s.RPC.SendTransaction(ctx, tx)
+		if err != nil {
+			log.Printf("Solana sweep of %s failed: %v", account.Address, err)
+			continue
+		}
+		log.Printf("Swept %d lamports from %s (user %s) to the hot wallet: %s", amount, account.Address, account.UserID, signature)
+		sent = append(sent, signature)
+	}
+	return sent, nil
+}
+
+// sweepTokens moves every token balance at or above its minimum withdrawal
+// into the hot wallet's associated token account, creating it if needed.
+func (s *Sweeper) sweepTokens(ctx context.Context, hot PublicKey, accounts []DepositAccount) ([]string, error) {
+	if s.FeePayer == nil {
+		return nil, nil
+	}
+
+	var sent []string
+	for _, account := range accounts {
+		key := s.HD.DepositKey(account.Index)
+		from := PublicKeyOf(key)
+		if len(s.Tokens) == 0 || from.String() != account.Address {
+			continue // The SOL sweep logs the mismatch
+		}
+		for _, token := range s.Tokens {
+			source := token.AccountOf(from)
+			balance, err := s.RPC.TokenBalance(ctx, source.String())
+			if err != nil {
+				return sent, err
+			}
+			if balance == 0 || balance < token.MinWithdrawal {
+				continue
+			}
+
+			blockhash, err := s.RPC.LatestBlockhash(ctx)
+			if err != nil {
+				return sent, err
+			}
+			payer := s.FeePayer.PublicKey()
+			msg := NewMessage(payer, blockhash,
+				CreateAssociatedTokenAccount(payer, hot, token.Mint),
+				TransferChecked(source, token.Mint, token.AccountOf(hot), from, balance, token.Decimals),
+			)
+			tx, err := SignWith(ctx, msg, s.FeePayer, NewKeypairSigner(key))
+			if err != nil {
+				return sent, err
+			}
+			signature, err := s.RPC.SendTransaction(ctx, tx)
+			if err != nil {
+				log.Printf("Solana sweep of %s from %s failed: %v", token.Currency, account.Address, err)
+				continue
+			}
+			log.Printf("Swept %d %s base units from %s (user %s) to the hot wallet: %s", balance, token.Currency, account.Address, account.UserID, signature)
+			sent = append(sent, signature)
+		}
+	}
+	return sent, nil
+}
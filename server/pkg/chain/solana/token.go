@@ -0,0 +1,155 @@
+package solana
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+var (
+	// TokenProgram owns SPL token mints and accounts
+	TokenProgram = MustPublicKey("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+
+	// AssociatedTokenProgram creates each owner's canonical token account
+	AssociatedTokenProgram = MustPublicKey("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL")
+
+	// USDTMint is Tether's mainnet mint
+	USDTMint = MustPublicKey("Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB")
+)
+
+// TokenAccountRent is the rent-exempt minimum of a token account, paid by
+// whoever creates it
+const TokenAccountRent = 2_039_280
+
+// ErrNoProgramAddress is returned when no bump seed yields an address off
+// the curve, which in practice never happens
+var ErrNoProgramAddress = errors.New("solana: no valid program address")
+
+// Token is an SPL token the server accepts
+type Token struct {
+	Currency models.Currency
+	Mint     PublicKey
+	Decimals uint8
+
+	// MinWithdrawal is the smallest payout, in base units. Deposit
+	// addresses holding less are not swept.
+	MinWithdrawal uint64
+}
+
+// USDT is Tether on mainnet. Other clusters need their own mint.
+var USDT = Token{
+	Currency:      models.CurrencyUSDT,
+	Mint:          USDTMint,
+	Decimals:      6,
+	MinWithdrawal: 1_000_000, // 1 USDT
+}
+
+// AccountOf returns owner's associated token account for the token.
+func (t Token) AccountOf(owner PublicKey) PublicKey {
+	return AssociatedTokenAddress(owner, t.Mint)
+}
+
+// AssociatedTokenAddress derives owner's canonical token account for mint.
+func AssociatedTokenAddress(owner, mint PublicKey) PublicKey {
+	address, _, err := FindProgramAddress([][]byte{owner[:], TokenProgram[:], mint[:]}, AssociatedTokenProgram)
+	if err != nil {
+		panic(err)
+	}
+	return address
+}
+
+// CreateProgramAddress hashes seeds into an address owned by program. It
+// fails if the result is on the ed25519 curve, where a private key could
+// exist.
+func CreateProgramAddress(seeds [][]byte, program PublicKey) (PublicKey, error) {
+	h := sha256.New()
+	for _, s := range seeds {
+		h.Write(s)
+	}
+	h.Write(program[:])
+	h.Write([]byte("ProgramDerivedAddress"))
+	var address PublicKey
+	copy(address[:], h.Sum(nil))
+	if OnCurve(address) {
+		return PublicKey{}, ErrNoProgramAddress
+	}
+	return address, nil
+}
+
+// FindProgramAddress returns the first program address found by appending
+// a bump seed from 255 down, and that bump.
+func FindProgramAddress(seeds [][]byte, program PublicKey) (PublicKey, uint8, error) {
+	for bump := 255; bump >= 0; bump-- {
+		address, err := CreateProgramAddress(append(seeds[:len(seeds):len(seeds)], []byte{byte(bump)}), program)
+		if err == nil {
+			return address, uint8(bump), nil
+		}
+	}
+	return PublicKey{}, 0, ErrNoProgramAddress
+}
+
+var (
+	curveP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	// curveD is -121665/121666 mod p
+	curveD = new(big.Int).Mod(new(big.Int).Mul(big.NewInt(-121665), new(big.Int).ModInverse(big.NewInt(121666), curveP)), curveP)
+)
+
+// OnCurve reports whether k decodes to an ed25519 point: whether
+// x² = (y²-1)/(dy²+1) has a solution.
+func OnCurve(k PublicKey) bool {
+	le := k
+	le[31] &= 0x7f // Drop the sign of x
+	for i, j := 0, len(le)-1; i < j; i, j = i+1, j-1 {
+		le[i], le[j] = le[j], le[i]
+	}
+	y := new(big.Int).SetBytes(le[:])
+	if y.Cmp(curveP) >= 0 {
+		return false
+	}
+
+	y2 := new(big.Int).Mul(y, y)
+	u := new(big.Int).Sub(y2, big.NewInt(1))
+	v := new(big.Int).Add(new(big.Int).Mul(curveD, y2), big.NewInt(1))
+	x2 := new(big.Int).Mul(u, new(big.Int).ModInverse(v.Mod(v, curveP), curveP))
+	x2.Mod(x2, curveP)
+	if x2.Sign() == 0 {
+		return true
+	}
+	return big.Jacobi(x2, curveP) == 1
+}
+
+// CreateAssociatedTokenAccount creates owner's token account for mint,
+// paid for by payer, unless it already exists.
+func CreateAssociatedTokenAccount(payer, owner, mint PublicKey) Instruction {
+	return Instruction{
+		Program: AssociatedTokenProgram,
+		Accounts: []AccountMeta{
+			{Key: payer, Signer: true, Writable: true},
+			{Key: AssociatedTokenAddress(owner, mint), Writable: true},
+			{Key: owner},
+			{Key: mint},
+			{Key: SystemProgram},
+			{Key: TokenProgram},
+		},
+		Data: []byte{1}, // CreateIdempotent
+	}
+}
+
+// TransferChecked moves amount base units between two token accounts of
+// mint. The decimals are checked against the mint.
+func TransferChecked(source, mint, destination, authority PublicKey, amount uint64, decimals uint8) Instruction {
+	data := binary.LittleEndian.AppendUint64([]byte{12}, amount) // TransferChecked
+	return Instruction{
+		Program: TokenProgram,
+		Accounts: []AccountMeta{
+			{Key: source, Writable: true},
+			{Key: mint},
+			{Key: destination, Writable: true},
+			{Key: authority, Signer: true},
+		},
+		Data: append(data, decimals),
+	}
+}
@@ -0,0 +1,43 @@
+package solana
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestCreateProgramAddress(t *testing.T) {
+	program := MustPublicKey("BPFLoader1111111111111111111111111111111111")
+	seed := MustPublicKey("SeedPubey1111111111111111111111111111111111")
+	for _, tc := range []struct {
+		seeds [][]byte
+		want  string
+	}{
+		{[][]byte{{}, {1}}, "3gF2KMe9KiC6FNVBmfg9i267aMPvK37FewCip4eGBFcT"},
+		{[][]byte{[]byte("☉")}, "7ytmC1nT1xY4RfxCV2ZgyA7UakC93do5ZdyhdF3EtPj7"},
+		{[][]byte{[]byte("Talking"), []byte("Squirrels")}, "HwRVBufQ4haG5XSgpspwKtNd3PC9GM9m1196uJW36vds"},
+		{[][]byte{seed[:]}, "GUs5qLUfsEHkcMB9T38vjr18ypEhRuNWiePW2LoK4E3K"},
+	} {
+		got, err := CreateProgramAddress(tc.seeds, program)
+		if err != nil || got.String() != tc.want {
+			t.Errorf("%q = %s, %v; want %s", tc.seeds, got, err, tc.want)
+		}
+	}
+}
+
+func TestAssociatedTokenAddress(t *testing.T) {
+	owner := MustPublicKey("B8UwBUUnKwCyKuGMbFKWaG7exYdDk2ozZrPg72NyVbfj")
+	mint := MustPublicKey("7o36UsWR1JQLpZ9PE2gn9L4SQ69CNNiWAXd4Jt7rqz9Z")
+	if got := AssociatedTokenAddress(owner, mint).String(); got != "DShWnroshVbeUp28oopA3Pu7oFPDBtC1DBmPECXXAQ9n" {
+		t.Fatalf("ATA = %s", got)
+	}
+}
+
+func TestOnCurve(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	if !OnCurve(PublicKeyOf(key)) {
+		t.Error("an ed25519 public key is off the curve")
+	}
+	if OnCurve(AssociatedTokenAddress(PublicKeyOf(key), USDTMint)) {
+		t.Error("a program address is on the curve")
+	}
+}
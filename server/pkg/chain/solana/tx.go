@@ -0,0 +1,332 @@
+package solana
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/mr-tron/base58"
+)
+
+// FeePerSignature is the base fee, in lamports, for each transaction signature
+const FeePerSignature = 5000
+
+// ErrMalformedTx is returned when a serialized transaction cannot be decoded
+var ErrMalformedTx = errors.New("solana: malformed transaction")
+
+// PublicKey is a 32-byte account address
+type PublicKey [32]byte
+
+// SystemProgram owns plain SOL accounts and performs transfers
+var SystemProgram = PublicKey{}
+
+// ParsePublicKey decodes a base58 address.
+func ParsePublicKey(s string) (PublicKey, error) {
+	var k PublicKey
+	b, err := base58.Decode(s)
+	if err != nil || len(b) != len(k) {
+		return k, fmt.Errorf("solana: invalid address %q", s)
+	}
+	copy(k[:], b)
+	return k, nil
+}
+
+// MustPublicKey is ParsePublicKey for constants.
+func MustPublicKey(s string) PublicKey {
+	k, err := ParsePublicKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// PublicKeyOf returns the address of an ed25519 key.
+func PublicKeyOf(key ed25519.PrivateKey) PublicKey {
+	var k PublicKey
+	copy(k[:], key.Public().(ed25519.PublicKey))
+	return k
+}
+
+func (k PublicKey) String() string {
+	return base58.Encode(k[:])
+}
+
+// AccountMeta is an account an instruction reads or writes
+type AccountMeta struct {
+	Key      PublicKey
+	Signer   bool
+	Writable bool
+}
+
+// Instruction is a call into a program
+type Instruction struct {
+	Program  PublicKey
+	Accounts []AccountMeta
+	Data     []byte
+}
+
+// SystemTransfer moves lamports between two system accounts.
+func SystemTransfer(from, to PublicKey, lamports uint64) Instruction {
+	data := binary.LittleEndian.AppendUint32(nil, 2) // Transfer
+	data = binary.LittleEndian.AppendUint64(data, lamports)
+	return Instruction{
+		Program: SystemProgram,
+		Accounts: []AccountMeta{
+			{Key: from, Signer: true, Writable: true},
+			{Key: to, Writable: true},
+		},
+		Data: data,
+	}
+}
+
+// CompiledInstruction refers to accounts by their index in the message
+type CompiledInstruction struct {
+	ProgramIndex uint8
+	Accounts     []uint8
+	Data         []byte
+}
+
+// Message is a legacy transaction message: the part that gets signed
+type Message struct {
+	RequiredSignatures uint8
+	ReadonlySigned     uint8
+	ReadonlyUnsigned   uint8
+	Accounts           []PublicKey
+	Blockhash          PublicKey
+	Instructions       []CompiledInstruction
+}
+
+// NewMessage compiles instructions into a message paid for by feePayer.
+// Accounts are ordered writable signers, readonly signers, writable
+// non-signers, then readonly non-signers, as the runtime requires.
+func NewMessage(feePayer PublicKey, blockhash PublicKey, instructions ...Instruction) Message {
+	metas := map[PublicKey]*AccountMeta{feePayer: {Key: feePayer, Signer: true, Writable: true}}
+	order := []PublicKey{feePayer}
+	add := func(m AccountMeta) {
+		if existing, ok := metas[m.Key]; ok {
+			existing.Signer = existing.Signer || m.Signer
+			existing.Writable = existing.Writable || m.Writable
+			return
+		}
+		metas[m.Key] = &m
+		order = append(order, m.Key)
+	}
+	for _, ix := range instructions {
+		for _, a := range ix.Accounts {
+			add(a)
+		}
+		add(AccountMeta{Key: ix.Program})
+	}
+
+	msg := Message{Blockhash: blockhash}
+	for _, class := range []struct{ signer, writable bool }{{true, true}, {true, false}, {false, true}, {false, false}} {
+		for _, key := range order {
+			m := metas[key]
+			if m.Signer != class.signer || m.Writable != class.writable {
+				continue
+			}
+			msg.Accounts = append(msg.Accounts, key)
+			switch {
+			case m.Signer:
+				msg.RequiredSignatures++
+				if !m.Writable {
+					msg.ReadonlySigned++
+				}
+			case !m.Writable:
+				msg.ReadonlyUnsigned++
+			}
+		}
+	}
+
+	index := map[PublicKey]uint8{}
+	for i, key := range msg.Accounts {
+		index[key] = uint8(i)
+	}
+	for _, ix := range instructions {
+		compiled := CompiledInstruction{ProgramIndex: index[ix.Program], Data: ix.Data}
+		for _, a := range ix.Accounts {
+			compiled.Accounts = append(compiled.Accounts, index[a.Key])
+		}
+		msg.Instructions = append(msg.Instructions, compiled)
+	}
+	return msg
+}
+
+// Serialize returns the wire encoding of the message.
+func (m Message) Serialize() []byte {
+	b := []byte{m.RequiredSignatures, m.ReadonlySigned, m.ReadonlyUnsigned}
+	b = appendCompactU16(b, len(m.Accounts))
+	for _, k := range m.Accounts {
+		b = append(b, k[:]...)
+	}
+	b = append(b, m.Blockhash[:]...)
+	b = appendCompactU16(b, len(m.Instructions))
+	for _, ix := range m.Instructions {
+		b = append(b, ix.ProgramIndex)
+		b = appendCompactU16(b, len(ix.Accounts))
+		b = append(b, ix.Accounts...)
+		b = appendCompactU16(b, len(ix.Data))
+		b = append(b, ix.Data...)
+	}
+	return b
+}
+
+// Signers returns the accounts that must sign, in signature order.
+func (m Message) Signers() []PublicKey {
+	return m.Accounts[:m.RequiredSignatures]
+}
+
+// Writable reports whether the account at index i may be modified.
+func (m Message) Writable(i int) bool {
+	signers := int(m.RequiredSignatures)
+	if i < signers {
+		return i < signers-int(m.ReadonlySigned)
+	}
+	return i < len(m.Accounts)-int(m.ReadonlyUnsigned)
+}
+
+// SignedTransaction is a message with one signature per required signer
+type SignedTransaction struct {
+	Signatures [][]byte
+	Message    Message
+}
+
+// Signature is the transaction ID: the base58 fee payer signature.
+func (t *SignedTransaction) Signature() string {
+	return base58.Encode(t.Signatures[0])
+}
+
+// Serialize returns the wire encoding of the transaction.
+func (t *SignedTransaction) Serialize() []byte {
+	b := appendCompactU16(nil, len(t.Signatures))
+	for _, sig := range t.Signatures {
+		b = append(b, sig...)
+	}
+	return append(b, t.Message.Serialize()...)
+}
+
+// Verify checks every signature against its signer.
+func (t *SignedTransaction) Verify() bool {
+	signers := t.Message.Signers()
+	if len(t.Signatures) != len(signers) {
+		return false
+	}
+	msg := t.Message.Serialize()
+	for i, signer := range signers {
+		if !ed25519.Verify(signer[:], msg, t.Signatures[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sign signs a message with the keys of all its signers.
+func Sign(m Message, keys ...ed25519.PrivateKey) (*SignedTransaction, error) {
+	byKey := map[PublicKey]ed25519.PrivateKey{}
+	for _, k := range keys {
+		byKey[PublicKeyOf(k)] = k
+	}
+
+	msg := m.Serialize()
+	tx := &SignedTransaction{Message: m}
+	for _, signer := range m.Signers() {
+		key, ok := byKey[signer]
+		if !ok {
+			return nil, fmt.Errorf("solana: missing key for signer %s", signer)
+		}
+		tx.Signatures = append(tx.Signatures, ed25519.Sign(key, msg))
+	}
+	return tx, nil
+}
+
+// DecodeTransaction parses a serialized legacy transaction.
+func DecodeTransaction(raw []byte) (*SignedTransaction, error) {
+	r := &reader{b: raw}
+	tx := &SignedTransaction{}
+
+	n := r.compactU16()
+	for i := 0; i < n; i++ {
+		tx.Signatures = append(tx.Signatures, r.bytes(ed25519.SignatureSize))
+	}
+
+	m := &tx.Message
+	m.RequiredSignatures, m.ReadonlySigned, m.ReadonlyUnsigned = r.byte(), r.byte(), r.byte()
+	n = r.compactU16()
+	for i := 0; i < n; i++ {
+		var k PublicKey
+		copy(k[:], r.bytes(32))
+		m.Accounts = append(m.Accounts, k)
+	}
+	copy(m.Blockhash[:], r.bytes(32))
+	n = r.compactU16()
+	for i := 0; i < n; i++ {
+		ix := CompiledInstruction{ProgramIndex: r.byte()}
+		ix.Accounts = r.bytes(r.compactU16())
+		ix.Data = r.bytes(r.compactU16())
+		m.Instructions = append(m.Instructions, ix)
+	}
+
+	if r.err || len(r.b) != 0 || int(m.RequiredSignatures) > len(m.Accounts) {
+		return nil, ErrMalformedTx
+	}
+	for _, ix := range m.Instructions {
+		if int(ix.ProgramIndex) >= len(m.Accounts) {
+			return nil, ErrMalformedTx
+		}
+		for _, a := range ix.Accounts {
+			if int(a) >= len(m.Accounts) {
+				return nil, ErrMalformedTx
+			}
+		}
+	}
+	return tx, nil
+}
+
+func appendCompactU16(b []byte, n int) []byte {
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// reader consumes a byte slice, latching err on underflow
+type reader struct {
+	b   []byte
+	err bool
+}
+
+func (r *reader) bytes(n int) []byte {
+	if n > len(r.b) {
+		r.err = true
+		r.b = nil
+		return make([]byte, n)
+	}
+	out := r.b[:n:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *reader) byte() byte {
+	return r.bytes(1)[0]
+}
+
+func (r *reader) compactU16() int {
+	n := 0
+	for shift := 0; shift < 21; shift += 7 {
+		c := r.byte()
+		n |= int(c&0x7f) << shift
+		if c&0x80 == 0 {
+			break
+		}
+	}
+	if n > 0xffff {
+		r.err = true
+		return 0
+	}
+	return n
+}
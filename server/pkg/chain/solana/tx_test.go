@@ -0,0 +1,73 @@
+package solana
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestTransferMessageLayout(t *testing.T) {
+	from := PublicKeyOf(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, 32)))
+	to := PublicKeyOf(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, 32)))
+
+	m := NewMessage(from, PublicKey{9}, SystemTransfer(from, to, 42))
+
+	if m.RequiredSignatures != 1 || m.ReadonlySigned != 0 || m.ReadonlyUnsigned != 1 {
+		t.Errorf("header = %d %d %d, want 1 0 1", m.RequiredSignatures, m.ReadonlySigned, m.ReadonlyUnsigned)
+	}
+	want := []PublicKey{from, to, SystemProgram}
+	for i, k := range want {
+		if m.Accounts[i] != k {
+			t.Errorf("account %d = %s, want %s", i, m.Accounts[i], k)
+		}
+	}
+	if !m.Writable(0) || !m.Writable(1) || m.Writable(2) {
+		t.Error("wrong writable accounts")
+	}
+	transfer := m.Instructions[0]
+	if transfer.ProgramIndex != 2 || !bytes.Equal(transfer.Accounts, []byte{0, 1}) {
+		t.Errorf("transfer instruction = %+v", transfer)
+	}
+	wantData := []byte{2, 0, 0, 0, 42, 0, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(transfer.Data, wantData) {
+		t.Errorf("transfer data = %v, want %v", transfer.Data, wantData)
+	}
+}
+
+func TestSignAndDecodeRoundTrip(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{3}, 32))
+	from := PublicKeyOf(key)
+	to := PublicKeyOf(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{4}, 32)))
+
+	tx, err := Sign(NewMessage(from, PublicKey{7}, SystemTransfer(from, to, 1)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tx.Verify() {
+		t.Fatal("signed transaction does not verify")
+	}
+
+	decoded, err := DecodeTransaction(tx.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Serialize(), tx.Serialize()) || !decoded.Verify() {
+		t.Error("decoded transaction differs")
+	}
+
+	// Tampering with the message breaks the signature
+	raw := tx.Serialize()
+	raw[len(raw)-1] ^= 1
+	tampered, err := DecodeTransaction(raw)
+	if err != nil || tampered.Verify() {
+		t.Error("tampered transaction verified")
+	}
+
+	if _, err := DecodeTransaction(raw[:len(raw)-3]); err == nil {
+		t.Error("truncated transaction decoded")
+	}
+
+	if _, err := Sign(NewMessage(from, PublicKey{7}), ed25519.NewKeyFromSeed(bytes.Repeat([]byte{5}, 32))); err == nil {
+		t.Error("signed without the fee payer's key")
+	}
+}
@@ -0,0 +1,316 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+const (
+	// pageSize is the most signatures requested per history page
+	pageSize = 1000
+
+	// statusBatch is the most signatures getSignatureStatuses accepts
+	statusBatch = 256
+)
+
+// ErrUnknownUser is returned by a Store for a memo naming no user
+var ErrUnknownUser = errors.New("solana: deposit memo names no user")
+
+// DepositAccount is a per-user deposit address and its derivation index
+type DepositAccount struct {
+	Address string
+	UserID  uuid.UUID
+	Index   uint32
+}
+
+// Store persists what the watcher has seen. DBStore is the production one.
+type Store interface {
+	// Cursor returns the newest processed signature for address, "" if none
+	Cursor(address string) (string, error)
+	SaveCursor(address, signature string) error
+
+	// DepositAccounts lists the per-user deposit addresses to watch
+	DepositAccounts() ([]DepositAccount, error)
+
+	// Announce records a seen but unconfirmed deposit; repeats are no-ops
+	Announce(userID uuid.UUID, currency models.Currency, amount int64, signature string) error
+
+	// Pending lists announced deposits awaiting confirmation
+	Pending() ([]models.Transaction, error)
+	Confirm(deposit *models.Transaction) error
+	Fail(deposit *models.Transaction) error
+}
+
+// Watcher credits SOL and SPL token deposits. Funds sent to a user's
+// deposit address are theirs; funds sent to the hot wallet are attributed
+// by a memo carrying the user ID. Tokens are watched in each address's
+// associated token account for the mint. A deposit is recorded as PENDING
+// when first seen and credited once it has Confirmations blocks on top of
+// it.
+//
+// Each address is polled separately, which is fine for thousands of users;
+// beyond that a webhook-based RPC provider should feed Sync instead.
+type Watcher struct {
+	RPC           RPC
+	Store         Store
+	HotWallet     string  // Memo deposits; empty to only watch deposit addresses
+	Tokens        []Token // Accepted besides SOL
+	Confirmations uint64
+	Poll          time.Duration
+
+	// DropAfter fails a pending deposit the node has not known for this long
+	DropAfter time.Duration
+}
+
+// NewWatcher creates a watcher backed by the database.
+func NewWatcher(rpc RPC, hotWallet string) *Watcher {
+	return &Watcher{
+		RPC:           rpc,
+		Store:         DBStore{},
+		HotWallet:     hotWallet,
+		Confirmations: finalityDepth,
+		Poll:          10 * time.Second,
+		DropAfter:     10 * time.Minute,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Poll)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Sync(ctx); err != nil {
+			log.Printf("Solana deposit watcher: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watched is an account the watcher scans
+type watched struct {
+	address string
+	owner   *uuid.UUID // Nil for the hot wallet, where memos name the user
+	token   *Token     // Nil for SOL
+}
+
+// Sync records new deposits and then confirms or fails pending ones.
+func (w *Watcher) Sync(ctx context.Context) error {
+	accounts, err := w.Store.DepositAccounts()
+	if err != nil {
+		return err
+	}
+
+	var all []watched
+	internal := make(map[string]bool, len(accounts)*(1+len(w.Tokens)))
+	add := func(address string, owner *uuid.UUID) error {
+		key, err := ParsePublicKey(address)
+		if err != nil {
+			return err
+		}
+		all = append(all, watched{address: address, owner: owner})
+		for i := range w.Tokens {
+			all = append(all, watched{address: w.Tokens[i].AccountOf(key).String(), owner: owner, token: &w.Tokens[i]})
+		}
+		return nil
+	}
+	if w.HotWallet != "" {
+		if err := add(w.HotWallet, nil); err != nil {
+			return err
+		}
+	}
+	for i := range accounts {
+		if err := add(accounts[i].Address, &accounts[i].UserID); err != nil {
+			return err
+		}
+	}
+	for _, a := range all {
+		if a.owner != nil {
+			internal[a.address] = true
+		}
+	}
+
+	// One failing address should not hold up the rest
+	var scanErr error
+	for _, a := range all {
+		if err := w.scan(ctx, a, internal); err != nil && scanErr == nil {
+			scanErr = err
+		}
+	}
+
+	if err := w.confirm(ctx); err != nil {
+		return err
+	}
+	return scanErr
+}
+
+// scan walks an account's history since its cursor, oldest first. Deposits
+// to an owned account belong to the owner; otherwise the memo names the
+// user. Transfers out of internal accounts, such as sweeps, are not
+// deposits.
+func (w *Watcher) scan(ctx context.Context, a watched, internal map[string]bool) error {
+	cursor, err := w.Store.Cursor(a.address)
+	if err != nil {
+		return err
+	}
+
+	// Page backwards to the cursor. Without a cursor only the most recent
+	// page is read, so a fresh install does not replay years of history.
+	var infos []SignatureInfo
+	before := ""
+	for {
+		page, err := w.RPC.SignaturesForAddress(ctx, a.address, before, cursor, pageSize)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, page...)
+		if len(page) < pageSize || cursor == "" {
+			break
+		}
+		before = page[len(page)-1].Signature
+	}
+
+	for i := len(infos) - 1; i >= 0; i-- {
+		info := infos[i]
+		if !info.Failed {
+			tx, err := w.RPC.Transaction(ctx, info.Signature)
+			if errors.Is(err, ErrNotFound) {
+				return nil // Not queryable yet; pick it up next time
+			}
+			if err != nil {
+				return err
+			}
+			if err := w.record(tx, a, internal); err != nil {
+				return err
+			}
+		}
+		if err := w.Store.SaveCursor(a.address, info.Signature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record announces tx if it is an attributable deposit. Deposits that
+// cannot be attributed are logged for manual review and skipped.
+func (w *Watcher) record(tx *Transaction, a watched, internal map[string]bool) error {
+	currency, amount := models.CurrencySOL, received(tx, a.address, internal)
+	if a.token != nil {
+		currency, amount = a.token.Currency, tokensReceived(tx, a.address, a.token.Mint, internal)
+	}
+	if tx.Failed || amount == 0 {
+		return nil
+	}
+
+	userID, ok := uuid.Nil, a.owner != nil
+	if a.owner != nil {
+		userID = *a.owner
+	} else {
+		userID, ok = memoUser(tx.Memos)
+	}
+	if !ok {
+		log.Printf("Solana deposit %s of %d %s base units has no user memo, needs manual review", tx.Signature, amount, currency)
+		return nil
+	}
+
+	err := w.Store.Announce(userID, currency, int64(amount), tx.Signature)
+	if errors.Is(err, ErrUnknownUser) {
+		log.Printf("Solana deposit %s names unknown user %s, needs manual review", tx.Signature, userID)
+		return nil
+	}
+	return err
+}
+
+// confirm settles pending deposits that are deep enough or gone
+func (w *Watcher) confirm(ctx context.Context) error {
+	pending, err := w.Store.Pending()
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(pending); start += statusBatch {
+		batch := pending[start:min(start+statusBatch, len(pending))]
+		signatures := make([]string, len(batch))
+		for i, d := range batch {
+			signatures[i] = d.TxHash
+		}
+
+		statuses, err := w.RPC.SignatureStatuses(ctx, signatures)
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			deposit := &batch[i]
+			var status *SignatureStatus
+			var err error
+			if i < len(statuses) {
+				status = statuses[i]
+			}
+
+			switch {
+			case status == nil && time.Since(deposit.CreatedAt) > w.DropAfter:
+				log.Printf("Solana deposit %s disappeared from the chain, marking failed", deposit.TxHash)
+				err = w.Store.Fail(deposit)
+			case status == nil:
+				continue
+			case status.Failed:
+				err = w.Store.Fail(deposit)
+			case status.Finalized || status.Confirmations >= w.Confirmations:
+				err = w.Store.Confirm(deposit)
+				if err == nil {
+					log.Printf("Credited Solana deposit %s: %d %s base units to user %s", deposit.TxHash, deposit.Amount, deposit.Currency, deposit.UserID)
+				}
+			}
+			if err != nil {
+				log.Printf("Failed to settle Solana deposit %s: %v", deposit.TxHash, err)
+			}
+		}
+	}
+	return nil
+}
+
+// received sums the lamports transferred to address from outside
+func received(tx *Transaction, address string, internal map[string]bool) uint64 {
+	var total uint64
+	for _, t := range tx.Transfers {
+		if t.To == address && !internal[t.From] {
+			total += t.Lamports
+		}
+	}
+	return total
+}
+
+// tokensReceived sums the tokens of mint transferred to the token account
+// from outside. Plain transfers do not name the mint, but a token account
+// only ever holds one.
+func tokensReceived(tx *Transaction, account string, mint PublicKey, internal map[string]bool) uint64 {
+	var total uint64
+	for _, t := range tx.TokenTransfers {
+		if t.To == account && !internal[t.From] && (t.Mint == "" || t.Mint == mint.String()) {
+			total += t.Amount
+		}
+	}
+	return total
+}
+
+// memoUser finds the user ID in a deposit's memos
+func memoUser(memos []string) (uuid.UUID, bool) {
+	for _, memo := range memos {
+		if id, err := uuid.Parse(strings.TrimSpace(memo)); err == nil {
+			return id, true
+		}
+	}
+	return uuid.Nil, false
+}
@@ -0,0 +1,314 @@
+package solana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// memStore is an in-memory Store
+type memStore struct {
+	cursors  map[string]string
+	accounts []DepositAccount
+	deposits map[depositID]*models.Transaction
+	users    map[uuid.UUID]bool
+}
+
+// depositID identifies a deposit: one signature can pay several users
+type depositID struct {
+	signature string
+	user      uuid.UUID
+}
+
+func newMemStore(users ...uuid.UUID) *memStore {
+	s := &memStore{cursors: map[string]string{}, deposits: map[depositID]*models.Transaction{}, users: map[uuid.UUID]bool{}}
+	for _, u := range users {
+		s.users[u] = true
+	}
+	return s
+}
+
+func (s *memStore) Cursor(address string) (string, error) { return s.cursors[address], nil }
+
+func (s *memStore) SaveCursor(address, signature string) error {
+	s.cursors[address] = signature
+	return nil
+}
+
+func (s *memStore) DepositAccounts() ([]DepositAccount, error) { return s.accounts, nil }
+
+func (s *memStore) Announce(userID uuid.UUID, currency models.Currency, amount int64, signature string) error {
+	if !s.users[userID] {
+		return ErrUnknownUser
+	}
+	if id := (depositID{signature, userID}); s.deposits[id] == nil {
+		s.deposits[id] = &models.Transaction{
+			ID: uuid.New(), UserID: userID, Currency: currency, Amount: amount, TxHash: signature,
+			Type: models.TxTypeDeposit, Status: models.TxStatusPending, CreatedAt: time.Now(),
+		}
+	}
+	return nil
+}
+
+func (s *memStore) Pending() ([]models.Transaction, error) {
+	var pending []models.Transaction
+	for _, d := range s.deposits {
+		if d.Status == models.TxStatusPending {
+			pending = append(pending, *d)
+		}
+	}
+	return pending, nil
+}
+
+func (s *memStore) Confirm(d *models.Transaction) error {
+	s.deposits[depositID{d.TxHash, d.UserID}].Status = models.TxStatusCompleted
+	return nil
+}
+
+func (s *memStore) Fail(d *models.Transaction) error {
+	s.deposits[depositID{d.TxHash, d.UserID}].Status = models.TxStatusFailed
+	return nil
+}
+
+const (
+	hotWallet = "Hot1111111111111111111111111111111111111111"
+	sender    = "Sender11111111111111111111111111111111111111"
+)
+
+func newTestWatcher(chain *Fake, store *memStore) *Watcher {
+	w := NewWatcher(chain, hotWallet)
+	w.Store = store
+	w.Confirmations = 5
+	return w
+}
+
+func TestWatcherCreditsAfterConfirmations(t *testing.T) {
+	ctx := context.Background()
+	user := uuid.New()
+	chain := NewFake()
+	store := newMemStore(user)
+	w := newTestWatcher(chain, store)
+
+	sig := chain.Transfer(sender, hotWallet, 2*LamportsPerSOL, user.String())
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deposit := store.deposits[depositID{sig, user}]
+	if deposit == nil || deposit.Status != models.TxStatusPending || deposit.Amount != 2*LamportsPerSOL {
+		t.Fatalf("deposit after first sync = %+v, want pending 2 SOL", deposit)
+	}
+
+	chain.Advance(4)
+	w.Sync(ctx)
+	if deposit.Status != models.TxStatusPending {
+		t.Fatalf("credited with 4 confirmations, want 5")
+	}
+
+	chain.Advance(1)
+	w.Sync(ctx)
+	if deposit.Status != models.TxStatusCompleted {
+		t.Fatalf("status = %s after 5 confirmations, want COMPLETED", deposit.Status)
+	}
+
+	// Nothing is announced twice on later passes
+	w.Sync(ctx)
+	if len(store.deposits) != 1 {
+		t.Errorf("got %d deposits, want 1", len(store.deposits))
+	}
+}
+
+func TestWatcherSkipsUnattributable(t *testing.T) {
+	ctx := context.Background()
+	user := uuid.New()
+	chain := NewFake()
+	store := newMemStore(user)
+	w := newTestWatcher(chain, store)
+
+	chain.Transfer(sender, hotWallet, LamportsPerSOL, "")                    // No memo
+	chain.Transfer(sender, hotWallet, LamportsPerSOL, "hello")               // Not a user ID
+	chain.Transfer(sender, hotWallet, LamportsPerSOL, uuid.New().String())   // Unknown user
+	chain.FailedTransfer(sender, hotWallet, LamportsPerSOL, user.String())   // Reverted
+	chain.Transfer(hotWallet, sender, LamportsPerSOL, user.String())         // Outgoing
+	last := chain.Transfer(sender, hotWallet, LamportsPerSOL, user.String()) // Real deposit
+
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.deposits) != 1 || store.deposits[depositID{last, user}] == nil {
+		t.Errorf("deposits = %v, want only %s", store.deposits, last)
+	}
+	if store.cursors[hotWallet] != last {
+		t.Errorf("cursor = %s, want %s", store.cursors[hotWallet], last)
+	}
+}
+
+func TestWatcherFailsDroppedDeposit(t *testing.T) {
+	ctx := context.Background()
+	user := uuid.New()
+	chain := NewFake()
+	store := newMemStore(user)
+	w := newTestWatcher(chain, store)
+	w.DropAfter = 0
+
+	sig := chain.Transfer(sender, hotWallet, LamportsPerSOL, user.String())
+	w.Sync(ctx)
+	chain.Drop(sig)
+	w.Sync(ctx)
+
+	if status := store.deposits[depositID{sig, user}].Status; status != models.TxStatusFailed {
+		t.Errorf("status = %s, want FAILED", status)
+	}
+}
+
+func TestDepositAddressesAndSweep(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	chain := NewFake()
+	store := newMemStore(alice, bob)
+	hd, _ := NewHDWallet([]byte("0123456789abcdef0123456789abcdef"))
+	hot := PublicKeyOf(hd.Derive(1).PrivateKey()).String()
+	store.accounts = []DepositAccount{
+		{Address: hd.DepositAddress(0), UserID: alice, Index: 0},
+		{Address: hd.DepositAddress(1), UserID: bob, Index: 1},
+	}
+
+	w := NewWatcher(chain, hot)
+	w.Store = store
+	w.Confirmations = 1
+
+	// No memo needed: the address identifies the user
+	aliceSig := chain.Transfer(sender, hd.DepositAddress(0), 3*LamportsPerSOL, "")
+	bobSig := chain.Transfer(sender, hd.DepositAddress(1), 1000, "")
+	chain.Advance(1)
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := store.deposits[depositID{aliceSig, alice}]; d == nil || d.UserID != alice || d.Status != models.TxStatusCompleted {
+		t.Fatalf("alice deposit = %+v", d)
+	}
+	if d := store.deposits[depositID{bobSig, bob}]; d == nil || d.UserID != bob {
+		t.Fatalf("bob deposit = %+v", d)
+	}
+
+	sweeper := NewSweeper(chain, hd, hot)
+	sweeper.Store = store
+	sent, err := sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("swept %d addresses, want 1 (bob holds dust)", len(sent))
+	}
+
+	hotBalance, _ := chain.Balance(ctx, hot)
+	aliceBalance, _ := chain.Balance(ctx, hd.DepositAddress(0))
+	if hotBalance != 3*LamportsPerSOL-FeePerSignature || aliceBalance != 0 {
+		t.Errorf("after sweep hot = %d, alice = %d", hotBalance, aliceBalance)
+	}
+
+	// The sweep lands in the hot wallet history but is not a deposit
+	chain.Advance(1)
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.deposits) != 2 {
+		t.Errorf("got %d deposits after sweep, want 2", len(store.deposits))
+	}
+}
+
+func TestBatchedDepositCreditsEveryAddress(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	chain := NewFake()
+	store := newMemStore(alice, bob)
+	hd, _ := NewHDWallet([]byte("0123456789abcdef0123456789abcdef"))
+	store.accounts = []DepositAccount{
+		{Address: hd.DepositAddress(0), UserID: alice, Index: 0},
+		{Address: hd.DepositAddress(1), UserID: bob, Index: 1},
+	}
+	w := newTestWatcher(chain, store)
+	w.Confirmations = 1
+
+	// One exchange transaction pays both deposit addresses
+	sig := chain.BatchTransfer(sender, map[string]uint64{
+		hd.DepositAddress(0): 2 * LamportsPerSOL,
+		hd.DepositAddress(1): 3 * LamportsPerSOL,
+	})
+	chain.Advance(1)
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for user, want := range map[uuid.UUID]int64{alice: 2 * LamportsPerSOL, bob: 3 * LamportsPerSOL} {
+		d := store.deposits[depositID{sig, user}]
+		if d == nil || d.Amount != want || d.Status != models.TxStatusCompleted {
+			t.Errorf("deposit of %s = %+v, want %d completed", user, d, want)
+		}
+	}
+	if len(store.deposits) != 2 {
+		t.Errorf("got %d deposits, want 2", len(store.deposits))
+	}
+}
+
+func TestTokenDepositsAndSweep(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	chain := NewFake()
+	store := newMemStore(alice, bob)
+	hd, _ := NewHDWallet([]byte("0123456789abcdef0123456789abcdef"))
+	hotSigner := NewKeypairSigner(hd.Derive(1).PrivateKey())
+	hot := hotSigner.PublicKey()
+	chain.Transfer(sender, hot.String(), LamportsPerSOL, "")
+	store.accounts = []DepositAccount{{Address: hd.DepositAddress(0), UserID: alice, Index: 0}}
+	depositKey, _ := ParsePublicKey(hd.DepositAddress(0))
+
+	w := NewWatcher(chain, hot.String())
+	w.Store = store
+	w.Tokens = []Token{USDT}
+	w.Confirmations = 1
+
+	// Into alice's deposit address, and to the hot wallet with bob's memo
+	aliceSig := chain.TransferToken(sender, depositKey, USDTMint, 25_000_000, "")
+	bobSig := chain.TransferToken(sender, hot, USDTMint, 5_000_000, bob.String())
+	chain.Advance(1)
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := store.deposits[depositID{aliceSig, alice}]; d == nil || d.UserID != alice || d.Currency != models.CurrencyUSDT || d.Amount != 25_000_000 || d.Status != models.TxStatusCompleted {
+		t.Fatalf("alice deposit = %+v", d)
+	}
+	if d := store.deposits[depositID{bobSig, bob}]; d == nil || d.UserID != bob || d.Currency != models.CurrencyUSDT {
+		t.Fatalf("bob deposit = %+v", d)
+	}
+
+	sweeper := NewSweeper(chain, hd, hot.String())
+	sweeper.Store = store
+	sweeper.Tokens = []Token{USDT}
+	sweeper.FeePayer = hotSigner
+	sent, err := sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("swept %d times, want 1", len(sent))
+	}
+
+	hotTokens, _ := chain.TokenBalance(ctx, USDT.AccountOf(hot).String())
+	aliceTokens, _ := chain.TokenBalance(ctx, USDT.AccountOf(depositKey).String())
+	if hotTokens != 30_000_000 || aliceTokens != 0 {
+		t.Errorf("after sweep hot = %d, alice = %d", hotTokens, aliceTokens)
+	}
+
+	// The sweep is not a deposit into the hot wallet
+	chain.Advance(1)
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.deposits) != 2 {
+		t.Errorf("got %d deposits after sweep, want 2", len(store.deposits))
+	}
+}
@@ -0,0 +1,18 @@
+package ton
+
+import "context"
+
+// Account reads the balance of one address. It implements chain.HotWallet.
+type Account struct {
+	Client Client
+	Wallet Address
+}
+
+func (a *Account) Address() string {
+	return a.Wallet.String()
+}
+
+func (a *Account) Balance(ctx context.Context) (int64, error) {
+	nanotons, err := a.Client.Balance(ctx, a.Wallet.String())
+	return int64(nanotons), err
+}
@@ -0,0 +1,107 @@
+package ton
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Address tags of the user-friendly form
+const (
+	tagBounceable    = 0x11
+	tagNonBounceable = 0x51
+	tagTestnet       = 0x80
+)
+
+// Address is a standard account address: a workchain and a 256-bit account ID
+type Address struct {
+	Workchain int8
+	Hash      [32]byte
+}
+
+// ParseAddress accepts the raw form ("0:<hex>") and the 48-character
+// user-friendly form in either base64 alphabet.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	if wc, hash, ok := strings.Cut(s, ":"); ok {
+		n, err := strconv.ParseInt(wc, 10, 8)
+		b, herr := hex.DecodeString(hash)
+		if err != nil || herr != nil || len(b) != len(a.Hash) {
+			return a, fmt.Errorf("ton: invalid address %q", s)
+		}
+		a.Workchain = int8(n)
+		copy(a.Hash[:], b)
+		return a, nil
+	}
+
+	if len(s) != 48 {
+		return a, fmt.Errorf("ton: invalid address %q", s)
+	}
+	b, err := base64.URLEncoding.DecodeString(strings.NewReplacer("+", "-", "/", "_").Replace(s))
+	if err != nil || len(b) != 36 {
+		return a, fmt.Errorf("ton: invalid address %q", s)
+	}
+	if tag := b[0] &^ tagTestnet; tag != tagBounceable && tag != tagNonBounceable {
+		return a, fmt.Errorf("ton: invalid address tag in %q", s)
+	}
+	if crc16(b[:34]) != binary.BigEndian.Uint16(b[34:]) {
+		return a, fmt.Errorf("ton: invalid address checksum in %q", s)
+	}
+	a.Workchain = int8(b[1])
+	copy(a.Hash[:], b[2:34])
+	return a, nil
+}
+
+// MustParseAddress is ParseAddress for constants.
+func MustParseAddress(s string) Address {
+	a, err := ParseAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// String returns the bounceable mainnet user-friendly form.
+func (a Address) String() string {
+	return a.Friendly(true, false)
+}
+
+// Friendly returns the url-safe user-friendly form.
+func (a Address) Friendly(bounceable, testnet bool) string {
+	b := make([]byte, 36)
+	b[0] = tagNonBounceable
+	if bounceable {
+		b[0] = tagBounceable
+	}
+	if testnet {
+		b[0] |= tagTestnet
+	}
+	b[1] = byte(a.Workchain)
+	copy(b[2:], a.Hash[:])
+	binary.BigEndian.PutUint16(b[34:], crc16(b[:34]))
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// Raw returns the "workchain:hex" form.
+func (a Address) Raw() string {
+	return strconv.Itoa(int(a.Workchain)) + ":" + hex.EncodeToString(a.Hash[:])
+}
+
+// crc16 is CRC-16/XMODEM, the address checksum
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,40 @@
+package ton
+
+import "testing"
+
+func TestAddressForms(t *testing.T) {
+	zero := Address{}
+	if got := zero.String(); got != "EQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAM9c" {
+		t.Errorf("bounceable = %s", got)
+	}
+	if got := zero.Friendly(false, false); got != "UQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAJKZ" {
+		t.Errorf("non-bounceable = %s", got)
+	}
+
+	a := Address{Workchain: -1}
+	a.Hash[0], a.Hash[31] = 0xfe, 0x01
+	for _, s := range []string{a.Raw(), a.String(), a.Friendly(false, true)} {
+		parsed, err := ParseAddress(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if parsed != a {
+			t.Errorf("%s parsed as %s", s, parsed.Raw())
+		}
+	}
+}
+
+func TestParseAddressRejects(t *testing.T) {
+	valid := Address{}.String()
+	for _, s := range []string{
+		"",
+		"0:abcd",
+		valid[:47] + "d", // Checksum
+		"x" + valid[1:],  // Tag
+		valid + "AAAA",
+	} {
+		if _, err := ParseAddress(s); err == nil {
+			t.Errorf("%q parsed", s)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package ton
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+)
+
+const (
+	maxCellBits = 1023
+	maxCellRefs = 4
+)
+
+// bocMagic starts every serialized bag of cells
+var bocMagic = []byte{0xb5, 0xee, 0x9c, 0x72}
+
+// ErrMalformedBOC is returned when a bag of cells cannot be decoded
+var ErrMalformedBOC = errors.New("ton: malformed bag of cells")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Cell is an ordinary cell: up to 1023 bits and four references
+type Cell struct {
+	data []byte // Bits, most significant first
+	bits int
+	refs []*Cell
+}
+
+// Bits returns the number of data bits.
+func (c *Cell) Bits() int { return c.bits }
+
+// Refs returns the referenced cells.
+func (c *Cell) Refs() []*Cell { return c.refs }
+
+// Depth is zero for a leaf, else one more than the deepest reference.
+func (c *Cell) Depth() uint16 {
+	var depth uint16
+	for _, r := range c.refs {
+		if d := r.Depth() + 1; d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// descriptors returns the two descriptor bytes of an ordinary cell
+func (c *Cell) descriptors() []byte {
+	return []byte{byte(len(c.refs)), byte(c.bits/8 + (c.bits+7)/8)}
+}
+
+// paddedData appends the completion tag to a partial last byte
+func (c *Cell) paddedData() []byte {
+	out := make([]byte, (c.bits+7)/8)
+	copy(out, c.data)
+	if c.bits%8 != 0 {
+		out[len(out)-1] |= 0x80 >> (c.bits % 8)
+	}
+	return out
+}
+
+// Hash is the representation hash that identifies the cell.
+func (c *Cell) Hash() []byte {
+	h := sha256.New()
+	h.Write(c.descriptors())
+	h.Write(c.paddedData())
+	for _, r := range c.refs {
+		binary.Write(h, binary.BigEndian, r.Depth())
+	}
+	for _, r := range c.refs {
+		h.Write(r.Hash())
+	}
+	return h.Sum(nil)
+}
+
+// Builder assembles a cell
+type Builder struct {
+	cell Cell
+	err  error
+}
+
+// NewBuilder starts an empty cell.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) storeBit(bit bool) {
+	if b.cell.bits >= maxCellBits {
+		b.err = fmt.Errorf("ton: cell overflow")
+		return
+	}
+	if b.cell.bits%8 == 0 {
+		b.cell.data = append(b.cell.data, 0)
+	}
+	if bit {
+		b.cell.data[b.cell.bits/8] |= 0x80 >> (b.cell.bits % 8)
+	}
+	b.cell.bits++
+}
+
+// StoreBit appends one bit.
+func (b *Builder) StoreBit(bit bool) *Builder {
+	b.storeBit(bit)
+	return b
+}
+
+// StoreUint appends v as an n-bit unsigned integer, n <= 64.
+func (b *Builder) StoreUint(v uint64, n int) *Builder {
+	if n < 64 && v>>n != 0 {
+		b.err = fmt.Errorf("ton: %d does not fit in %d bits", v, n)
+		return b
+	}
+	for i := n - 1; i >= 0; i-- {
+		b.storeBit(v>>i&1 == 1)
+	}
+	return b
+}
+
+// StoreInt appends v as an n-bit two's complement integer.
+func (b *Builder) StoreInt(v int64, n int) *Builder {
+	return b.StoreUint(uint64(v)&(1<<n-1), n)
+}
+
+// StoreBytes appends whole bytes.
+func (b *Builder) StoreBytes(p []byte) *Builder {
+	for _, c := range p {
+		b.StoreUint(uint64(c), 8)
+	}
+	return b
+}
+
+// StoreCoins appends an amount of nanotons as VarUInteger 16.
+func (b *Builder) StoreCoins(v uint64) *Builder {
+	n := 0
+	for x := v; x > 0; x >>= 8 {
+		n++
+	}
+	b.StoreUint(uint64(n), 4)
+	for i := n - 1; i >= 0; i-- {
+		b.StoreUint(v>>(8*i)&0xff, 8)
+	}
+	return b
+}
+
+// StoreAddress appends a MsgAddressInt, or addr_none for nil.
+func (b *Builder) StoreAddress(a *Address) *Builder {
+	if a == nil {
+		return b.StoreUint(0, 2)
+	}
+	b.StoreUint(0b100, 3) // addr_std$10, no anycast
+	b.StoreInt(int64(a.Workchain), 8)
+	return b.StoreBytes(a.Hash[:])
+}
+
+// StoreRef appends a reference to c.
+func (b *Builder) StoreRef(c *Cell) *Builder {
+	if len(b.cell.refs) >= maxCellRefs {
+		b.err = fmt.Errorf("ton: too many cell references")
+		return b
+	}
+	b.cell.refs = append(b.cell.refs, c)
+	return b
+}
+
+// StoreSlice appends the remaining bits and references of s.
+func (b *Builder) StoreSlice(s *Slice) *Builder {
+	for s.pos < s.cell.bits {
+		b.storeBit(s.bit())
+	}
+	for s.ref < len(s.cell.refs) {
+		b.StoreRef(s.cell.refs[s.ref])
+		s.ref++
+	}
+	return b
+}
+
+// Cell finishes the cell.
+func (b *Builder) Cell() (*Cell, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	c := b.cell
+	return &c, nil
+}
+
+// Slice reads a cell from the start
+type Slice struct {
+	cell *Cell
+	pos  int
+	ref  int
+	err  error
+}
+
+// BeginParse starts reading c.
+func (c *Cell) BeginParse() *Slice {
+	return &Slice{cell: c}
+}
+
+// Err returns the first read past the end of the cell, if any.
+func (s *Slice) Err() error { return s.err }
+
+// RemainingBits is the number of unread bits.
+func (s *Slice) RemainingBits() int { return s.cell.bits - s.pos }
+
+func (s *Slice) bit() bool {
+	if s.pos >= s.cell.bits {
+		s.err = fmt.Errorf("ton: cell underflow")
+		return false
+	}
+	bit := s.cell.data[s.pos/8]&(0x80>>(s.pos%8)) != 0
+	s.pos++
+	return bit
+}
+
+// LoadBit reads one bit.
+func (s *Slice) LoadBit() bool { return s.bit() }
+
+// LoadUint reads an n-bit unsigned integer, n <= 64.
+func (s *Slice) LoadUint(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		v <<= 1
+		if s.bit() {
+			v |= 1
+		}
+	}
+	return v
+}
+
+// LoadInt reads an n-bit two's complement integer.
+func (s *Slice) LoadInt(n int) int64 {
+	v := s.LoadUint(n)
+	if n < 64 && v&(1<<(n-1)) != 0 {
+		v |= ^uint64(0) << n
+	}
+	return int64(v)
+}
+
+// LoadBytes reads n whole bytes.
+func (s *Slice) LoadBytes(n int) []byte {
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(s.LoadUint(8))
+	}
+	return out
+}
+
+// LoadCoins reads a VarUInteger 16 amount. Amounts beyond 64 bits are an
+// error.
+func (s *Slice) LoadCoins() uint64 {
+	n := int(s.LoadUint(4))
+	v := new(big.Int).SetBytes(s.LoadBytes(n))
+	if !v.IsUint64() {
+		s.err = fmt.Errorf("ton: amount out of range")
+		return 0
+	}
+	return v.Uint64()
+}
+
+// LoadAddress reads a MsgAddress, returning nil for addr_none. Only
+// addr_none and addr_std without anycast are supported.
+func (s *Slice) LoadAddress() *Address {
+	switch s.LoadUint(2) {
+	case 0b00:
+		return nil
+	case 0b10:
+		if s.LoadBit() {
+			s.err = fmt.Errorf("ton: anycast addresses are not supported")
+			return nil
+		}
+		a := &Address{Workchain: int8(s.LoadInt(8))}
+		copy(a.Hash[:], s.LoadBytes(32))
+		return a
+	default:
+		s.err = fmt.Errorf("ton: unsupported address kind")
+		return nil
+	}
+}
+
+// LoadRef reads the next reference.
+func (s *Slice) LoadRef() *Cell {
+	if s.ref >= len(s.cell.refs) {
+		s.err = fmt.Errorf("ton: no more cell references")
+		return &Cell{}
+	}
+	c := s.cell.refs[s.ref]
+	s.ref++
+	return c
+}
+
+// ToBOC serializes a tree of cells rooted at c as a bag of cells with a
+// CRC32-C checksum.
+func (c *Cell) ToBOC() []byte {
+	// Parents must precede their references: reverse post-order
+	var order []*Cell
+	seen := map[*Cell]bool{}
+	var visit func(*Cell)
+	visit = func(cell *Cell) {
+		if seen[cell] {
+			return
+		}
+		seen[cell] = true
+		for _, r := range cell.refs {
+			visit(r)
+		}
+		order = append(order, cell)
+	}
+	visit(c)
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	index := make(map[*Cell]int, len(order))
+	for i, cell := range order {
+		index[cell] = i
+	}
+
+	sizeBytes := byteLen(uint64(len(order)))
+	var cells []byte
+	for _, cell := range order {
+		cells = append(cells, cell.descriptors()...)
+		cells = append(cells, cell.paddedData()...)
+		for _, r := range cell.refs {
+			cells = appendUint(cells, uint64(index[r]), sizeBytes)
+		}
+	}
+	offBytes := byteLen(uint64(len(cells)))
+
+	out := append([]byte{}, bocMagic...)
+	out = append(out, 0x40|byte(sizeBytes)) // has_crc32c
+	out = append(out, byte(offBytes))
+	out = appendUint(out, uint64(len(order)), sizeBytes) // cells
+	out = appendUint(out, 1, sizeBytes)                  // roots
+	out = appendUint(out, 0, sizeBytes)                  // absent
+	out = appendUint(out, uint64(len(cells)), offBytes)
+	out = appendUint(out, 0, sizeBytes) // root index
+	out = append(out, cells...)
+	return binary.LittleEndian.AppendUint32(out, crc32.Checksum(out, castagnoli))
+}
+
+// ParseBOC decodes a bag of cells with a single root.
+func ParseBOC(boc []byte) (*Cell, error) {
+	if len(boc) < 6 || !bytes.Equal(boc[:4], bocMagic) {
+		return nil, ErrMalformedBOC
+	}
+	flags := boc[4]
+	hasIndex, hasCRC := flags&0x80 != 0, flags&0x40 != 0
+	sizeBytes, offBytes := int(flags&0x07), int(boc[5])
+	if sizeBytes == 0 || sizeBytes > 4 || offBytes == 0 || offBytes > 8 {
+		return nil, ErrMalformedBOC
+	}
+	if hasCRC {
+		if len(boc) < 4 {
+			return nil, ErrMalformedBOC
+		}
+		body := boc[:len(boc)-4]
+		if crc32.Checksum(body, castagnoli) != binary.LittleEndian.Uint32(boc[len(boc)-4:]) {
+			return nil, fmt.Errorf("%w: checksum mismatch", ErrMalformedBOC)
+		}
+		boc = body
+	}
+
+	r := &bocReader{b: boc[6:]}
+	count := int(r.uint(sizeBytes))
+	roots := int(r.uint(sizeBytes))
+	r.uint(sizeBytes) // absent
+	r.uint(offBytes)  // total cell bytes
+	if roots != 1 || count == 0 || count > len(boc) {
+		return nil, ErrMalformedBOC
+	}
+	root := int(r.uint(sizeBytes))
+	if hasIndex {
+		r.bytes(count * offBytes)
+	}
+
+	type raw struct {
+		cell *Cell
+		refs []int
+	}
+	raws := make([]raw, count)
+	for i := range raws {
+		d := r.bytes(2)
+		if r.err {
+			return nil, ErrMalformedBOC
+		}
+		refCount, dataLen := int(d[0]&0x07), (int(d[1])+1)/2
+		if d[0]&0x08 != 0 || refCount > maxCellRefs {
+			return nil, fmt.Errorf("%w: exotic cells are not supported", ErrMalformedBOC)
+		}
+		cell := &Cell{data: append([]byte{}, r.bytes(dataLen)...), bits: dataLen * 8}
+		if d[1]%2 == 1 && dataLen > 0 {
+			// Strip the completion tag
+			last := cell.data[dataLen-1]
+			if last == 0 {
+				return nil, ErrMalformedBOC
+			}
+			trailing := 0
+			for last&1 == 0 {
+				last >>= 1
+				trailing++
+			}
+			cell.bits -= trailing + 1
+			cell.data[dataLen-1] &^= 1 << trailing
+		}
+		raws[i].cell = cell
+		for j := 0; j < refCount; j++ {
+			raws[i].refs = append(raws[i].refs, int(r.uint(sizeBytes)))
+		}
+	}
+	if r.err || len(r.b) != 0 || root >= count {
+		return nil, ErrMalformedBOC
+	}
+
+	// References point forward, so resolve from the end
+	for i := count - 1; i >= 0; i-- {
+		for _, ref := range raws[i].refs {
+			if ref <= i || ref >= count {
+				return nil, ErrMalformedBOC
+			}
+			raws[i].cell.refs = append(raws[i].cell.refs, raws[ref].cell)
+		}
+	}
+	return raws[root].cell, nil
+}
+
+// byteLen is the number of bytes needed to store n, at least one
+func byteLen(n uint64) int {
+	size := 1
+	for n >= 1<<(8*size) && size < 8 {
+		size++
+	}
+	return size
+}
+
+func appendUint(b []byte, v uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// bocReader consumes a byte slice, latching err on underflow
+type bocReader struct {
+	b   []byte
+	err bool
+}
+
+func (r *bocReader) bytes(n int) []byte {
+	if n > len(r.b) {
+		r.err = true
+		r.b = nil
+		return make([]byte, n)
+	}
+	out := r.b[:n:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *bocReader) uint(size int) uint64 {
+	var v uint64
+	for _, c := range r.bytes(size) {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
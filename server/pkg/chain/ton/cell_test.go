@@ -0,0 +1,79 @@
+package ton
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEmptyCell(t *testing.T) {
+	c, err := NewBuilder().Cell()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(c.Hash()); got != "96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7" {
+		t.Errorf("hash = %s", got)
+	}
+	if got := base64.StdEncoding.EncodeToString(c.ToBOC()); got != "te6cckEBAQEAAgAAAEysuc0=" {
+		t.Errorf("boc = %s", got)
+	}
+}
+
+func TestBOCRoundTrip(t *testing.T) {
+	dest := MustParseAddress("0:" + hex.EncodeToString(bytes.Repeat([]byte{0xab}, 32)))
+	leaf, _ := NewBuilder().StoreUint(0xdeadbeef, 32).StoreBit(true).Cell()
+	root, err := NewBuilder().
+		StoreUint(5, 3).
+		StoreInt(-1, 8).
+		StoreCoins(1_500_000_000).
+		StoreAddress(&dest).
+		StoreAddress(nil).
+		StoreRef(leaf).
+		StoreRef(leaf).
+		Cell()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseBOC(root.ToBOC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Hash(), root.Hash()) {
+		t.Fatal("hash changed in round trip")
+	}
+
+	s := parsed.BeginParse()
+	if v := s.LoadUint(3); v != 5 {
+		t.Errorf("uint = %d", v)
+	}
+	if v := s.LoadInt(8); v != -1 {
+		t.Errorf("int = %d", v)
+	}
+	if v := s.LoadCoins(); v != 1_500_000_000 {
+		t.Errorf("coins = %d", v)
+	}
+	if a := s.LoadAddress(); a == nil || *a != dest {
+		t.Errorf("address = %v", a)
+	}
+	if a := s.LoadAddress(); a != nil {
+		t.Errorf("addr_none = %v", a)
+	}
+	ref := s.LoadRef().BeginParse()
+	if v := ref.LoadUint(32); v != 0xdeadbeef || !ref.LoadBit() {
+		t.Errorf("ref = %x", v)
+	}
+	if s.Err() != nil || ref.Err() != nil {
+		t.Fatal(s.Err(), ref.Err())
+	}
+}
+
+func TestParseBOCRejectsCorruption(t *testing.T) {
+	c, _ := NewBuilder().StoreUint(42, 16).Cell()
+	boc := c.ToBOC()
+	boc[len(boc)-6] ^= 1
+	if _, err := ParseBOC(boc); err == nil {
+		t.Fatal("corrupted BOC parsed")
+	}
+}
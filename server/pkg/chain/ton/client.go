@@ -0,0 +1,186 @@
+package ton
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPClient talks to a toncenter v2 API, e.g. https://toncenter.com/api/v2
+type HTTPClient struct {
+	URL    string
+	APIKey string // Optional; raises the rate limit
+	HTTP   *http.Client
+}
+
+// NewHTTPClient creates a client for the API at url.
+func NewHTTPClient(url, apiKey string) *HTTPClient {
+	return &HTTPClient{
+		URL:    strings.TrimRight(url, "/"),
+		APIKey: apiKey,
+		HTTP:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type apiError struct {
+	Code    int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("ton: api error %d: %s", e.Code, e.Message)
+}
+
+// call performs one API request, a GET with query or a POST with body, and
+// decodes its result into out
+func (c *HTTPClient) call(ctx context.Context, method string, query url.Values, body interface{}, out interface{}) error {
+	endpoint := c.URL + "/" + method
+	var req *http.Request
+	var err error
+	if body == nil {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	} else {
+		var encoded []byte
+		if encoded, err = json.Marshal(body); err != nil {
+			return err
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(encoded))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Errors come back as non-200 with the same envelope
+	var envelope struct {
+		OK     bool            `json:"ok"`
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+		Code   int             `json:"code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("ton: unexpected status %d from %s", resp.StatusCode, method)
+	}
+	if !envelope.OK {
+		return &apiError{Code: envelope.Code, Message: envelope.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, out)
+}
+
+type apiMessage struct {
+	Hash        string `json:"hash"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Value       string `json:"value"`
+	Message     string `json:"message"`
+}
+
+func (m apiMessage) decode() Message {
+	value, _ := strconv.ParseUint(m.Value, 10, 64)
+	return Message{
+		Hash:        m.Hash,
+		Source:      m.Source,
+		Destination: m.Destination,
+		Value:       value,
+		Comment:     m.Message,
+	}
+}
+
+func (c *HTTPClient) Transactions(ctx context.Context, address string, limit int, lt uint64, hash string, toLt uint64) ([]Transaction, error) {
+	query := url.Values{
+		"address":  {address},
+		"limit":    {strconv.Itoa(limit)},
+		"archival": {"true"},
+	}
+	if lt != 0 && hash != "" {
+		query.Set("lt", strconv.FormatUint(lt, 10))
+		query.Set("hash", hash)
+	}
+	if toLt != 0 {
+		query.Set("to_lt", strconv.FormatUint(toLt, 10))
+	}
+
+	var result []struct {
+		ID struct {
+			Lt   string `json:"lt"`
+			Hash string `json:"hash"`
+		} `json:"transaction_id"`
+		Utime   int64        `json:"utime"`
+		InMsg   *apiMessage  `json:"in_msg"`
+		OutMsgs []apiMessage `json:"out_msgs"`
+	}
+	if err := c.call(ctx, "getTransactions", query, nil, &result); err != nil {
+		return nil, err
+	}
+
+	txs := make([]Transaction, len(result))
+	for i, r := range result {
+		lt, err := strconv.ParseUint(r.ID.Lt, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ton: invalid lt %q", r.ID.Lt)
+		}
+		txs[i] = Transaction{Hash: r.ID.Hash, Lt: lt, Time: time.Unix(r.Utime, 0)}
+		if r.InMsg != nil {
+			in := r.InMsg.decode()
+			txs[i].In = &in
+		}
+		for _, m := range r.OutMsgs {
+			txs[i].Out = append(txs[i].Out, m.decode())
+		}
+	}
+	return txs, nil
+}
+
+func (c *HTTPClient) Balance(ctx context.Context, address string) (uint64, error) {
+	var balance string
+	if err := c.call(ctx, "getAddressBalance", url.Values{"address": {address}}, nil, &balance); err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(balance, 10, 64)
+}
+
+func (c *HTTPClient) Seqno(ctx context.Context, address string) (uint32, error) {
+	var result struct {
+		ExitCode int             `json:"exit_code"`
+		Stack    [][]interface{} `json:"stack"`
+	}
+	body := map[string]interface{}{"address": address, "method": "seqno", "stack": []interface{}{}}
+	if err := c.call(ctx, "runGetMethod", nil, body, &result); err != nil {
+		return 0, err
+	}
+	// An undeployed wallet has no code to run; its first message uses 0
+	if result.ExitCode != 0 || len(result.Stack) == 0 || len(result.Stack[0]) != 2 {
+		return 0, nil
+	}
+	hex, _ := result.Stack[0][1].(string)
+	n, err := strconv.ParseUint(strings.TrimPrefix(hex, "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("ton: invalid seqno %q", hex)
+	}
+	return uint32(n), nil
+}
+
+func (c *HTTPClient) SendBoc(ctx context.Context, boc []byte) error {
+	return c.call(ctx, "sendBoc", nil, map[string]string{"boc": base64.StdEncoding.EncodeToString(boc)}, nil)
+}
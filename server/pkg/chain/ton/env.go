@@ -0,0 +1,108 @@
+package ton
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HotWallet is the custodial wallet users deposit TON to with their user ID
+// as comment, from TON_HOT_WALLET. Empty when not configured.
+func HotWallet() string {
+	return os.Getenv("TON_HOT_WALLET")
+}
+
+// ColdWallet is the offline wallet excess hot wallet funds are proposed to
+// move to, from TON_COLD_WALLET. Empty when not configured.
+func ColdWallet() string {
+	return os.Getenv("TON_COLD_WALLET")
+}
+
+// ClientFromEnv returns a client for TON_API_URL, authenticated with
+// TON_API_KEY if set, or nil when unset.
+func ClientFromEnv() Client {
+	url := os.Getenv("TON_API_URL")
+	if url == "" {
+		return nil
+	}
+	return NewHTTPClient(url, os.Getenv("TON_API_KEY"))
+}
+
+// hotWalletAddress parses TON_HOT_WALLET, returning false when unset
+func hotWalletAddress() (Address, bool, error) {
+	if HotWallet() == "" {
+		return Address{}, false, nil
+	}
+	a, err := ParseAddress(HotWallet())
+	return a, err == nil, err
+}
+
+// WatcherFromEnv builds the deposit watcher. Returns nil without an API or
+// hot wallet.
+func WatcherFromEnv(client Client) (*Watcher, error) {
+	hot, ok, err := hotWalletAddress()
+	if client == nil || !ok {
+		return nil, err
+	}
+
+	w := NewWatcher(client, hot)
+	if d, err := time.ParseDuration(os.Getenv("TON_POLL_INTERVAL")); err == nil && d > 0 {
+		w.Poll = d
+	}
+	return w, nil
+}
+
+// PayoutsFromEnv builds TON payouts from TON_HOT_WALLET, signed with the
+// Ed25519 seed in TON_HOT_WALLET_KEY_FILE, or TON_HOT_WALLET_KEY when no
+// file is configured, both as hex. TON_WALLET_ID overrides the subwallet
+// ID. Returns nil when no key is configured.
+func PayoutsFromEnv(client Client) (*Payouts, error) {
+	encoded := os.Getenv("TON_HOT_WALLET_KEY")
+	if path := os.Getenv("TON_HOT_WALLET_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ton: reading hot wallet key file: %w", err)
+		}
+		encoded = string(data)
+	}
+	encoded = strings.TrimSpace(encoded)
+	if client == nil || encoded == "" {
+		return nil, nil
+	}
+
+	hot, ok, err := hotWalletAddress()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("ton: TON_HOT_WALLET_KEY is set without TON_HOT_WALLET")
+	}
+	seed, err := hex.DecodeString(encoded)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ton: hot wallet key must be a %d-byte hex seed", ed25519.SeedSize)
+	}
+
+	p := NewPayouts(client, hot, ed25519.NewKeyFromSeed(seed))
+	if id := os.Getenv("TON_WALLET_ID"); id != "" {
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("ton: invalid TON_WALLET_ID %q", id)
+		}
+		p.WalletID = uint32(n)
+	}
+	return p, nil
+}
+
+// HotWalletFromEnv returns the TON_HOT_WALLET account, or nil without an API
+// or hot wallet.
+func HotWalletFromEnv(client Client) (*Account, error) {
+	hot, ok, err := hotWalletAddress()
+	if client == nil || !ok {
+		return nil, err
+	}
+	return &Account{Client: client, Wallet: hot}, nil
+}
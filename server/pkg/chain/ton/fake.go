@@ -0,0 +1,205 @@
+package ton
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotAccepted is how the fake rejects an external message the wallet
+// would refuse, as a real API rejects it on sendBoc
+var ErrNotAccepted = errors.New("ton: external message not accepted")
+
+// Fake is an in-memory chain implementing Client. Messages land as soon as
+// they are sent, in transactions on both accounts. Wallets added with
+// AddWallet execute signed transfers like a wallet v4r2, checking the
+// signature, seqno and expiry, and charging Fee per transfer.
+type Fake struct {
+	Fee       uint64 // Charged to the wallet for each transfer it executes
+	DropSends bool   // Accept transfers but never land them
+
+	mu       sync.Mutex
+	now      time.Time
+	lt       uint64
+	balances map[string]uint64
+	wallets  map[string]*fakeWallet
+	txs      map[string][]Transaction // Per account, in lt order
+}
+
+type fakeWallet struct {
+	key   ed25519.PublicKey
+	seqno uint32
+}
+
+// NewFake returns an empty chain.
+func NewFake() *Fake {
+	return &Fake{
+		Fee:      5_000_000,
+		now:      time.Unix(1_700_000_000, 0),
+		balances: map[string]uint64{},
+		wallets:  map[string]*fakeWallet{},
+		txs:      map[string][]Transaction{},
+	}
+}
+
+// Now is the chain's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the chain's clock forward.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+// AddWallet deploys a wallet controlled by key holding balance.
+func (f *Fake) AddWallet(address Address, key ed25519.PublicKey, balance uint64) {
+	f.mu.Lock()
+	f.wallets[address.Raw()] = &fakeWallet{key: key}
+	f.balances[address.Raw()] += balance
+	f.mu.Unlock()
+}
+
+// Deposit lands a transfer from outside the system with an optional comment
+// and returns the hash of the receiving transaction.
+func (f *Fake) Deposit(from, to Address, amount uint64, comment string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[to.Raw()] += amount
+	return f.landLocked(to, Message{
+		Hash:        newHash(),
+		Source:      from.String(),
+		Destination: to.String(),
+		Value:       amount,
+		Comment:     comment,
+	}, nil)
+}
+
+func (f *Fake) landLocked(account Address, in Message, out []Message) string {
+	f.lt += 1000
+	tx := Transaction{Hash: newHash(), Lt: f.lt, Time: f.now, In: &in, Out: out}
+	f.txs[account.Raw()] = append(f.txs[account.Raw()], tx)
+	return tx.Hash
+}
+
+func (f *Fake) Transactions(ctx context.Context, address string, limit int, lt uint64, hash string, toLt uint64) ([]Transaction, error) {
+	a, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	txs := f.txs[a.Raw()]
+	var page []Transaction
+	started := lt == 0
+	for i := len(txs) - 1; i >= 0 && len(page) < limit; i-- {
+		tx := txs[i]
+		if !started {
+			if tx.Lt != lt || tx.Hash != hash {
+				continue
+			}
+			started = true
+		}
+		if tx.Lt <= toLt {
+			break
+		}
+		page = append(page, tx)
+	}
+	if !started {
+		return nil, fmt.Errorf("ton: transaction %d:%s not found", lt, hash)
+	}
+	return page, nil
+}
+
+func (f *Fake) Balance(ctx context.Context, address string) (uint64, error) {
+	a, err := ParseAddress(address)
+	if err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.balances[a.Raw()], nil
+}
+
+func (f *Fake) Seqno(ctx context.Context, address string) (uint32, error) {
+	a, err := ParseAddress(address)
+	if err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.wallets[a.Raw()]; w != nil {
+		return w.seqno, nil
+	}
+	return 0, nil
+}
+
+// SendBoc executes a wallet transfer. With send mode 3 a transfer the
+// wallet cannot afford still consumes its seqno but sends nothing.
+func (f *Fake) SendBoc(ctx context.Context, boc []byte) error {
+	t, err := ParseTransfer(boc)
+	if err != nil {
+		return err
+	}
+	hash, err := t.Hash()
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	wallet := t.Wallet.Raw()
+	w := f.wallets[wallet]
+	switch {
+	case w == nil:
+		return fmt.Errorf("%w: no wallet at %s", ErrNotAccepted, t.Wallet)
+	case !t.Verify(w.key):
+		return fmt.Errorf("%w: bad signature", ErrNotAccepted)
+	case t.WalletID != DefaultWalletID:
+		return fmt.Errorf("%w: wrong subwallet", ErrNotAccepted)
+	case int64(t.ValidUntil) <= f.now.Unix():
+		return fmt.Errorf("%w: expired", ErrNotAccepted)
+	case t.Seqno != w.seqno:
+		return fmt.Errorf("%w: seqno %d, expected %d", ErrNotAccepted, t.Seqno, w.seqno)
+	case f.balances[wallet] < f.Fee:
+		return fmt.Errorf("%w: cannot pay fees", ErrNotAccepted)
+	case f.balances[wallet]-f.Fee < t.Amount && t.Mode&2 == 0:
+		// Without ignore-errors the failed action rolls the whole
+		// transaction back
+		return fmt.Errorf("%w: insufficient funds", ErrNotAccepted)
+	}
+	if f.DropSends {
+		return nil
+	}
+
+	w.seqno++
+	f.balances[wallet] -= f.Fee
+	in := Message{Hash: hash, Destination: t.Wallet.String()}
+	if f.balances[wallet] < t.Amount {
+		f.landLocked(t.Wallet, in, nil)
+		return nil
+	}
+
+	f.balances[wallet] -= t.Amount
+	f.balances[t.To.Raw()] += t.Amount
+	out := Message{Hash: newHash(), Source: t.Wallet.String(), Destination: t.To.String(), Value: t.Amount}
+	f.landLocked(t.Wallet, in, []Message{out})
+	f.landLocked(t.To, out, nil)
+	return nil
+}
+
+func newHash() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,187 @@
+package ton
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hugolol/gamblefights/pkg/chain"
+)
+
+const (
+	// MinWithdrawalNanotons keeps payouts well above the network fees
+	MinWithdrawalNanotons = 10_000_000
+
+	// DefaultTTL is how long a prepared payout can land for
+	DefaultTTL = 60 * time.Second
+
+	// expiryGrace allows for the API to index a transaction that landed
+	// just before its message expired
+	expiryGrace = 30 * time.Second
+
+	// historyPage is the most transactions requested per page
+	historyPage = 50
+)
+
+// Payouts pays TON withdrawals from a wallet v4r2 hot wallet. It implements
+// chain.Payouts.
+//
+// A wallet executes messages strictly by seqno, so one payout is in flight
+// at a time: Prepare returns chain.ErrBusy until the previous one has
+// landed or expired. The in-flight payout is only tracked in memory; after
+// a restart a new payout may reuse the seqno of one still in flight, and
+// whichever does not land expires and is refunded.
+type Payouts struct {
+	Client   Client
+	Wallet   Address
+	Key      ed25519.PrivateKey
+	WalletID uint32
+	TTL      time.Duration
+	Now      func() time.Time
+
+	mu      sync.Mutex
+	pending *Transfer
+}
+
+// NewPayouts creates payouts from wallet, which key controls.
+func NewPayouts(client Client, wallet Address, key ed25519.PrivateKey) *Payouts {
+	return &Payouts{
+		Client:   client,
+		Wallet:   wallet,
+		Key:      key,
+		WalletID: DefaultWalletID,
+		TTL:      DefaultTTL,
+		Now:      time.Now,
+	}
+}
+
+func (p *Payouts) ValidateAddress(address string) error {
+	a, err := ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	if a.Workchain != 0 || a == p.Wallet {
+		return fmt.Errorf("ton: cannot withdraw to %s", address)
+	}
+	return nil
+}
+
+func (p *Payouts) MinAmount() int64 {
+	return MinWithdrawalNanotons
+}
+
+func (p *Payouts) Prepare(ctx context.Context, destination string, amount int64) (*chain.Prepared, error) {
+	to, err := ParseAddress(destination)
+	if err != nil {
+		return nil, err
+	}
+	if amount < MinWithdrawalNanotons {
+		return nil, fmt.Errorf("ton: payout of %d nanotons is below the minimum", amount)
+	}
+	seqno, err := p.Client.Seqno(ctx, p.Wallet.String())
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.Now()
+	if last := p.pending; last != nil && last.Seqno >= seqno && now.Unix() <= int64(last.ValidUntil) {
+		return nil, chain.ErrBusy
+	}
+
+	t := &Transfer{
+		Wallet:     p.Wallet,
+		WalletID:   p.WalletID,
+		Seqno:      seqno,
+		ValidUntil: uint32(now.Add(p.TTL).Unix()),
+		To:         to,
+		Amount:     uint64(amount),
+		Mode:       sendMode,
+	}
+	if err := t.Sign(p.Key); err != nil {
+		return nil, err
+	}
+	msg, err := t.Message()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := t.Hash()
+	if err != nil {
+		return nil, err
+	}
+	p.pending = t
+	return &chain.Prepared{TxHash: hash, Raw: msg.ToBOC()}, nil
+}
+
+func (p *Payouts) Broadcast(ctx context.Context, raw []byte) error {
+	return p.Client.SendBoc(ctx, raw)
+}
+
+// Status looks for the transaction that handled the payout's message in the
+// hot wallet's history. Transactions in the history are final; one that
+// sent nothing could not afford the payout.
+func (p *Payouts) Status(ctx context.Context, prepared *chain.Prepared) (chain.Status, error) {
+	t, err := ParseTransfer(prepared.Raw)
+	if err != nil {
+		return chain.Status{}, err
+	}
+	// Nothing older than the message can have handled it
+	since := time.Unix(int64(t.ValidUntil), 0).Add(-p.TTL - expiryGrace)
+
+	var lt uint64
+	var hash string
+	for {
+		page, err := p.Client.Transactions(ctx, p.Wallet.String(), historyPage, lt, hash, 0)
+		if err != nil {
+			return chain.Status{}, err
+		}
+		for i, tx := range page {
+			if lt != 0 && i == 0 {
+				continue // The page starts with the last one seen
+			}
+			if tx.Time.Before(since) {
+				return chain.Status{}, nil
+			}
+			if tx.In != nil && tx.In.Source == "" && tx.In.Hash == prepared.TxHash {
+				sent := len(tx.Out) > 0
+				return chain.Status{Found: true, Failed: !sent, Confirmed: sent}, nil
+			}
+		}
+		if len(page) < historyPage {
+			return chain.Status{}, nil
+		}
+		last := page[len(page)-1]
+		lt, hash = last.Lt, last.Hash
+	}
+}
+
+// Expired reports whether the payout's message can no longer land and, if
+// it did, Status has seen it. Nothing expires before its deadline plus
+// grace. After that, a wallet seqno that moved past the message's may have
+// been used by this very payout, so it only counts once the history
+// reaches past the deadline and must hold the transaction that used it.
+func (p *Payouts) Expired(ctx context.Context, prepared *chain.Prepared) (bool, error) {
+	t, err := ParseTransfer(prepared.Raw)
+	if err != nil {
+		return false, err
+	}
+	deadline := time.Unix(int64(t.ValidUntil), 0)
+	if !p.Now().After(deadline.Add(expiryGrace)) {
+		return false, nil
+	}
+	seqno, err := p.Client.Seqno(ctx, p.Wallet.String())
+	if err != nil {
+		return false, err
+	}
+	if seqno <= t.Seqno {
+		return true, nil // Never used, and now too late to be
+	}
+	latest, err := p.Client.Transactions(ctx, p.Wallet.String(), 1, 0, "", 0)
+	if err != nil {
+		return false, err
+	}
+	return len(latest) > 0 && latest[0].Time.After(deadline), nil
+}
@@ -0,0 +1,183 @@
+package ton
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hugolol/gamblefights/pkg/chain"
+)
+
+var (
+	hotKey  = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	hot     = Address{Hash: [32]byte{1}}
+	payee   = Address{Hash: [32]byte{2}}.Friendly(false, false)
+	outside = Address{Hash: [32]byte{3}}
+)
+
+func newTestPayouts(fake *Fake) *Payouts {
+	fake.AddWallet(hot, hotKey.Public().(ed25519.PublicKey), 0)
+	p := NewPayouts(fake, hot, hotKey)
+	p.Now = fake.Now
+	return p
+}
+
+func TestPayoutsLifecycle(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	p := newTestPayouts(fake)
+	fake.Deposit(outside, hot, 2*NanotonsPerTON, "")
+
+	if err := p.ValidateAddress("not-an-address"); err == nil {
+		t.Error("accepted an invalid address")
+	}
+	if err := p.ValidateAddress(hot.String()); err == nil {
+		t.Error("accepted the hot wallet as destination")
+	}
+	if err := p.ValidateAddress(Address{Workchain: -1, Hash: [32]byte{4}}.Raw()); err == nil {
+		t.Error("accepted a masterchain address")
+	}
+	if err := p.ValidateAddress(payee); err != nil {
+		t.Errorf("rejected a valid address: %v", err)
+	}
+
+	prepared, err := p.Prepare(ctx, payee, NanotonsPerTON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := p.Status(ctx, prepared)
+	if err != nil || status.Found {
+		t.Fatalf("status before broadcast = %+v, %v", status, err)
+	}
+
+	// One payout in flight at a time
+	if _, err := p.Prepare(ctx, payee, NanotonsPerTON); !errors.Is(err, chain.ErrBusy) {
+		t.Fatalf("second prepare = %v, want ErrBusy", err)
+	}
+
+	if err := p.Broadcast(ctx, prepared.Raw); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = p.Status(ctx, prepared)
+	if !status.Confirmed || status.Failed {
+		t.Fatalf("status after broadcast = %+v, want confirmed", status)
+	}
+	if expired, _ := p.Expired(ctx, prepared); expired {
+		t.Error("expired before its deadline")
+	}
+
+	balance, _ := fake.Balance(ctx, hot.String())
+	received, _ := fake.Balance(ctx, payee)
+	if received != NanotonsPerTON || balance != NanotonsPerTON-fake.Fee {
+		t.Fatalf("balances = hot %d, payee %d", balance, received)
+	}
+
+	// The next payout can go once the first has landed
+	if _, err := p.Prepare(ctx, payee, NanotonsPerTON); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPayoutsExpiryWaitsForHistory(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	p := newTestPayouts(fake)
+	fake.Deposit(outside, hot, 2*NanotonsPerTON, "")
+
+	prepared, err := p.Prepare(ctx, payee, NanotonsPerTON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Broadcast(ctx, prepared.Raw); err != nil {
+		t.Fatal(err)
+	}
+
+	// The payout used the seqno, but the history has nothing past the
+	// deadline to show whether it was this payout
+	fake.Advance(p.TTL + expiryGrace + time.Second)
+	if expired, _ := p.Expired(ctx, prepared); expired {
+		t.Fatal("expired on a used seqno before the history passed the deadline")
+	}
+
+	fake.Deposit(outside, hot, NanotonsPerTON, "")
+	if expired, _ := p.Expired(ctx, prepared); !expired {
+		t.Fatal("not expired once the history passed the deadline")
+	}
+	if status, _ := p.Status(ctx, prepared); !status.Confirmed {
+		t.Fatalf("status = %+v, want confirmed", status)
+	}
+}
+
+func TestPayoutsUnaffordable(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	p := newTestPayouts(fake)
+	fake.Deposit(outside, hot, NanotonsPerTON/2, "")
+
+	prepared, err := p.Prepare(ctx, payee, NanotonsPerTON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Broadcast(ctx, prepared.Raw); err != nil {
+		t.Fatal(err)
+	}
+	status, _ := p.Status(ctx, prepared)
+	if !status.Found || !status.Failed {
+		t.Fatalf("status = %+v, want failed", status)
+	}
+}
+
+func TestPayoutsExpire(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	p := newTestPayouts(fake)
+	fake.Deposit(outside, hot, 2*NanotonsPerTON, "")
+	fake.DropSends = true
+
+	prepared, err := p.Prepare(ctx, payee, NanotonsPerTON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Broadcast(ctx, prepared.Raw)
+	if expired, _ := p.Expired(ctx, prepared); expired {
+		t.Fatal("expired right away")
+	}
+
+	fake.Advance(p.TTL + expiryGrace + time.Second)
+	if expired, _ := p.Expired(ctx, prepared); !expired {
+		t.Fatal("still valid after its deadline")
+	}
+	if status, _ := p.Status(ctx, prepared); status.Found {
+		t.Fatalf("status = %+v, want not found", status)
+	}
+
+	// Too late to land, and the seqno is free for the next payout
+	fake.DropSends = false
+	if err := p.Broadcast(ctx, prepared.Raw); !errors.Is(err, ErrNotAccepted) {
+		t.Fatalf("late broadcast = %v, want ErrNotAccepted", err)
+	}
+	if _, err := p.Prepare(ctx, payee, NanotonsPerTON); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTransferRoundTrip(t *testing.T) {
+	tr := &Transfer{Wallet: hot, WalletID: DefaultWalletID, Seqno: 7, ValidUntil: 1_700_000_060, To: outside, Amount: 123, Mode: sendMode}
+	if err := tr.Sign(hotKey); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := tr.Message()
+	parsed, err := ParseTransfer(msg.ToBOC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Signature, tr.Signature) || parsed.Seqno != 7 || parsed.To != outside || parsed.Amount != 123 {
+		t.Fatalf("parsed %+v", parsed)
+	}
+	if !parsed.Verify(hotKey.Public().(ed25519.PublicKey)) {
+		t.Fatal("signature does not verify after parsing")
+	}
+}
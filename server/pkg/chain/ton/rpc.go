@@ -0,0 +1,51 @@
+// Package ton credits TON deposits and pays TON withdrawals from a wallet
+// v4r2 hot wallet.
+//
+// All chain access goes through the Client interface. HTTPClient talks to a
+// toncenter v2 API; Fake is an in-memory chain for tests and local
+// development.
+package ton
+
+import (
+	"context"
+	"time"
+)
+
+// NanotonsPerTON is the number of nanotons in one TON
+const NanotonsPerTON = 1_000_000_000
+
+// Message is the part of a message deposits and payouts care about
+type Message struct {
+	Hash        string // Base64 hash of the message cell
+	Source      string // Empty for external messages
+	Destination string
+	Value       uint64
+	Comment     string // Text comment, if the body carried one
+}
+
+// Transaction is one transaction of an account. TON transactions handle a
+// single inbound message; what they sent in response is in Out.
+type Transaction struct {
+	Hash string
+	Lt   uint64 // Logical time, increasing per account
+	Time time.Time
+	In   *Message
+	Out  []Message
+}
+
+// Client is the subset of the toncenter v2 API the server uses.
+type Client interface {
+	// Transactions returns address's transactions, newest first. When lt and
+	// hash are set the page starts at that transaction, inclusive; toLt,
+	// when set, excludes it and everything older.
+	Transactions(ctx context.Context, address string, limit int, lt uint64, hash string, toLt uint64) ([]Transaction, error)
+
+	// Balance returns an account's balance in nanotons
+	Balance(ctx context.Context, address string) (uint64, error)
+
+	// Seqno returns a wallet's next sequence number, 0 before deployment
+	Seqno(ctx context.Context, address string) (uint32, error)
+
+	// SendBoc submits a serialized external message
+	SendBoc(ctx context.Context, boc []byte) error
+}
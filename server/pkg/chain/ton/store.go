@@ -0,0 +1,53 @@
+package ton
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// chainName keys this chain's cursors
+const chainName = "ton"
+
+// DBStore keeps cursors in chain_cursors and deposits in the ledger.
+type DBStore struct{}
+
+func (DBStore) Cursor(address string) (string, error) {
+	var cursor models.ChainCursor
+	err := db.DB.First(&cursor, "chain = ? AND address = ?", chainName, address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return cursor.Signature, err
+}
+
+func (DBStore) SaveCursor(address, cursor string) error {
+	return db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain"}, {Name: "address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"signature", "updated_at"}),
+	}).Create(&models.ChainCursor{
+		Chain:     chainName,
+		Address:   address,
+		Signature: cursor,
+		UpdatedAt: time.Now(),
+	}).Error
+}
+
+func (DBStore) Credit(userID uuid.UUID, nanotons int64, hash string) error {
+	var count int64
+	if err := db.DB.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUnknownUser
+	}
+	_, err := ledger.Deposit(ledger.DepositKey(models.CurrencyTON, hash, userID), userID, models.CurrencyTON, nanotons, models.AccountExternal, hash)
+	return err
+}
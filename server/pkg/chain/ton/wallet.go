@@ -0,0 +1,160 @@
+package ton
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// DefaultWalletID is the subwallet ID of a wallet v4r2 on the basechain
+const DefaultWalletID = 698983191
+
+// sendMode 3 pays fees separately and ignores action errors, so a payout
+// that cannot be afforded still consumes its seqno instead of being retried
+// by validators until it expires
+const sendMode = 3
+
+// Transfer is a signed external message to a wallet v4r2 asking it to send
+// Amount to To. Seqno and ValidUntil make it replay-proof and bound when it
+// can land.
+type Transfer struct {
+	Wallet     Address
+	WalletID   uint32
+	Seqno      uint32
+	ValidUntil uint32 // Unix time
+	To         Address
+	Amount     uint64
+	Mode       uint8
+	Signature  []byte
+}
+
+// internalMessage is the message the wallet sends. It is non-bounceable so
+// funds reach addresses that have not been deployed yet.
+func (t *Transfer) internalMessage() (*Cell, error) {
+	return NewBuilder().
+		StoreUint(0, 1).   // int_msg_info$0
+		StoreBit(true).    // ihr_disabled
+		StoreBit(false).   // bounce
+		StoreBit(false).   // bounced
+		StoreAddress(nil). // src, filled in by the wallet
+		StoreAddress(&t.To).
+		StoreCoins(t.Amount).
+		StoreBit(false).  // No extra currencies
+		StoreCoins(0).    // ihr_fee
+		StoreCoins(0).    // fwd_fee
+		StoreUint(0, 64). // created_lt
+		StoreUint(0, 32). // created_at
+		StoreBit(false).  // No state init
+		StoreBit(false).  // Empty inline body
+		Cell()
+}
+
+// signingMessage is the part of the body the signature covers
+func (t *Transfer) signingMessage() (*Cell, error) {
+	internal, err := t.internalMessage()
+	if err != nil {
+		return nil, err
+	}
+	return NewBuilder().
+		StoreUint(uint64(t.WalletID), 32).
+		StoreUint(uint64(t.ValidUntil), 32).
+		StoreUint(uint64(t.Seqno), 32).
+		StoreUint(0, 8). // Simple send
+		StoreUint(uint64(t.Mode), 8).
+		StoreRef(internal).
+		Cell()
+}
+
+// Sign signs the transfer with the wallet's key.
+func (t *Transfer) Sign(key ed25519.PrivateKey) error {
+	msg, err := t.signingMessage()
+	if err != nil {
+		return err
+	}
+	t.Signature = ed25519.Sign(key, msg.Hash())
+	return nil
+}
+
+// Verify checks the signature against the wallet's public key.
+func (t *Transfer) Verify(key ed25519.PublicKey) bool {
+	msg, err := t.signingMessage()
+	return err == nil && len(t.Signature) == ed25519.SignatureSize && ed25519.Verify(key, msg.Hash(), t.Signature)
+}
+
+// Message builds the external message carrying the signed transfer.
+func (t *Transfer) Message() (*Cell, error) {
+	if len(t.Signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("ton: transfer is not signed")
+	}
+	signing, err := t.signingMessage()
+	if err != nil {
+		return nil, err
+	}
+	body, err := NewBuilder().StoreBytes(t.Signature).StoreSlice(signing.BeginParse()).Cell()
+	if err != nil {
+		return nil, err
+	}
+	return NewBuilder().
+		StoreUint(0b10, 2). // ext_in_msg_info$10
+		StoreAddress(nil).  // src
+		StoreAddress(&t.Wallet).
+		StoreCoins(0).   // import_fee
+		StoreBit(false). // No state init
+		StoreBit(true).  // Body by reference
+		StoreRef(body).
+		Cell()
+}
+
+// Hash is the base64 message hash, which identifies the transfer on chain.
+func (t *Transfer) Hash() (string, error) {
+	msg, err := t.Message()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(msg.Hash()), nil
+}
+
+// ParseTransfer decodes an external message built by Transfer.Message. The
+// signature is not checked.
+func ParseTransfer(boc []byte) (*Transfer, error) {
+	msg, err := ParseBOC(boc)
+	if err != nil {
+		return nil, err
+	}
+	s := msg.BeginParse()
+	if s.LoadUint(2) != 0b10 || s.LoadAddress() != nil {
+		return nil, fmt.Errorf("ton: not an external message")
+	}
+	wallet := s.LoadAddress()
+	s.LoadCoins()
+	if s.LoadBit() || !s.LoadBit() {
+		return nil, fmt.Errorf("ton: unsupported external message layout")
+	}
+	body := s.LoadRef().BeginParse()
+	if s.Err() != nil || wallet == nil {
+		return nil, fmt.Errorf("ton: malformed external message")
+	}
+
+	t := &Transfer{Wallet: *wallet, Signature: body.LoadBytes(ed25519.SignatureSize)}
+	t.WalletID = uint32(body.LoadUint(32))
+	t.ValidUntil = uint32(body.LoadUint(32))
+	t.Seqno = uint32(body.LoadUint(32))
+	if op := body.LoadUint(8); op != 0 {
+		return nil, fmt.Errorf("ton: unsupported wallet op %d", op)
+	}
+	t.Mode = uint8(body.LoadUint(8))
+
+	internal := body.LoadRef().BeginParse()
+	if internal.LoadUint(1) != 0 {
+		return nil, fmt.Errorf("ton: not an internal message")
+	}
+	internal.LoadUint(3) // ihr_disabled, bounce, bounced
+	internal.LoadAddress()
+	to := internal.LoadAddress()
+	t.Amount = internal.LoadCoins()
+	if body.Err() != nil || internal.Err() != nil || to == nil {
+		return nil, fmt.Errorf("ton: malformed transfer")
+	}
+	t.To = *to
+	return t, nil
+}
@@ -0,0 +1,152 @@
+package ton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownUser is returned by a Store for a comment naming no user
+var ErrUnknownUser = errors.New("ton: deposit comment names no user")
+
+// Store persists what the watcher has seen. DBStore is the production one.
+type Store interface {
+	// Cursor returns the newest processed transaction of address as
+	// "lt:hash", "" if none
+	Cursor(address string) (string, error)
+	SaveCursor(address, cursor string) error
+
+	// Credit credits a deposit to the user; repeats are no-ops
+	Credit(userID uuid.UUID, nanotons int64, hash string) error
+}
+
+// Watcher credits TON deposits to the hot wallet, attributed by a comment
+// carrying the user ID. The API only serves transactions from blocks the
+// masterchain has committed, which are final, so deposits are credited as
+// soon as they are seen.
+type Watcher struct {
+	Client    Client
+	Store     Store
+	HotWallet Address
+	Poll      time.Duration
+}
+
+// NewWatcher creates a watcher backed by the database.
+func NewWatcher(client Client, hotWallet Address) *Watcher {
+	return &Watcher{
+		Client:    client,
+		Store:     DBStore{},
+		HotWallet: hotWallet,
+		Poll:      10 * time.Second,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Poll)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Sync(ctx); err != nil {
+			log.Printf("TON deposit watcher: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync walks the hot wallet's history since the cursor, oldest first, and
+// credits deposits.
+func (w *Watcher) Sync(ctx context.Context) error {
+	address := w.HotWallet.String()
+	saved, err := w.Store.Cursor(address)
+	if err != nil {
+		return err
+	}
+	cursorLt, _, err := parseCursor(saved)
+	if err != nil {
+		return err
+	}
+
+	// Page backwards to the cursor. Without a cursor only the most recent
+	// page is read, so a fresh install does not replay years of history.
+	var txs []Transaction
+	var lt uint64
+	var hash string
+	for {
+		page, err := w.Client.Transactions(ctx, address, historyPage, lt, hash, cursorLt)
+		if err != nil {
+			return err
+		}
+		full := len(page) == historyPage
+		if lt != 0 && len(page) > 0 {
+			page = page[1:] // The page starts with the last one seen
+		}
+		txs = append(txs, page...)
+		if !full || saved == "" {
+			break
+		}
+		last := page[len(page)-1]
+		lt, hash = last.Lt, last.Hash
+	}
+
+	for i := len(txs) - 1; i >= 0; i-- {
+		if err := w.record(&txs[i]); err != nil {
+			return err
+		}
+		if err := w.Store.SaveCursor(address, formatCursor(txs[i].Lt, txs[i].Hash)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record credits tx if it is an attributable deposit. Deposits that cannot
+// be attributed are logged for manual review and skipped.
+func (w *Watcher) record(tx *Transaction) error {
+	// External messages are the hot wallet's own payouts
+	if tx.In == nil || tx.In.Source == "" || tx.In.Value == 0 {
+		return nil
+	}
+
+	userID, err := uuid.Parse(strings.TrimSpace(tx.In.Comment))
+	if err != nil {
+		log.Printf("TON deposit %s of %d nanotons has no user comment, needs manual review", tx.Hash, tx.In.Value)
+		return nil
+	}
+
+	err = w.Store.Credit(userID, int64(tx.In.Value), tx.Hash)
+	if errors.Is(err, ErrUnknownUser) {
+		log.Printf("TON deposit %s names unknown user %s, needs manual review", tx.Hash, userID)
+		return nil
+	}
+	if err == nil {
+		log.Printf("Credited TON deposit %s: %d nanotons to user %s", tx.Hash, tx.In.Value, userID)
+	}
+	return err
+}
+
+func formatCursor(lt uint64, hash string) string {
+	return strconv.FormatUint(lt, 10) + ":" + hash
+}
+
+func parseCursor(cursor string) (uint64, string, error) {
+	if cursor == "" {
+		return 0, "", nil
+	}
+	lt, hash, ok := strings.Cut(cursor, ":")
+	n, err := strconv.ParseUint(lt, 10, 64)
+	if !ok || err != nil {
+		return 0, "", fmt.Errorf("ton: invalid cursor %q", cursor)
+	}
+	return n, hash, nil
+}
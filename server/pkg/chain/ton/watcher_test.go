@@ -0,0 +1,103 @@
+package ton
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memStore is an in-memory Store
+type memStore struct {
+	cursors  map[string]string
+	credited map[string]int64 // By transaction hash
+	users    map[uuid.UUID]bool
+}
+
+func newMemStore(users ...uuid.UUID) *memStore {
+	s := &memStore{cursors: map[string]string{}, credited: map[string]int64{}, users: map[uuid.UUID]bool{}}
+	for _, u := range users {
+		s.users[u] = true
+	}
+	return s
+}
+
+func (s *memStore) Cursor(address string) (string, error) { return s.cursors[address], nil }
+
+func (s *memStore) SaveCursor(address, cursor string) error {
+	s.cursors[address] = cursor
+	return nil
+}
+
+func (s *memStore) Credit(userID uuid.UUID, nanotons int64, hash string) error {
+	if !s.users[userID] {
+		return ErrUnknownUser
+	}
+	s.credited[hash] = nanotons
+	return nil
+}
+
+func TestWatcherCreditsCommentedDeposits(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	user := uuid.New()
+	store := newMemStore(user)
+	w := NewWatcher(fake, hot)
+	w.Store = store
+
+	good := fake.Deposit(outside, hot, 3*NanotonsPerTON, user.String())
+	anonymous := fake.Deposit(outside, hot, NanotonsPerTON, "")
+	stranger := fake.Deposit(outside, hot, NanotonsPerTON, uuid.New().String())
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if store.credited[good] != 3*NanotonsPerTON {
+		t.Fatalf("credited %v", store.credited)
+	}
+	if _, ok := store.credited[anonymous]; ok {
+		t.Error("credited a deposit without comment")
+	}
+	if _, ok := store.credited[stranger]; ok {
+		t.Error("credited a deposit for an unknown user")
+	}
+
+	// Resumes from the cursor, across pages, without crediting twice
+	delete(store.credited, good)
+	var later []string
+	for i := 0; i < historyPage+5; i++ {
+		later = append(later, fake.Deposit(outside, hot, uint64(i+1), user.String()))
+	}
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.credited[good]; ok {
+		t.Error("credited a deposit again after restart")
+	}
+	for i, hash := range later {
+		if store.credited[hash] != int64(i+1) {
+			t.Fatalf("deposit %d credited %d", i, store.credited[hash])
+		}
+	}
+}
+
+func TestWatcherSkipsPayouts(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake()
+	p := newTestPayouts(fake)
+	store := newMemStore()
+	w := NewWatcher(fake, hot)
+	w.Store = store
+
+	fake.Deposit(outside, hot, 2*NanotonsPerTON, "")
+	prepared, err := p.Prepare(ctx, payee, NanotonsPerTON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Broadcast(ctx, prepared.Raw)
+	if err := w.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.credited) != 0 {
+		t.Fatalf("credited %v", store.credited)
+	}
+}
@@ -0,0 +1,303 @@
+// Package currency is the registry of assets users hold and wager: the
+// decimals of their atomic units, exact formatting and parsing of amounts,
+// wager limits and preset tiers, and which operations are enabled.
+//
+// Amounts everywhere else are int64 atomic units (lamports, nanotons, USDT
+// millionths); this package is the only place that knows how they read.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+var (
+	// ErrUnknown means the currency is not in the registry
+	ErrUnknown = errors.New("currency: unknown currency")
+
+	// ErrDisabled means the operation is switched off for the currency
+	ErrDisabled = errors.New("currency: operation disabled")
+
+	// ErrWagerOutOfRange means a wager is outside the currency's limits
+	ErrWagerOutOfRange = errors.New("currency: wager out of range")
+)
+
+// Feature is an operation that can be switched off per currency
+type Feature string
+
+const (
+	Deposits    Feature = "deposits"
+	Withdrawals Feature = "withdrawals"
+	Wagering    Feature = "wagering"
+)
+
+// Info describes a currency
+type Info struct {
+	Code     models.Currency
+	Name     string
+	Decimals int // Atomic units per whole unit are 10^Decimals
+
+	// Wager limits and tiers, in atomic units
+	MinWager     int64
+	MaxWager     int64
+	DefaultWager int64
+	Presets      []int64
+
+	Deposits    bool
+	Withdrawals bool
+	Wagering    bool
+}
+
+// Defaults is the registry used when nothing is configured, in display
+// order
+var Defaults = []Info{
+	{
+		Code: models.CurrencySOL, Name: "Solana", Decimals: 9,
+		MinWager: 10_000_000, MaxWager: 100_000_000_000, DefaultWager: 100_000_000, // 0.01-100 SOL
+		Presets:  []int64{50_000_000, 100_000_000, 250_000_000, 500_000_000, 1_000_000_000},
+		Deposits: true, Withdrawals: true, Wagering: true,
+	},
+	{
+		Code: models.CurrencyTON, Name: "Toncoin", Decimals: 9,
+		MinWager: 100_000_000, MaxWager: 10_000_000_000_000, DefaultWager: 1_000_000_000, // 0.1-10,000 TON
+		Presets:  []int64{500_000_000, 1_000_000_000, 2_500_000_000, 5_000_000_000, 10_000_000_000},
+		Deposits: true, Withdrawals: true, Wagering: true,
+	},
+	{
+		Code: models.CurrencyUSDT, Name: "Tether USD", Decimals: 6,
+		MinWager: 500_000, MaxWager: 10_000_000_000, DefaultWager: 5_000_000, // 0.5-10,000 USDT
+		Presets:  []int64{1_000_000, 5_000_000, 10_000_000, 25_000_000, 100_000_000},
+		Deposits: true, Withdrawals: true, Wagering: true,
+	},
+}
+
+var (
+	registry = index(Defaults)
+	order    = Defaults
+	mu       sync.RWMutex
+)
+
+func index(infos []Info) map[models.Currency]Info {
+	m := make(map[models.Currency]Info, len(infos))
+	for _, info := range infos {
+		m[info.Code] = info
+	}
+	return m
+}
+
+// Set replaces the registry.
+func Set(infos []Info) {
+	mu.Lock()
+	registry, order = index(infos), infos
+	mu.Unlock()
+}
+
+// Lookup returns the currency with code.
+func Lookup(code models.Currency) (Info, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	info, ok := registry[code]
+	return info, ok
+}
+
+// All returns every registered currency in display order.
+func All() []Info {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Info(nil), order...)
+}
+
+// Enabled reports whether feature is on for the currency. Unknown
+// currencies have nothing enabled.
+func Enabled(code models.Currency, feature Feature) bool {
+	info, ok := Lookup(code)
+	return ok && info.Enabled(feature)
+}
+
+// Enabled reports whether feature is on.
+func (c Info) Enabled(feature Feature) bool {
+	switch feature {
+	case Deposits:
+		return c.Deposits
+	case Withdrawals:
+		return c.Withdrawals
+	case Wagering:
+		return c.Wagering
+	}
+	return false
+}
+
+// CheckWager returns nil if amount may be wagered in the currency.
+func CheckWager(code models.Currency, amount int64) error {
+	info, ok := Lookup(code)
+	switch {
+	case !ok:
+		return ErrUnknown
+	case !info.Wagering:
+		return ErrDisabled
+	case amount < info.MinWager || amount > info.MaxWager:
+		return fmt.Errorf("%w: %s outside %s to %s", ErrWagerOutOfRange,
+			info.Format(amount), info.Format(info.MinWager), info.Format(info.MaxWager))
+	}
+	return nil
+}
+
+// Display formats amount of the currency with its code, e.g. "1.50 SOL".
+// Unknown currencies show the raw atomic amount.
+func Display(amount int64, code models.Currency) string {
+	info, ok := Lookup(code)
+	if !ok {
+		return strconv.FormatInt(amount, 10) + " " + string(code)
+	}
+	return info.Display(amount)
+}
+
+// Display formats amount with the currency code, e.g. "1.50 SOL".
+func (c Info) Display(amount int64) string {
+	return c.Format(amount) + " " + string(c.Code)
+}
+
+// Format writes atomic units as an exact decimal, keeping at least two
+// fractional digits: 1500000000 lamports is "1.50", 5000 is "0.000005".
+func (c Info) Format(amount int64) string {
+	sign := ""
+	u := uint64(amount)
+	if amount < 0 {
+		sign, u = "-", uint64(-amount) // -MinInt64 wraps but converts to the right magnitude
+	}
+	digits := strconv.FormatUint(u, 10)
+	if c.Decimals <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= c.Decimals {
+		digits = strings.Repeat("0", c.Decimals-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-c.Decimals], digits[len(digits)-c.Decimals:]
+
+	keep := len(strings.TrimRight(frac, "0"))
+	if keep < 2 {
+		keep = min(2, len(frac))
+	}
+	return sign + whole + "." + frac[:keep]
+}
+
+// Parse reads a non-negative decimal amount, such as "1.5", into atomic
+// units. More fractional digits than the currency has are an error rather
+// than rounded away.
+func (c Info) Parse(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("currency: empty amount")
+	}
+	if len(frac) > c.Decimals {
+		return 0, fmt.Errorf("currency: %s has at most %d decimals", c.Code, c.Decimals)
+	}
+	digits := whole + frac + strings.Repeat("0", c.Decimals-len(frac))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("currency: invalid amount %q", s)
+		}
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("currency: amount %q out of range", s)
+	}
+	return n, nil
+}
+
+// LoadFromEnv applies CURRENCY_WAGER_LIMITS and CURRENCY_DISABLED to the
+// defaults.
+func LoadFromEnv() error {
+	infos, err := Configure(Defaults, os.Getenv("CURRENCY_WAGER_LIMITS"), os.Getenv("CURRENCY_DISABLED"))
+	if err != nil {
+		return err
+	}
+	Set(infos)
+	return nil
+}
+
+// Configure returns a copy of infos with wager limits and disabled features
+// applied. limits holds comma-separated CURRENCY:min:max entries in atomic
+// units, e.g. "SOL:10000000:100000000000"; disabled holds comma-separated
+// CURRENCY:feature entries, or a bare CURRENCY to disable everything, e.g.
+// "USDT:wagering,TON".
+func Configure(infos []Info, limits, disabled string) ([]Info, error) {
+	out := make([]Info, len(infos))
+	copy(out, infos)
+	find := func(code string) (*Info, error) {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		for i := range out {
+			if string(out[i].Code) == code {
+				return &out[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%w %q", ErrUnknown, code)
+	}
+
+	for _, part := range SplitList(limits) {
+		fields := strings.Split(part, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("currency: wager limit %q is not CURRENCY:min:max", part)
+		}
+		info, err := find(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		lo, err1 := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+		hi, err2 := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+		if err1 != nil || err2 != nil || lo <= 0 || lo > hi {
+			return nil, fmt.Errorf("currency: wager limit %q must satisfy 0 < min <= max", part)
+		}
+		info.MinWager, info.MaxWager = lo, hi
+		info.DefaultWager = max(lo, min(info.DefaultWager, hi))
+		var presets []int64
+		for _, p := range info.Presets {
+			if p >= lo && p <= hi {
+				presets = append(presets, p)
+			}
+		}
+		info.Presets = presets
+	}
+
+	for _, part := range SplitList(disabled) {
+		code, feature, scoped := strings.Cut(part, ":")
+		info, err := find(code)
+		if err != nil {
+			return nil, err
+		}
+		if !scoped {
+			info.Deposits, info.Withdrawals, info.Wagering = false, false, false
+			continue
+		}
+		switch Feature(strings.ToLower(strings.TrimSpace(feature))) {
+		case Deposits:
+			info.Deposits = false
+		case Withdrawals:
+			info.Withdrawals = false
+		case Wagering:
+			info.Wagering = false
+		default:
+			return nil, fmt.Errorf("currency: unknown feature in %q", part)
+		}
+	}
+	return out, nil
+}
+
+// SplitList returns the trimmed, non-empty comma-separated entries of s, as
+// in the per-currency env lists
+func SplitList(s string) []string {
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
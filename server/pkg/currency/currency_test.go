@@ -0,0 +1,134 @@
+package currency
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+func TestFormat(t *testing.T) {
+	sol, _ := Lookup(models.CurrencySOL)
+	usdt, _ := Lookup(models.CurrencyUSDT)
+
+	tests := []struct {
+		info   Info
+		amount int64
+		want   string
+	}{
+		{sol, 0, "0.00"},
+		{sol, 1_500_000_000, "1.50"},
+		{sol, 100_000_000, "0.10"},
+		{sol, 5_000, "0.000005"},
+		{sol, 1, "0.000000001"},
+		{sol, 123_456_789_012, "123.456789012"},
+		{sol, -2_000_000_000, "-2.00"},
+		{usdt, 1_230_000, "1.23"},
+		{usdt, 999_999_999_999, "999999.999999"},
+	}
+	for _, tt := range tests {
+		if got := tt.info.Format(tt.amount); got != tt.want {
+			t.Errorf("%s Format(%d) = %q, want %q", tt.info.Code, tt.amount, got, tt.want)
+		}
+	}
+
+	if got := Display(250_000_000, models.CurrencySOL); got != "0.25 SOL" {
+		t.Errorf("Display = %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	sol, _ := Lookup(models.CurrencySOL)
+	usdt, _ := Lookup(models.CurrencyUSDT)
+
+	good := []struct {
+		info Info
+		in   string
+		want int64
+	}{
+		{sol, "1", 1_000_000_000},
+		{sol, "1.5", 1_500_000_000},
+		{sol, " .25 ", 250_000_000},
+		{sol, "2.", 2_000_000_000},
+		{sol, "0.000000001", 1},
+		{usdt, "10.05", 10_050_000},
+	}
+	for _, tt := range good {
+		got, err := tt.info.Parse(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("%s Parse(%q) = %d, %v, want %d", tt.info.Code, tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", ".", "-1", "+1", "1e9", "1.2.3", "0.0000000001", "99999999999"} {
+		if _, err := sol.Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+	if _, err := usdt.Parse("1.0000001"); err == nil {
+		t.Error("USDT accepted 7 decimals")
+	}
+
+	// Formatting round-trips
+	for _, amount := range []int64{0, 1, 10_000_000, 1_500_000_000, 123_456_789_012} {
+		if got, err := sol.Parse(sol.Format(amount)); err != nil || got != amount {
+			t.Errorf("round trip of %d = %d, %v", amount, got, err)
+		}
+	}
+}
+
+func TestCheckWager(t *testing.T) {
+	if err := CheckWager(models.CurrencySOL, 100_000_000); err != nil {
+		t.Errorf("default SOL wager rejected: %v", err)
+	}
+	if err := CheckWager(models.CurrencySOL, 1); !errors.Is(err, ErrWagerOutOfRange) {
+		t.Errorf("dust wager: %v", err)
+	}
+	if err := CheckWager("DOGE", 1); !errors.Is(err, ErrUnknown) {
+		t.Errorf("unknown currency: %v", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	infos, err := Configure(Defaults, "sol:200000000:1000000000", "USDT:wagering, TON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Set(Defaults)
+	Set(infos)
+
+	sol, _ := Lookup(models.CurrencySOL)
+	if sol.MinWager != 200_000_000 || sol.MaxWager != 1_000_000_000 || sol.DefaultWager != 200_000_000 {
+		t.Errorf("SOL limits = %d-%d, default %d", sol.MinWager, sol.MaxWager, sol.DefaultWager)
+	}
+	if len(sol.Presets) != 3 {
+		t.Errorf("SOL presets = %v, want those within the limits", sol.Presets)
+	}
+	if Enabled(models.CurrencyUSDT, Wagering) || !Enabled(models.CurrencyUSDT, Deposits) {
+		t.Error("USDT: want only wagering disabled")
+	}
+	if Enabled(models.CurrencyTON, Deposits) || Enabled(models.CurrencyTON, Withdrawals) || Enabled(models.CurrencyTON, Wagering) {
+		t.Error("TON: want everything disabled")
+	}
+	if !errors.Is(CheckWager(models.CurrencyUSDT, 5_000_000), ErrDisabled) {
+		t.Error("USDT wager accepted while disabled")
+	}
+
+	// The defaults are untouched
+	if Defaults[0].MinWager != 10_000_000 || !Defaults[2].Wagering {
+		t.Error("Configure modified its input")
+	}
+
+	for _, bad := range [][2]string{
+		{"SOL:1", ""},
+		{"SOL:5:1", ""},
+		{"SOL:0:1", ""},
+		{"DOGE:1:2", ""},
+		{"", "SOL:trading"},
+		{"", "DOGE"},
+	} {
+		if _, err := Configure(Defaults, bad[0], bad[1]); err == nil {
+			t.Errorf("Configure(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package db
+
+import "log"
+
+// RevealLegacySeeds marks matches played before per-user seed pairs as
+// revealed. Those matches stored their server seed in the clear and have no
+// row in server_seeds, so no rotation will ever reveal them.
+func RevealLegacySeeds() error {
+	result := DB.Exec(`UPDATE matches SET seed_revealed = TRUE
+		WHERE seed_revealed = FALSE
+		AND NOT EXISTS (SELECT 1 FROM server_seeds WHERE server_seeds.seed_hashed = matches.server_seed_hashed)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Marked %d legacy matches as revealed", result.RowsAffected)
+	}
+	return nil
+}
+
+// FlagLegacyTestFights marks test fights recorded before matches had an
+// IsTest flag. Those were stored with the player on both sides.
+func FlagLegacyTestFights() error {
+	result := DB.Exec(`UPDATE matches SET is_test = TRUE
+		WHERE is_test = FALSE AND player_a_id = player_b_id`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Flagged %d legacy test fights", result.RowsAffected)
+	}
+	return nil
+}
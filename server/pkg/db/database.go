@@ -63,6 +63,15 @@ func Connect() {
 			&models.Wallet{},
 			&models.Match{},
 			&models.Transaction{},
+			&models.ServerSeed{},
+			&models.MerkleRoot{},
+			&models.LedgerAccount{},
+			&models.JournalEntry{},
+			&models.Posting{},
+			&models.IdempotencyRecord{},
+			&models.ChainCursor{},
+			&models.Withdrawal{},
+			&models.TreasuryProposal{},
 		)
 		if err != nil {
 			log.Fatal("Failed to migrate database:", err)
@@ -1,11 +1,9 @@
 package fairness
 
 import (
-	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 	"strconv"
 )
 
@@ -24,25 +22,23 @@ func HashServerSeed(serverSeed string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// HashClientSeed is the commitment a player sends before revealing their
+// per-match client seed: SHA256 hex, the same as HashServerSeed.
+func HashClientSeed(clientSeed string) string {
+	return HashServerSeed(clientSeed)
+}
+
 // CalculateOutcome determines the winner based on seeds and nonce.
 // Returns true for Player A (User), false for Player B (Opponent/House).
-// Also returns the raw HMAC hash for verification.
+// Also returns the raw HMAC hash for verification. It is version 1 of the
+// rules; matches are derived through Lookup.
 func CalculateOutcome(serverSeed string, clientSeed string, nonce int64) (bool, string) {
-	message := fmt.Sprintf("%s-%d", clientSeed, nonce)
-	
-	h := hmac.New(sha256.New, []byte(serverSeed))
-	h.Write([]byte(message))
-	hashBytes := h.Sum(nil)
-	hashString := hex.EncodeToString(hashBytes)
-
-	// Take the first 8 characters (32 bits) of the hex string
-	// This gives us a number between 0 and 4,294,967,295
-	subHash := hashString[:8]
-	decValue, _ := strconv.ParseUint(subHash, 16, 64)
-
-	// Determine winner: Simple boolean 50/50
-	// Even = Player A, Odd = Player B
-	isPlayerAWin := decValue%2 == 0
+	return algorithms[1].Outcome(Input{ServerSeed: serverSeed, ClientSeed: clientSeed, Nonce: nonce})
+}
 
-	return isPlayerAWin, hashString
+// DecValue takes the first 8 characters (32 bits) of the hex hash.
+// This gives us a number between 0 and 4,294,967,295
+func DecValue(hashString string) uint64 {
+	decValue, _ := strconv.ParseUint(hashString[:8], 16, 64)
+	return decValue
 }
@@ -1,6 +1,7 @@
 package fairness
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -67,3 +68,227 @@ func TestHashServerSeed(t *testing.T) {
 		t.Error("Hashing not consistent")
 	}
 }
+
+func TestStreamDeterministic(t *testing.T) {
+	s1 := NewStream("server-seed-test", "client-seed-test", 1)
+	s2 := NewStream("server-seed-test", "client-seed-test", 1)
+
+	// Read across several HMAC rounds
+	for i := 0; i < 100; i++ {
+		if s1.Byte() != s2.Byte() {
+			t.Fatalf("Stream diverged at byte %d", i)
+		}
+	}
+
+	s3 := NewStream("server-seed-test", "client-seed-test", 2)
+	if NewStream("server-seed-test", "client-seed-test", 1).Uint32() == s3.Uint32() {
+		t.Error("Stream did not change with nonce")
+	}
+}
+
+func TestFightScript(t *testing.T) {
+	for _, version := range []int{1, BeaconVersion} {
+		algo, err := Lookup(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkFightScripts(t, algo)
+	}
+}
+
+func checkFightScripts(t *testing.T, algo Algorithm) {
+	playerA := PlayerInfo{ID: "a", Username: "alice", Character: "fighter", Skin: "default"}
+	playerB := PlayerInfo{ID: "b", Username: "bob", Character: "fighter", Skin: "default"}
+	other := map[string]string{"playerA": "playerB", "playerB": "playerA"}
+
+	for nonce := int64(0); nonce < 200; nonce++ {
+		winner := "playerA"
+		if nonce%2 == 1 {
+			winner = "playerB"
+		}
+
+		in := Input{ServerSeed: "server-seed-test", ClientSeed: "client-seed-test", Nonce: nonce, Beacon: "beef"}
+		script := algo.FightScript(in, "match", playerA, playerB, winner)
+		again := algo.FightScript(in, "match", playerA, playerB, winner)
+
+		json1, _ := json.Marshal(script)
+		json2, _ := json.Marshal(again)
+		if string(json1) != string(json2) {
+			t.Fatalf("v%d fight script not deterministic for nonce %d", algo.Version, nonce)
+		}
+
+		if script.Winner != winner {
+			t.Fatalf("Expected winner %s, got %s", winner, script.Winner)
+		}
+
+		// Replay damage and check the loser drops to 0 exactly at the KO
+		health := map[string]int{"playerA": v1FighterHealth, "playerB": v1FighterHealth}
+		for _, e := range script.Events {
+			if e.Type == "ko" {
+				if e.Actor == winner {
+					t.Fatalf("Winner was knocked out (nonce %d)", nonce)
+				}
+				if health[e.Actor] != 0 {
+					t.Fatalf("Loser has %d HP at KO (nonce %d)", health[e.Actor], nonce)
+				}
+				if health[winner] <= 0 {
+					t.Fatalf("Winner has %d HP at KO (nonce %d)", health[winner], nonce)
+				}
+				break
+			}
+			if e.Hit {
+				health[other[e.Actor]] -= e.Damage
+				if health[other[e.Actor]] <= 0 && e.Type != "finish_move" {
+					t.Fatalf("Health reached 0 before the finishing blow (nonce %d)", nonce)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamCursor(t *testing.T) {
+	s := NewStream("server-seed-test", "client-seed-test", 3)
+	for i := 0; i < 45; i++ {
+		s.Byte()
+	}
+	if s.Cursor() != 45 {
+		t.Fatalf("Expected cursor 45, got %d", s.Cursor())
+	}
+
+	resumed := NewStreamAt("server-seed-test", "client-seed-test", 3, s.Cursor())
+	if s.Uint64() != resumed.Uint64() {
+		t.Error("Stream resumed at cursor does not match")
+	}
+}
+
+func TestStreamIntn(t *testing.T) {
+	s := NewStream("server-seed-test", "client-seed-test", 1)
+	counts := make([]int, 6)
+	for i := 0; i < 6000; i++ {
+		v := s.Intn(6)
+		if v < 0 || v >= 6 {
+			t.Fatalf("Intn out of range: %d", v)
+		}
+		counts[v]++
+	}
+	for face, n := range counts {
+		if n < 850 || n > 1150 {
+			t.Errorf("Face %d drawn %d times, expected about 1000", face, n)
+		}
+	}
+}
+
+func TestStreamWeightedIndex(t *testing.T) {
+	s := NewStream("server-seed-test", "client-seed-test", 1)
+
+	idx, err := s.WeightedIndex([]uint64{0, 5, 0})
+	if err != nil || idx != 1 {
+		t.Fatalf("Expected only index 1, got %d (%v)", idx, err)
+	}
+
+	if _, err := s.WeightedIndex([]uint64{0, 0}); err != ErrNoWeight {
+		t.Errorf("Expected ErrNoWeight, got %v", err)
+	}
+
+	// Heavier weight should win roughly 3x as often
+	counts := make([]int, 2)
+	for i := 0; i < 4000; i++ {
+		idx, _ := s.WeightedIndex([]uint64{1, 3})
+		counts[idx]++
+	}
+	if counts[1] < 2800 || counts[1] > 3200 {
+		t.Errorf("Weighted pick off: %v", counts)
+	}
+}
+
+func TestStreamPerm(t *testing.T) {
+	p := NewStream("server-seed-test", "client-seed-test", 1).Perm(10)
+	again := NewStream("server-seed-test", "client-seed-test", 1).Perm(10)
+
+	seen := make(map[int]bool)
+	for i, v := range p {
+		if v != again[i] {
+			t.Fatal("Perm not deterministic")
+		}
+		seen[v] = true
+	}
+	if len(seen) != 10 {
+		t.Errorf("Perm is not a permutation: %v", p)
+	}
+}
+
+func TestLookupVersion(t *testing.T) {
+	algo, err := Lookup(CurrentVersion)
+	if err != nil {
+		t.Fatalf("Current version not registered: %v", err)
+	}
+
+	if algo.Version != CurrentVersion || Current().Version != CurrentVersion {
+		t.Errorf("Lookup(%d) returned version %d", CurrentVersion, algo.Version)
+	}
+
+	if _, err := Lookup(0); err == nil {
+		t.Error("Expected error for unknown version")
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	serverSeed := "server-seed-test"
+	in := Input{ServerSeed: serverSeed, ClientSeed: CombineClientSeeds("alice", "bob"), Nonce: 7}
+	algo, _ := Lookup(1)
+	playerAWins, outcomeHash := algo.Outcome(in)
+	winner := "playerB"
+	if playerAWins {
+		winner = "playerA"
+	}
+	script := algo.FightScript(in, "match", PlayerInfo{ID: "a"}, PlayerInfo{ID: "b"}, winner)
+	scriptJSON, _ := json.Marshal(script)
+
+	proof := Proof{
+		MatchID:          "match",
+		FairnessVersion:  1,
+		ServerSeed:       serverSeed,
+		ServerSeedHashed: HashServerSeed(serverSeed),
+		ClientSeedA:      "alice",
+		ClientSeedB:      "bob",
+		Nonce:            7,
+		Winner:           winner,
+		OutcomeHash:      outcomeHash,
+		FightScript:      scriptJSON,
+	}
+
+	if report := Verify(proof); !report.Passed {
+		t.Fatalf("Valid proof failed: %+v", report.Checks)
+	}
+
+	tampered := proof
+	tampered.Nonce = 8
+	if Verify(tampered).Passed {
+		t.Error("Proof with wrong nonce passed")
+	}
+
+	tampered = proof
+	tampered.ServerSeedHashed = HashServerSeed("other")
+	if Verify(tampered).Passed {
+		t.Error("Proof with wrong commitment passed")
+	}
+}
+
+func TestBeaconVersion(t *testing.T) {
+	algo, err := Lookup(BeaconVersion)
+	if err != nil {
+		t.Fatalf("Beacon version not registered: %v", err)
+	}
+
+	in := Input{ServerSeed: "server-seed-test", ClientSeed: "client-seed-test", Nonce: 1, Beacon: "beef"}
+	_, withBeacon := algo.Outcome(in)
+	in.Beacon = "cafe"
+	_, otherBeacon := algo.Outcome(in)
+	if withBeacon == otherBeacon {
+		t.Error("Outcome hash did not change with the beacon")
+	}
+
+	if algo.Message(in) != "client-seed-test-cafe-1" {
+		t.Errorf("Unexpected beacon message %q", algo.Message(in))
+	}
+}
@@ -0,0 +1,30 @@
+package fairness
+
+// FightEvent represents a single event in the fight animation
+type FightEvent struct {
+	Time   float64 `json:"time"`  // Seconds from start
+	Type   string  `json:"type"`  // Event type
+	Actor  string  `json:"actor"` // "playerA" or "playerB"
+	Hit    bool    `json:"hit,omitempty"`
+	Crit   bool    `json:"crit,omitempty"`
+	Dodge  bool    `json:"dodge,omitempty"`
+	Damage int     `json:"damage,omitempty"`
+}
+
+// FightScript is the complete animation script sent to clients
+type FightScript struct {
+	MatchID  string       `json:"matchId"`
+	PlayerA  PlayerInfo   `json:"playerA"`
+	PlayerB  PlayerInfo   `json:"playerB"`
+	Winner   string       `json:"winner"` // "playerA" or "playerB"
+	Duration float64      `json:"duration"`
+	Events   []FightEvent `json:"events"`
+}
+
+// PlayerInfo for the fight script
+type PlayerInfo struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Character string `json:"character"`
+	Skin      string `json:"skin"`
+}
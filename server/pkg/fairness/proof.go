@@ -0,0 +1,156 @@
+package fairness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Proof is a portable bundle with everything needed to re-check a match
+// without trusting the server.
+type Proof struct {
+	MatchID          string          `json:"matchId"`
+	FairnessVersion  int             `json:"fairnessVersion"`
+	ServerSeed       string          `json:"serverSeed"`
+	ServerSeedHashed string          `json:"serverSeedHashed"`
+	ClientSeedA      string          `json:"clientSeedA"`
+	ClientSeedB      string          `json:"clientSeedB"`
+	Nonce            int64           `json:"nonce"`
+	BeaconRound      uint64          `json:"beaconRound,omitempty"`
+	BeaconRandomness string          `json:"beaconRandomness,omitempty"`
+	Winner           string          `json:"winner"`                // "playerA" or "playerB"
+	OutcomeHash      string          `json:"outcomeHash,omitempty"` // Optional, as shown in MATCH_RESULT
+	FightScript      json.RawMessage `json:"fightScript,omitempty"`
+}
+
+// Check is the result of a single verification step
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+// Report is the full re-derivation of a match from its proof
+type Report struct {
+	MatchID         string  `json:"matchId"`
+	FairnessVersion int     `json:"fairnessVersion"`
+	Message         string  `json:"message"`     // HMAC message (clientSeed-nonce)
+	OutcomeHash     string  `json:"outcomeHash"` // Full HMAC-SHA256 hex
+	DecValue        uint64  `json:"decValue"`    // First 8 hex chars as an integer
+	DerivedWinner   string  `json:"derivedWinner"`
+	WinnerMatches   bool    `json:"winnerMatches"`
+	Checks          []Check `json:"checks"`
+	Passed          bool    `json:"passed"`
+}
+
+// Verify re-derives a match from its proof and reports every check.
+func Verify(p Proof) Report {
+	in := Input{
+		ServerSeed: p.ServerSeed,
+		ClientSeed: CombineClientSeeds(p.ClientSeedA, p.ClientSeedB),
+		Nonce:      p.Nonce,
+		Beacon:     p.BeaconRandomness,
+	}
+	report := Report{
+		MatchID:         p.MatchID,
+		FairnessVersion: p.FairnessVersion,
+	}
+
+	// 1. The revealed seed must match the commitment
+	if p.ServerSeed == "" {
+		report.add(Check{Name: "server seed hash", Detail: "server seed has not been revealed yet"})
+	} else {
+		hashed := HashServerSeed(p.ServerSeed)
+		report.add(Check{
+			Name:   "server seed hash",
+			Passed: hashed == p.ServerSeedHashed,
+			Detail: fmt.Sprintf("SHA256(serverSeed) = %s, committed %s", hashed, p.ServerSeedHashed),
+		})
+	}
+
+	algo, err := Lookup(p.FairnessVersion)
+	if err != nil {
+		report.add(Check{Name: "fairness version", Detail: err.Error()})
+		return report.finish()
+	}
+	report.Message = algo.Message(in)
+
+	// The beacon value itself is public; point the reader at it
+	if p.BeaconRandomness != "" {
+		report.add(Check{
+			Name:    "beacon",
+			Passed:  true,
+			Skipped: true,
+			Detail:  fmt.Sprintf("round %d randomness %s; compare with the public beacon", p.BeaconRound, p.BeaconRandomness),
+		})
+	}
+	if p.ServerSeed == "" {
+		return report.finish()
+	}
+
+	// 2. Recompute the outcome hash
+	playerAWins, outcomeHash := algo.Outcome(in)
+	report.OutcomeHash = outcomeHash
+	report.DecValue = DecValue(outcomeHash)
+	if p.OutcomeHash == "" {
+		report.add(Check{Name: "outcome hash", Passed: true, Skipped: true, Detail: "no outcome hash supplied, recomputed " + outcomeHash})
+	} else {
+		report.add(Check{
+			Name:   "outcome hash",
+			Passed: outcomeHash == p.OutcomeHash,
+			Detail: fmt.Sprintf("HMAC(serverSeed, %q) = %s", report.Message, outcomeHash),
+		})
+	}
+
+	// 3. Re-derive the winner
+	report.DerivedWinner = "playerB"
+	if playerAWins {
+		report.DerivedWinner = "playerA"
+	}
+	report.WinnerMatches = report.DerivedWinner == p.Winner
+	report.add(Check{
+		Name:   "winner",
+		Passed: report.WinnerMatches,
+		Detail: fmt.Sprintf("decValue %d derives %s, recorded %s", report.DecValue, report.DerivedWinner, p.Winner),
+	})
+
+	// 4. Re-derive the fight script
+	if len(p.FightScript) == 0 {
+		report.add(Check{Name: "fight script", Passed: true, Skipped: true, Detail: "no fight script supplied"})
+		return report.finish()
+	}
+	var recorded FightScript
+	if err := json.Unmarshal(p.FightScript, &recorded); err != nil {
+		report.add(Check{Name: "fight script", Detail: "recorded fight script is not valid JSON: " + err.Error()})
+		return report.finish()
+	}
+	derived := algo.FightScript(in, recorded.MatchID, recorded.PlayerA, recorded.PlayerB, report.DerivedWinner)
+
+	// Compare canonical encodings; the database may reformat stored JSON
+	recordedJSON, _ := json.Marshal(recorded)
+	derivedJSON, _ := json.Marshal(derived)
+	check := Check{Name: "fight script", Passed: bytes.Equal(recordedJSON, derivedJSON)}
+	if check.Passed {
+		check.Detail = fmt.Sprintf("%d events reproduced exactly", len(derived.Events))
+	} else {
+		check.Detail = "re-derived fight script differs from the recorded one"
+	}
+	report.add(check)
+
+	return report.finish()
+}
+
+func (r *Report) add(c Check) {
+	r.Checks = append(r.Checks, c)
+}
+
+func (r Report) finish() Report {
+	r.Passed = len(r.Checks) > 0
+	for _, c := range r.Checks {
+		if !c.Passed {
+			r.Passed = false
+		}
+	}
+	return r
+}
@@ -0,0 +1,115 @@
+package fairness
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// blockSize is the number of bytes yielded by each HMAC round
+const blockSize = sha256.Size
+
+// ErrNoWeight is returned when a weighted pick has nothing to choose from.
+var ErrNoWeight = errors.New("fairness: weights must contain a positive total")
+
+// NewStreamAt creates a byte stream positioned at the given cursor, so a
+// single roll can be re-derived without replaying the ones before it.
+func NewStreamAt(serverSeed string, clientSeed string, nonce int64, cursor int64) *Stream {
+	s := NewStream(serverSeed, clientSeed, nonce)
+	if cursor > 0 {
+		s.round = cursor / blockSize
+		s.block = StreamBlock(serverSeed, clientSeed, nonce, s.round)
+		s.offset = int(cursor % blockSize)
+	}
+	return s
+}
+
+// Cursor returns the number of bytes consumed from the stream so far.
+// Byte i of the stream lives in round i/32 at offset i%32.
+func (s *Stream) Cursor() int64 {
+	if s.block == nil {
+		return 0
+	}
+	return s.round*blockSize + int64(s.offset)
+}
+
+// Uint64 reads the next 8 bytes as a big-endian integer.
+func (s *Stream) Uint64() uint64 {
+	var buf [8]byte
+	for i := range buf {
+		buf[i] = s.Byte()
+	}
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+// Uint64n returns a uniform value in [0, n). Draws that fall in the
+// incomplete top range are rejected and redrawn, avoiding modulo bias.
+// It panics if n is 0.
+func (s *Stream) Uint64n(n uint64) uint64 {
+	if n == 0 {
+		panic("fairness: invalid argument to Uint64n")
+	}
+	if n&(n-1) == 0 {
+		return s.Uint64() & (n - 1)
+	}
+
+	// Largest multiple of n that fits in 64 bits
+	limit := ^uint64(0) - (^uint64(0)%n+1)%n
+	for {
+		v := s.Uint64()
+		if v <= limit {
+			return v % n
+		}
+	}
+}
+
+// Intn returns a uniform value in [0, n). It panics if n <= 0.
+func (s *Stream) Intn(n int) int {
+	if n <= 0 {
+		panic("fairness: invalid argument to Intn")
+	}
+	return int(s.Uint64n(uint64(n)))
+}
+
+// WeightedIndex picks an index with probability proportional to its
+// weight, e.g. each entrant's stake in a multi-player pot.
+func (s *Stream) WeightedIndex(weights []uint64) (int, error) {
+	var total uint64
+	for _, w := range weights {
+		if total+w < total {
+			return 0, errors.New("fairness: weights overflow")
+		}
+		total += w
+	}
+	if total == 0 {
+		return 0, ErrNoWeight
+	}
+
+	target := s.Uint64n(total)
+	for i, w := range weights {
+		if target < w {
+			return i, nil
+		}
+		target -= w
+	}
+	return len(weights) - 1, nil
+}
+
+// Shuffle permutes n elements with a Fisher-Yates shuffle, calling swap
+// for each exchange.
+func (s *Stream) Shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		j := s.Intn(i + 1)
+		swap(i, j)
+	}
+}
+
+// Perm returns a permutation of the integers [0, n).
+func (s *Stream) Perm(n int) []int {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	s.Shuffle(n, func(i, j int) { p[i], p[j] = p[j], p[i] })
+	return p
+}
@@ -0,0 +1,68 @@
+package fairness
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Stream is a deterministic byte stream derived from the match seeds.
+// Round r yields the 32 bytes of HMAC(serverSeed, "clientSeed-nonce-r"),
+// so anyone holding the revealed seeds can replay every roll.
+type Stream struct {
+	serverSeed string
+	clientSeed string
+	nonce      int64
+
+	round  int64
+	block  []byte
+	offset int
+}
+
+// NewStream creates a byte stream positioned at round 0.
+func NewStream(serverSeed string, clientSeed string, nonce int64) *Stream {
+	return &Stream{
+		serverSeed: serverSeed,
+		clientSeed: clientSeed,
+		nonce:      nonce,
+	}
+}
+
+// StreamBlock returns the HMAC block for a single round of the stream.
+func StreamBlock(serverSeed string, clientSeed string, nonce int64, round int64) []byte {
+	message := fmt.Sprintf("%s-%d-%d", clientSeed, nonce, round)
+
+	h := hmac.New(sha256.New, []byte(serverSeed))
+	h.Write([]byte(message))
+	return h.Sum(nil)
+}
+
+// Byte returns the next byte, moving to a new HMAC round when the
+// current block is exhausted.
+func (s *Stream) Byte() byte {
+	if s.block == nil || s.offset == len(s.block) {
+		if s.block != nil {
+			s.round++
+		}
+		s.block = StreamBlock(s.serverSeed, s.clientSeed, s.nonce, s.round)
+		s.offset = 0
+	}
+	b := s.block[s.offset]
+	s.offset++
+	return b
+}
+
+// Uint32 reads the next 4 bytes as a big-endian integer.
+func (s *Stream) Uint32() uint32 {
+	var buf [4]byte
+	for i := range buf {
+		buf[i] = s.Byte()
+	}
+	return binary.BigEndian.Uint32(buf[:])
+}
+
+// Float64 returns a value in [0, 1) built from the next 4 bytes.
+func (s *Stream) Float64() float64 {
+	return float64(s.Uint32()) / (1 << 32)
+}
@@ -0,0 +1,184 @@
+package fairness
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Version 1 is frozen: this file is the only copy of its rules, so nothing
+// outside it can alter how historical matches verify. Do not edit it; add
+// a new version instead.
+
+const (
+	v1FighterHealth = 99
+	v1MaxExchanges  = 24
+)
+
+var v1AttackTypes = []string{"attack_light", "attack_heavy", "attack_special"}
+
+// v1Message is the HMAC message of the outcome: "clientSeed-nonce"
+func v1Message(clientSeed string, nonce int64) string {
+	return fmt.Sprintf("%s-%d", clientSeed, nonce)
+}
+
+// v1Outcome returns true if player A wins: the first 32 bits of
+// HMAC-SHA256(serverSeed, message) are even
+func v1Outcome(serverSeed, clientSeed string, nonce int64) (bool, string) {
+	h := hmac.New(sha256.New, []byte(serverSeed))
+	h.Write([]byte(v1Message(clientSeed, nonce)))
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	decValue, _ := strconv.ParseUint(hash[:8], 16, 64)
+	return decValue%2 == 0, hash
+}
+
+// v1Stream yields the bytes of HMAC(serverSeed, "clientSeed-nonce-round")
+// for rounds 0, 1, ...
+type v1Stream struct {
+	serverSeed, clientSeed string
+	nonce                  int64
+	round                  int64
+	block                  []byte
+	offset                 int
+}
+
+func (s *v1Stream) byte() byte {
+	if s.block == nil || s.offset == len(s.block) {
+		if s.block != nil {
+			s.round++
+		}
+		h := hmac.New(sha256.New, []byte(s.serverSeed))
+		h.Write([]byte(fmt.Sprintf("%s-%d-%d", s.clientSeed, s.nonce, s.round)))
+		s.block = h.Sum(nil)
+		s.offset = 0
+	}
+	b := s.block[s.offset]
+	s.offset++
+	return b
+}
+
+// float64 returns a value in [0, 1) built from the next 4 bytes
+func (s *v1Stream) float64() float64 {
+	var buf [4]byte
+	for i := range buf {
+		buf[i] = s.byte()
+	}
+	return float64(binary.BigEndian.Uint32(buf[:])) / (1 << 32)
+}
+
+// v1FightScript derives the fight animation for the decided winner
+func v1FightScript(serverSeed, clientSeed string, nonce int64, matchID string, playerA, playerB PlayerInfo, winner string) FightScript {
+	stream := &v1Stream{serverSeed: serverSeed, clientSeed: clientSeed, nonce: nonce}
+	other := func(player string) string {
+		if player == "playerA" {
+			return "playerB"
+		}
+		return "playerA"
+	}
+	round := func(t float64) float64 {
+		return math.Round(t*100) / 100
+	}
+
+	loser := other(winner)
+	health := map[string]int{
+		"playerA": v1FighterHealth,
+		"playerB": v1FighterHealth,
+	}
+
+	events := []FightEvent{
+		{Time: 0.2, Type: "move_fwd", Actor: "playerA"},
+		{Time: 0.3, Type: "move_fwd", Actor: "playerB"},
+	}
+
+	currentTime := 0.5
+	attacker := "playerA"
+	if stream.float64() >= 0.5 {
+		attacker = "playerB"
+	}
+
+	for exchange := 0; ; exchange++ {
+		defender := other(attacker)
+		attackType := v1AttackTypes[int(stream.float64()*float64(len(v1AttackTypes)))]
+
+		hitChance := 0.60
+		if attacker == winner {
+			hitChance = 0.80
+		}
+		forceFinish := attacker == winner && exchange >= v1MaxExchanges
+
+		hit := forceFinish || stream.float64() < hitChance
+		crit := hit && stream.float64() > 0.85
+		dodge := !hit && stream.float64() > 0.5
+
+		damage := 0
+		if hit {
+			damage = 10 + int(stream.float64()*10)
+			if attacker == winner {
+				damage += 3
+			}
+			if crit {
+				damage = damage * 3 / 2
+			}
+		}
+
+		if attacker == winner && hit && (forceFinish || damage >= health[loser]) {
+			damage = health[loser]
+			health[loser] = 0
+
+			events = append(events,
+				FightEvent{Time: round(currentTime), Type: "finish_move", Actor: winner, Hit: true, Crit: crit, Damage: damage},
+				FightEvent{Time: round(currentTime + 0.5), Type: "ko", Actor: loser},
+				FightEvent{Time: round(currentTime + 1.0), Type: "victory", Actor: winner},
+			)
+			break
+		}
+
+		if attacker == loser && damage >= health[winner] {
+			damage = health[winner] - 1
+			if damage == 0 {
+				hit, crit = false, false
+			}
+		}
+
+		health[defender] -= damage
+
+		events = append(events, FightEvent{
+			Time:   round(currentTime),
+			Type:   attackType,
+			Actor:  attacker,
+			Hit:    hit,
+			Crit:   crit,
+			Damage: damage,
+		})
+
+		reactionType := "react_hit"
+		if dodge {
+			reactionType = "react_dodge"
+		} else if !hit {
+			reactionType = "react_block"
+		}
+		events = append(events, FightEvent{
+			Time:  round(currentTime + 0.1),
+			Type:  reactionType,
+			Actor: defender,
+			Dodge: dodge,
+		})
+
+		attacker = defender
+		currentTime += 1.8 + stream.float64()*0.6
+	}
+
+	return FightScript{
+		MatchID:  matchID,
+		PlayerA:  playerA,
+		PlayerB:  playerB,
+		Winner:   winner,
+		Duration: round(currentTime + 1.2),
+		Events:   events,
+	}
+}
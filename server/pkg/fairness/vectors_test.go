@@ -0,0 +1,97 @@
+package fairness
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+// The vectors in testdata/vectors.json are shared with the WebAssembly
+// build (web/scripts/check-fairness-wasm.mjs). Regenerate them only when a
+// new algorithm version is added:
+//
+//	go test ./pkg/fairness -run TestVectors -update
+var update = flag.Bool("update", false, "rewrite testdata/vectors.json")
+
+const vectorsPath = "testdata/vectors.json"
+
+type vector struct {
+	Version     int        `json:"version"`
+	ServerSeed  string     `json:"serverSeed"`
+	ClientSeedA string     `json:"clientSeedA"`
+	ClientSeedB string     `json:"clientSeedB"`
+	Nonce       int64      `json:"nonce"`
+	Beacon      string     `json:"beacon,omitempty"`
+	MatchID     string     `json:"matchId"`
+	PlayerA     PlayerInfo `json:"playerA"`
+	PlayerB     PlayerInfo `json:"playerB"`
+	Expected    expected   `json:"expected"`
+}
+
+type expected struct {
+	Message           string `json:"message"`
+	Hash              string `json:"hash"`
+	DecValue          uint64 `json:"decValue"`
+	IsPlayerAWin      bool   `json:"isPlayerAWin"`
+	FightScriptSha256 string `json:"fightScriptSha256"`
+}
+
+// derive computes the expected values for a vector with the Go code
+func derive(v vector) expected {
+	algo, err := Lookup(v.Version)
+	if err != nil {
+		panic(err)
+	}
+	in := Input{
+		ServerSeed: v.ServerSeed,
+		ClientSeed: CombineClientSeeds(v.ClientSeedA, v.ClientSeedB),
+		Nonce:      v.Nonce,
+		Beacon:     v.Beacon,
+	}
+	playerAWins, hash := algo.Outcome(in)
+	winner := "playerB"
+	if playerAWins {
+		winner = "playerA"
+	}
+	script, _ := json.Marshal(algo.FightScript(in, v.MatchID, v.PlayerA, v.PlayerB, winner))
+	sum := sha256.Sum256(script)
+
+	return expected{
+		Message:           algo.Message(in),
+		Hash:              hash,
+		DecValue:          DecValue(hash),
+		IsPlayerAWin:      playerAWins,
+		FightScriptSha256: hex.EncodeToString(sum[:]),
+	}
+}
+
+func TestVectors(t *testing.T) {
+	data, err := os.ReadFile(vectorsPath)
+	if err != nil {
+		t.Fatalf("Failed to read vectors: %v", err)
+	}
+	var vectors []vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("Failed to parse vectors: %v", err)
+	}
+
+	if *update {
+		for i := range vectors {
+			vectors[i].Expected = derive(vectors[i])
+		}
+		out, _ := json.MarshalIndent(vectors, "", "  ")
+		if err := os.WriteFile(vectorsPath, append(out, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	for i, v := range vectors {
+		if got := derive(v); got != v.Expected {
+			t.Errorf("Vector %d (v%d, nonce %d) mismatch:\n got  %+v\n want %+v", i, v.Version, v.Nonce, got, v.Expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package fairness
+
+import "fmt"
+
+// CurrentVersion is the algorithm used for new matches. Bump it whenever
+// the outcome or fight-script derivation changes; old versions stay
+// registered so historical matches remain verifiable.
+const CurrentVersion = 1
+
+// BeaconVersion is used instead of CurrentVersion when a public randomness
+// beacon round is mixed into the match.
+const BeaconVersion = 2
+
+// Input is everything a match is derived from
+type Input struct {
+	ServerSeed string
+	ClientSeed string
+	Nonce      int64
+	Beacon     string // Beacon randomness, only used by beacon versions
+}
+
+// Algorithm is one frozen revision of the fairness rules. Each version
+// keeps its own copy of the rules (see v1.go); exported helpers such as
+// CalculateOutcome wrap a version, never the other way round.
+type Algorithm struct {
+	Version     int
+	Description string
+
+	// Message returns the HMAC message the outcome is drawn from
+	Message func(in Input) string
+
+	// Outcome returns true if player A wins, plus the raw outcome hash
+	Outcome func(in Input) (bool, string)
+
+	// FightScript derives the animation for the decided winner
+	FightScript func(in Input, matchID string, playerA, playerB PlayerInfo, winner string) FightScript
+}
+
+var algorithms = map[int]Algorithm{
+	1: {
+		Version:     1,
+		Description: "HMAC-SHA256(serverSeed, clientSeed-nonce), first 32 bits even = player A; fight rolls from HMAC(serverSeed, clientSeed-nonce-round)",
+		Message: func(in Input) string {
+			return v1Message(in.ClientSeed, in.Nonce)
+		},
+		Outcome: func(in Input) (bool, string) {
+			return v1Outcome(in.ServerSeed, in.ClientSeed, in.Nonce)
+		},
+		FightScript: func(in Input, matchID string, playerA, playerB PlayerInfo, winner string) FightScript {
+			return v1FightScript(in.ServerSeed, in.ClientSeed, in.Nonce, matchID, playerA, playerB, winner)
+		},
+	},
+	2: {
+		Version:     2,
+		Description: "Version 1 with the beacon randomness appended to the client seed: clientSeed-beacon",
+		Message: func(in Input) string {
+			return v1Message(beaconClientSeed(in), in.Nonce)
+		},
+		Outcome: func(in Input) (bool, string) {
+			return v1Outcome(in.ServerSeed, beaconClientSeed(in), in.Nonce)
+		},
+		FightScript: func(in Input, matchID string, playerA, playerB PlayerInfo, winner string) FightScript {
+			return v1FightScript(in.ServerSeed, beaconClientSeed(in), in.Nonce, matchID, playerA, playerB, winner)
+		},
+	},
+}
+
+// beaconClientSeed mixes the beacon randomness into the client seed
+func beaconClientSeed(in Input) string {
+	return in.ClientSeed + "-" + in.Beacon
+}
+
+// Lookup returns the algorithm registered for a version.
+func Lookup(version int) (Algorithm, error) {
+	algo, ok := algorithms[version]
+	if !ok {
+		return Algorithm{}, fmt.Errorf("fairness: unknown algorithm version %d", version)
+	}
+	return algo, nil
+}
+
+// Current returns the algorithm used for new matches.
+func Current() Algorithm {
+	return algorithms[CurrentVersion]
+}
+
+// CombineClientSeeds joins both players' client seeds into the match client seed.
+func CombineClientSeeds(clientSeedA, clientSeedB string) string {
+	return clientSeedA + "-" + clientSeedB
+}
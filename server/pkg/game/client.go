@@ -2,12 +2,20 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
+
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/models"
 )
 
 const (
@@ -39,14 +47,39 @@ type Client struct {
 	// User ID derived from Auth Middleware
 	UserID string
 
-	// Wager amount for matchmaking (in lamports)
-	WagerAmount int64
-
 	// Lobby State
 	X         float64 `json:"x"`
 	Y         float64 `json:"y"`
 	Character string  `json:"character"`
 	InLobby   bool    `json:"in_lobby"`
+
+	// Room of the match in progress, if any
+	room   *GameRoom
+	roomMu sync.Mutex
+}
+
+// SetRoom records the match the client is playing, or clears it with nil.
+func (c *Client) SetRoom(room *GameRoom) {
+	c.roomMu.Lock()
+	c.room = room
+	c.roomMu.Unlock()
+}
+
+// ClearRoom forgets room if it is still the client's match, so a finished
+// match cannot clear a newer one.
+func (c *Client) ClearRoom(room *GameRoom) {
+	c.roomMu.Lock()
+	if c.room == room {
+		c.room = nil
+	}
+	c.roomMu.Unlock()
+}
+
+// Room returns the match the client is playing, or nil.
+func (c *Client) Room() *GameRoom {
+	c.roomMu.Lock()
+	defer c.roomMu.Unlock()
+	return c.room
 }
 
 // readPump pumps messages from the websocket connection to the hub.
@@ -76,19 +109,35 @@ func (c *Client) readPump() {
 
 		switch msg.Type {
 		case MsgTypeJoinQueue:
-			// Parse wager amount from payload
-			wagerAmount := int64(100_000_000) // Default 0.1 SOL
-			if msg.Payload != nil {
-				if wa, ok := msg.Payload["wagerAmount"].(float64); ok {
-					wagerAmount = int64(wa)
-				}
+			if c.Room() != nil {
+				errMsg, _ := json.Marshal(map[string]string{"type": MsgTypeError, "error": "Already in a match"})
+				c.Send <- errMsg
+				continue
+			}
+			// Parse currency and wager amount from payload
+			code := models.CurrencySOL
+			if cur, ok := msg.Payload["currency"].(string); ok && cur != "" {
+				code = models.Currency(strings.ToUpper(cur))
 			}
-			c.WagerAmount = wagerAmount
-			log.Printf("Player %s joined queue with wager %d lamports", c.UserID, wagerAmount)
-			c.Matchmaker.Add(c)
+			info, _ := currency.Lookup(code)
+			wagerAmount := info.DefaultWager
+			if wa, ok := msg.Payload["wagerAmount"].(float64); ok {
+				wagerAmount = int64(wa)
+			}
+			if reason := c.cannotWager(code, wagerAmount); reason != "" {
+				errMsg, _ := json.Marshal(map[string]string{"type": MsgTypeError, "error": reason})
+				c.Send <- errMsg
+				continue
+			}
+			log.Printf("Player %s joined %s queue with wager %d", c.UserID, code, wagerAmount)
+			c.Matchmaker.Add(Ticket{Client: c, Currency: code, WagerAmount: wagerAmount})
 		case MsgTypeLeaveQueue:
 			log.Printf("Player %s left queue", c.UserID)
 			// TODO: Remove from queue
+		case MsgTypeClientSeedCommit, MsgTypeClientSeedReveal:
+			if room := c.Room(); room != nil {
+				room.HandleSeedMessage(c, msg)
+			}
 		case MsgTypePing:
 			c.Send <- []byte(`{"type":"PONG"}`)
 		case MsgTypeLobbyEnter:
@@ -116,6 +165,34 @@ func (c *Client) readPump() {
 	}
 }
 
+// cannotWager explains why the player may not queue with amount of the
+// currency, or returns "" if they may. The wager must be within the
+// currency's limits and players need a balance covering it. Every currency
+// gets a wallet at login, so holding one says nothing.
+func (c *Client) cannotWager(code models.Currency, amount int64) string {
+	switch err := currency.CheckWager(code, amount); {
+	case errors.Is(err, currency.ErrWagerOutOfRange):
+		info, _ := currency.Lookup(code)
+		return "Wager must be between " + info.Display(info.MinWager) + " and " + info.Display(info.MaxWager)
+	case err != nil:
+		return "Wagering is not available for this currency"
+	}
+
+	userID, err := uuid.Parse(c.UserID)
+	if err != nil {
+		return "Invalid user ID"
+	}
+	balance, err := ledger.Balance(userID, code)
+	if err != nil {
+		log.Printf("Wallet lookup for %s failed: %v", c.UserID, err)
+		return "Failed to fetch wallet"
+	}
+	if balance < amount {
+		return "Insufficient " + string(code) + " balance"
+	}
+	return ""
+}
+
 // writePump pumps messages from the hub to the websocket connection.
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -5,79 +5,118 @@ import (
 	"sync"
 
 	"github.com/google/uuid"
+
+	"github.com/hugolol/gamblefights/pkg/beacon"
+	"github.com/hugolol/gamblefights/pkg/models"
 )
 
+// Ticket is a player's place in the queue. It is built once when the join
+// request is read and never changes, so a later request from the same
+// client cannot alter the stake of a queued or matched one.
+type Ticket struct {
+	Client      *Client
+	Currency    models.Currency
+	WagerAmount int64 // In atomic units of Currency
+}
+
 // Matchmaker handles queuing players and forming matches.
 type Matchmaker struct {
-	Queue chan *Client
+	Queue chan Ticket
 	Hub   *Hub
 	m     sync.Mutex
+
+	// Optional public randomness beacon mixed into every outcome
+	Beacon beacon.Client
 }
 
 func NewMatchmaker(hub *Hub) *Matchmaker {
 	mm := &Matchmaker{
-		Queue: make(chan *Client, 100),
+		Queue: make(chan Ticket, 100),
 		Hub:   hub,
 	}
 	go mm.Run()
 	return mm
 }
 
-func (mm *Matchmaker) Add(client *Client) {
-	mm.Queue <- client
-	client.Send <- []byte(`{"type":"QUEUE_JOINED"}`)
+func (mm *Matchmaker) Add(ticket Ticket) {
+	mm.Queue <- ticket
+	ticket.Client.Send <- []byte(`{"type":"QUEUE_JOINED"}`)
 }
 
+// Run pairs queued players. Each currency has its own queue, so players
+// only meet opponents staking the same asset.
 func (mm *Matchmaker) Run() {
-	var pending *Client
+	pending := map[models.Currency]Ticket{}
+
+	for ticket := range mm.Queue {
+		// Rejoining in another currency leaves the previous queue, from
+		// any of the player's connections
+		for currency, waiting := range pending {
+			if waiting.Client.UserID == ticket.Client.UserID && currency != ticket.Currency {
+				delete(pending, currency)
+			}
+		}
 
-	for client := range mm.Queue {
-		if pending == nil {
+		waiting, ok := pending[ticket.Currency]
+		if !ok {
 			// First player in pair
-			pending = client
-			log.Printf("Player %s waiting for match...", client.UserID)
+			pending[ticket.Currency] = ticket
+			log.Printf("Player %s waiting for %s match...", ticket.Client.UserID, ticket.Currency)
 		} else {
 			// Second player found -> Match!
-			opponent := client
+			opponent := ticket
 
-			// Prevent matching with self (basic check)
-			if pending == opponent {
+			// Prevent matching with self, even from another tab or device;
+			// the latest wager replaces the old one
+			if waiting.Client.UserID == opponent.Client.UserID {
+				pending[ticket.Currency] = opponent
 				continue
 			}
 
 			// Verify both still connected
-			if !mm.Hub.IsConnected(pending) {
-				pending = opponent
+			if !mm.Hub.IsConnected(waiting.Client) {
+				pending[ticket.Currency] = opponent
 				continue
 			}
 
-			mm.CreateMatch(pending, opponent)
-			pending = nil
+			mm.CreateMatch(waiting, opponent)
+			delete(pending, ticket.Currency)
 		}
 	}
 }
 
-func (mm *Matchmaker) CreateMatch(p1, p2 *Client) {
+// CreateMatch starts a match between two tickets in the same currency. Only
+// the tickets are read, never the clients' later requests.
+func (mm *Matchmaker) CreateMatch(t1, t2 Ticket) {
 	matchID := uuid.New().String()
+	p1, p2 := t1.Client, t2.Client
 
 	// Use the lower wager amount of the two players
-	wagerAmount := p1.WagerAmount
-	if p2.WagerAmount < wagerAmount {
-		wagerAmount = p2.WagerAmount
+	wagerAmount := t1.WagerAmount
+	if t2.WagerAmount < wagerAmount {
+		wagerAmount = t2.WagerAmount
 	}
 
-	log.Printf("Match created: %s vs %s (ID: %s, Wager: %d)", p1.UserID, p2.UserID, matchID, wagerAmount)
+	currency := t1.Currency
+
+	log.Printf("Match created: %s vs %s (ID: %s, Wager: %d %s)", p1.UserID, p2.UserID, matchID, wagerAmount, currency)
+
+	// Create the game room first so seed commits are routed to it
+	room := NewGameRoom(matchID, p1, p2, wagerAmount, currency, mm.Hub)
+	room.Beacon = mm.Beacon
+	p1.SetRoom(room)
+	p2.SetRoom(room)
 
 	// Notify players that match is found
-	matchFoundMsg := []byte(`{"type":"MATCH_FOUND","matchId":"` + matchID + `","wagerAmount":` + intToStr(wagerAmount) + `}`)
+	matchFoundMsg := []byte(`{"type":"MATCH_FOUND","matchId":"` + matchID + `","wagerAmount":` + intToStr(wagerAmount) + `,"currency":"` + string(currency) + `"}`)
 	p1.Send <- matchFoundMsg
 	p2.Send <- matchFoundMsg
 
-	// Create and start the game room
-	room := NewGameRoom(matchID, p1, p2, wagerAmount, mm.Hub)
-
 	// Run match in goroutine
 	go func() {
+		defer p1.ClearRoom(room)
+		defer p2.ClearRoom(room)
+
 		// Small delay to allow clients to prepare
 		// time.Sleep(500 * time.Millisecond)
 
@@ -8,6 +8,9 @@ const (
 	MsgTypeLobbyEnter    = "LOBBY_ENTER"
 	MsgTypeLobbyMove     = "LOBBY_MOVE"
 	MsgTypeLobbySnapshot = "LOBBY_SNAPSHOT"
+
+	MsgTypeClientSeedCommit = "CLIENT_SEED_COMMIT" // {matchId, commitment: sha256(clientSeed)}
+	MsgTypeClientSeedReveal = "CLIENT_SEED_REVEAL" // {matchId, clientSeed}
 )
 
 // Outgoing Message Types
@@ -19,6 +22,9 @@ const (
 	MsgTypeMatchError  = "MATCH_ERROR"
 	MsgTypePong        = "PONG"
 	MsgTypeError       = "ERROR"
+
+	MsgTypeSeedExchangeStart    = "SEED_EXCHANGE_START"    // Commit a fresh client seed now
+	MsgTypeClientSeedsCommitted = "CLIENT_SEEDS_COMMITTED" // Both committed, reveal now
 )
 
 // Incoming Message Structure
@@ -29,8 +35,8 @@ type IncomingMessage struct {
 
 // JoinQueuePayload contains wager info when joining queue
 type JoinQueuePayload struct {
-	WagerAmount int64  `json:"wagerAmount"` // In lamports
-	Currency    string `json:"currency"`    // "SOL", "TON", etc.
+	WagerAmount int64  `json:"wagerAmount"` // In atomic units of Currency
+	Currency    string `json:"currency"`    // "SOL", "TON" or "USDT"; defaults to SOL
 }
 
 // Outgoing Message Structure
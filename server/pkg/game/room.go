@@ -1,63 +1,55 @@
 package game
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
-	"math/rand"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/hugolol/gamblefights/pkg/beacon"
 	"github.com/hugolol/gamblefights/pkg/db"
 	"github.com/hugolol/gamblefights/pkg/fairness"
+	"github.com/hugolol/gamblefights/pkg/ledger"
 	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/rake"
+	"github.com/hugolol/gamblefights/pkg/receipt"
+	"github.com/hugolol/gamblefights/pkg/seeds"
+	"github.com/hugolol/gamblefights/pkg/seedvault"
 )
 
-// FightEvent represents a single event in the fight animation
-type FightEvent struct {
-	Time   float64 `json:"time"`   // Seconds from start
-	Type   string  `json:"type"`   // Event type
-	Actor  string  `json:"actor"`  // "playerA" or "playerB"
-	Hit    bool    `json:"hit,omitempty"`
-	Crit   bool    `json:"crit,omitempty"`
-	Dodge  bool    `json:"dodge,omitempty"`
-	Damage int     `json:"damage,omitempty"`
-}
-
-// FightScript is the complete animation script sent to clients
-type FightScript struct {
-	MatchID  string       `json:"matchId"`
-	PlayerA  PlayerInfo   `json:"playerA"`
-	PlayerB  PlayerInfo   `json:"playerB"`
-	Winner   string       `json:"winner"` // "playerA" or "playerB"
-	Duration float64      `json:"duration"`
-	Events   []FightEvent `json:"events"`
-}
-
-// PlayerInfo for the fight script
-type PlayerInfo struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Character string `json:"character"`
-	Skin      string `json:"skin"`
-}
+// beaconTimeout bounds how long a match waits for the next beacon round
+const beaconTimeout = 60 * time.Second
 
 // GameRoom manages a single match between two players
 type GameRoom struct {
-	ID        string
-	PlayerA   *Client
-	PlayerB   *Client
-	Match     *models.Match
-	Hub       *Hub
+	ID      string
+	PlayerA *Client
+	PlayerB *Client
+	Match   *models.Match
+	Hub     *Hub
+	Beacon  beacon.Client
+
+	// Currency both players wager and are settled in
+	Currency models.Currency
+
+	// Commits and reveals from the players during the seed handshake
+	seedMsgs chan seedMessage
 }
 
 // NewGameRoom creates a new game room for two matched players
-func NewGameRoom(id string, p1, p2 *Client, wagerAmount int64, hub *Hub) *GameRoom {
+func NewGameRoom(id string, p1, p2 *Client, wagerAmount int64, currency models.Currency, hub *Hub) *GameRoom {
 	return &GameRoom{
-		ID:      id,
-		PlayerA: p1,
-		PlayerB: p2,
-		Hub:     hub,
+		ID:       id,
+		PlayerA:  p1,
+		PlayerB:  p2,
+		Hub:      hub,
+		Currency: currency,
+		seedMsgs: make(chan seedMessage, 8),
 	}
 }
 
@@ -74,33 +66,91 @@ func (gr *GameRoom) StartMatch(wagerAmount int64) error {
 		return err
 	}
 
-	// Lock wagers from both players
-	if err := gr.lockWager(userA.ID, wagerAmount); err != nil {
-		gr.notifyError("Player A has insufficient balance")
+	// Draw from player A's pre-committed seed. The match is recorded under
+	// the seed lock so the seed cannot be rotated (and revealed) before the
+	// match is in progress.
+	match := models.Match{
+		ID:              uuid.MustParse(gr.ID),
+		PlayerAID:       userA.ID,
+		PlayerBID:       userB.ID,
+		WagerAmount:     wagerAmount,
+		Currency:        gr.Currency,
+		FairnessVersion: fairness.CurrentVersion,
+		Status:          models.MatchStatusInProgress,
+	}
+	var serverSeed string
+	pair, err := seeds.Draw(userA.ID, func(tx *gorm.DB, pair *seeds.Pair) error {
+		var err error
+		if serverSeed, err = seedvault.Open(pair.Active.Seed); err != nil {
+			return err
+		}
+		// Record the match up front (keyed by the room ID so the script's
+		// matchId lines up) so wagers can be booked against it
+		match.ServerSeed = pair.Active.Seed // Stays sealed until rotation
+		match.ServerSeedHashed = pair.Active.SeedHashed
+		return tx.Create(&match).Error
+	})
+	if err != nil {
+		gr.notifyError("Failed to create match")
+		return err
+	}
+
+	// Move both wagers into escrow in one transaction
+	if err := ledger.PlaceBets(match.ID, match.Currency, wagerAmount, userA.ID, userB.ID); err != nil {
+		db.DB.Model(&match).Update("status", models.MatchStatusCancelled)
+		var fundsErr *ledger.FundsError
+		switch {
+		case errors.As(err, &fundsErr) && fundsErr.UserID == userA.ID:
+			gr.notifyError("Player A has insufficient balance")
+		case errors.As(err, &fundsErr):
+			gr.notifyError("Player B has insufficient balance")
+		default:
+			gr.notifyError("Failed to lock wagers")
+		}
 		return err
 	}
-	if err := gr.lockWager(userB.ID, wagerAmount); err != nil {
-		// Refund player A
-		gr.refundWager(userA.ID, wagerAmount)
-		gr.notifyError("Player B has insufficient balance")
+
+	// Both players commit and reveal a fresh client seed for this match
+	clientSeedA, clientSeedB, err := gr.exchangeClientSeeds(pair.Active.SeedHashed)
+	if err != nil {
+		gr.cancelMatch(&match, "Match cancelled: "+err.Error()+", wagers refunded")
 		return err
 	}
 
-	// Generate server seed
-	serverSeed, err := fairness.GenerateServerSeed()
+	// The nonce belongs to the seed pair drawn from, which is player A's;
+	// player B's pair is untouched and either player can reveal A's
+	nonce, err := seeds.ReserveNonce(userA.ID)
 	if err != nil {
-		gr.refundWager(userA.ID, wagerAmount)
-		gr.refundWager(userB.ID, wagerAmount)
-		gr.notifyError("Failed to generate server seed")
+		gr.cancelMatch(&match, "Failed to reserve nonce")
 		return err
 	}
 
 	// Combine client seeds
-	combinedClientSeed := userA.ClientSeed + "-" + userB.ClientSeed
-	nonce := userA.Nonce // Use player A's nonce
+	algo := fairness.Current()
+	input := fairness.Input{
+		ServerSeed: serverSeed,
+		ClientSeed: fairness.CombineClientSeeds(clientSeedA, clientSeedB),
+		Nonce:      nonce,
+	}
+
+	// Mix in a beacon round published after both wagers were locked
+	var beaconRound *int64
+	if gr.Beacon != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), beaconTimeout)
+		round, err := beacon.Next(ctx, gr.Beacon, time.Second)
+		cancel()
+		if err != nil {
+			gr.cancelMatch(&match, "Failed to fetch randomness beacon")
+			return err
+		}
+		number := int64(round.Number)
+		beaconRound = &number
+		input.Beacon = round.Randomness
+		algo, _ = fairness.Lookup(fairness.BeaconVersion)
+	}
 
 	// Calculate outcome
-	playerAWins, outcomeHash := fairness.CalculateOutcome(serverSeed, combinedClientSeed, nonce)
+	playerAWins, outcomeHash := algo.Outcome(input)
 
 	// Determine winner
 	var winnerID uuid.UUID
@@ -113,61 +163,71 @@ func (gr *GameRoom) StartMatch(wagerAmount int64) error {
 		winnerStr = "playerB"
 	}
 
-	// Generate fight script
-	fightScript := gr.generateFightScript(userA, userB, winnerStr)
+	// Derive fight script from the same seeds
+	fightScript := algo.FightScript(input, gr.ID, playerInfo(userA), playerInfo(userB), winnerStr)
 	fightScriptJSON, _ := json.Marshal(fightScript)
 
-	// Create match record
+	// Payout winner (gets both wagers minus the house rake)
+	totalPot := wagerAmount * 2
+	houseRake := rake.Amount(match.Currency, wagerAmount, totalPot)
+	payout := totalPot - houseRake
+
+	// Complete match record
 	now := time.Now()
-	match := models.Match{
-		PlayerAID:        userA.ID,
-		PlayerBID:        userB.ID,
-		WagerAmount:      wagerAmount,
-		Currency:         models.CurrencySOL,
-		ServerSeed:       serverSeed,
-		ServerSeedHashed: fairness.HashServerSeed(serverSeed),
-		ClientSeedA:      userA.ClientSeed,
-		ClientSeedB:      userB.ClientSeed,
-		Nonce:            nonce,
-		WinnerID:         &winnerID,
-		Status:           models.MatchStatusCompleted,
-		FightScript:      string(fightScriptJSON),
-		FinishedAt:       &now,
+	match.ClientSeedA = clientSeedA
+	match.ClientSeedB = clientSeedB
+	match.Nonce = nonce
+	match.FairnessVersion = algo.Version
+	match.BeaconRound = beaconRound
+	match.BeaconRandomness = input.Beacon
+	match.WinnerID = &winnerID
+	match.Status = models.MatchStatusCompleted
+	match.FightScript = string(fightScriptJSON)
+	match.FinishedAt = &now
+	match.Rake = houseRake
+
+	// Sign the settlement receipt players can take off-platform
+	signedReceipt, err := receipt.SignMatch(&match, payout)
+	if err != nil {
+		log.Printf("Failed to sign receipt for match %s: %v", match.ID, err)
 	}
 
-	if err := db.DB.Create(&match).Error; err != nil {
-		gr.refundWager(userA.ID, wagerAmount)
-		gr.refundWager(userB.ID, wagerAmount)
-		log.Printf("Failed to create match: %v", err)
+	// Pay out of escrow and complete the match in one transaction. The seed
+	// columns are left to rotation, which alone reveals them.
+	err = ledger.Settle(match.ID, match.Currency, winnerID, totalPot, payout, func(tx *gorm.DB) error {
+		return tx.Omit(clause.Associations, "server_seed", "seed_revealed").Save(&match).Error
+	})
+	if err != nil {
+		log.Printf("Failed to settle match %s: %v", match.ID, err)
+		gr.cancelMatch(&match, "Failed to settle match")
 		return err
 	}
 
-	// Payout winner (gets both wagers minus house edge)
-	// For MVP: 0% house edge, winner gets full pot
-	totalPot := wagerAmount * 2
-	gr.payoutWinner(winnerID, totalPot, match.ID)
-
 	// Update user stats
 	gr.updateStats(userA.ID, userB.ID, winnerID, wagerAmount)
 
-	// Increment nonce for player A
-	db.DB.Model(&userA).Update("nonce", userA.Nonce+1)
-
 	// Notify players with match result
+	// The server seed stays secret until player A rotates their seed pair
 	matchResult := map[string]interface{}{
 		"type":             "MATCH_RESULT",
 		"matchId":          match.ID.String(),
 		"winner":           winnerStr,
 		"winnerId":         winnerID.String(),
-		"serverSeed":       serverSeed,
 		"serverSeedHashed": match.ServerSeedHashed,
-		"clientSeedA":      userA.ClientSeed,
-		"clientSeedB":      userB.ClientSeed,
+		"clientSeedA":      clientSeedA,
+		"clientSeedB":      clientSeedB,
 		"nonce":            nonce,
+		"fairnessVersion":  algo.Version,
+		"beaconRound":      beaconRound,
+		"beaconRandomness": input.Beacon,
 		"outcomeHash":      outcomeHash,
 		"fightScript":      fightScript,
 		"wagerAmount":      wagerAmount,
+		"currency":         match.Currency,
 		"totalPot":         totalPot,
+		"rake":             houseRake,
+		"payout":           payout,
+		"receipt":          signedReceipt,
 	}
 
 	resultJSON, _ := json.Marshal(matchResult)
@@ -179,52 +239,17 @@ func (gr *GameRoom) StartMatch(wagerAmount int64) error {
 	return nil
 }
 
-// lockWager deducts wager from user's wallet
-func (gr *GameRoom) lockWager(userID uuid.UUID, amount int64) error {
-	var wallet models.Wallet
-	err := db.DB.Where("user_id = ? AND currency = ?", userID, models.CurrencySOL).First(&wallet).Error
-	if err != nil {
-		return err
-	}
-
-	if wallet.Balance < amount {
-		return err
-	}
-
-	// Deduct balance
-	return db.DB.Model(&wallet).Update("balance", wallet.Balance-amount).Error
-}
-
-// refundWager returns wager to user's wallet
-func (gr *GameRoom) refundWager(userID uuid.UUID, amount int64) {
-	var wallet models.Wallet
-	err := db.DB.Where("user_id = ? AND currency = ?", userID, models.CurrencySOL).First(&wallet).Error
+// cancelMatch refunds both wagers from escrow and marks the match cancelled
+func (gr *GameRoom) cancelMatch(match *models.Match, message string) {
+	players := []uuid.UUID{match.PlayerAID, match.PlayerBID}
+	err := ledger.Refund(match.ID, match.Currency, match.WagerAmount, players, func(tx *gorm.DB) error {
+		return tx.Model(&models.Match{}).Where("id = ?", match.ID).
+			Update("status", models.MatchStatusCancelled).Error
+	})
 	if err != nil {
-		return
+		log.Printf("Failed to refund match %s: %v", match.ID, err)
 	}
-	db.DB.Model(&wallet).Update("balance", wallet.Balance+amount)
-}
-
-// payoutWinner credits the winner's wallet
-func (gr *GameRoom) payoutWinner(winnerID uuid.UUID, amount int64, matchID uuid.UUID) {
-	var wallet models.Wallet
-	err := db.DB.Where("user_id = ? AND currency = ?", winnerID, models.CurrencySOL).First(&wallet).Error
-	if err != nil {
-		return
-	}
-	db.DB.Model(&wallet).Update("balance", wallet.Balance+amount)
-
-	// Create transaction record
-	tx := models.Transaction{
-		UserID:   winnerID,
-		WalletID: wallet.ID,
-		MatchID:  &matchID,
-		Type:     models.TxTypeWin,
-		Amount:   amount,
-		Currency: models.CurrencySOL,
-		Status:   models.TxStatusCompleted,
-	}
-	db.DB.Create(&tx)
+	gr.notifyError(message)
 }
 
 // updateStats updates win/loss stats for both players
@@ -264,120 +289,12 @@ func (gr *GameRoom) notifyError(message string) {
 	}
 }
 
-// generateFightScript creates an entertaining fight animation sequence
-func (gr *GameRoom) generateFightScript(userA, userB models.User, winner string) FightScript {
-	events := []FightEvent{}
-
-	// Fight duration between 8-15 seconds
-	duration := 8.0 + rand.Float64()*7.0
-
-	// Generate a sequence of attacks leading to the outcome
-	currentTime := 0.5
-	playerAHealth := 100
-	playerBHealth := 100
-
-	// Initial approach
-	events = append(events, FightEvent{Time: 0.2, Type: "move_fwd", Actor: "playerA"})
-	events = append(events, FightEvent{Time: 0.3, Type: "move_fwd", Actor: "playerB"})
-
-	// Combat exchanges
-	attackTypes := []string{"attack_light", "attack_heavy", "attack_special"}
-
-	for currentTime < duration-2.0 {
-		attacker := "playerA"
-		defender := "playerB"
-		if rand.Float32() > 0.5 {
-			attacker = "playerB"
-			defender = "playerA"
-		}
-
-		attackType := attackTypes[rand.Intn(len(attackTypes))]
-		hit := rand.Float32() > 0.3 // 70% hit rate
-		crit := hit && rand.Float32() > 0.8 // 20% crit on hit
-		dodge := !hit && rand.Float32() > 0.5 // 50% dodge on miss
-
-		damage := 0
-		if hit {
-			damage = 10 + rand.Intn(15)
-			if crit {
-				damage *= 2
-			}
-			// Apply damage
-			if attacker == "playerA" {
-				playerBHealth -= damage
-			} else {
-				playerAHealth -= damage
-			}
-		}
-
-		// Attack event
-		events = append(events, FightEvent{
-			Time:   currentTime,
-			Type:   attackType,
-			Actor:  attacker,
-			Hit:    hit,
-			Crit:   crit,
-			Damage: damage,
-		})
-
-		// Reaction event
-		reactionType := "react_hit"
-		if dodge {
-			reactionType = "react_dodge"
-		} else if !hit {
-			reactionType = "react_block"
-		}
-		events = append(events, FightEvent{
-			Time:  currentTime + 0.1,
-			Type:  reactionType,
-			Actor: defender,
-			Dodge: dodge,
-		})
-
-		currentTime += 0.8 + rand.Float64()*0.6
-	}
-
-	// Finish move
-	events = append(events, FightEvent{
-		Time:  duration - 1.0,
-		Type:  "finish_move",
-		Actor: winner,
-	})
-
-	// KO
-	loser := "playerB"
-	if winner == "playerB" {
-		loser = "playerA"
-	}
-	events = append(events, FightEvent{
-		Time:  duration - 0.5,
-		Type:  "ko",
-		Actor: loser,
-	})
-
-	// Victory pose
-	events = append(events, FightEvent{
-		Time:  duration,
-		Type:  "victory",
-		Actor: winner,
-	})
-
-	return FightScript{
-		MatchID: gr.ID,
-		PlayerA: PlayerInfo{
-			ID:        userA.ID.String(),
-			Username:  userA.Username,
-			Character: "fighter",
-			Skin:      "default",
-		},
-		PlayerB: PlayerInfo{
-			ID:        userB.ID.String(),
-			Username:  userB.Username,
-			Character: "fighter",
-			Skin:      "default",
-		},
-		Winner:   winner,
-		Duration: duration,
-		Events:   events,
+// playerInfo builds the fight script entry for a user
+func playerInfo(user models.User) fairness.PlayerInfo {
+	return fairness.PlayerInfo{
+		ID:        user.ID.String(),
+		Username:  user.Username,
+		Character: "fighter",
+		Skin:      "default",
 	}
 }
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hugolol/gamblefights/pkg/fairness"
+)
+
+// Per-phase deadlines for the client seed handshake
+const (
+	seedCommitTimeout = 15 * time.Second
+	seedRevealTimeout = 15 * time.Second
+	maxClientSeedLen  = 64
+)
+
+// ErrSeedExchangeTimeout means a player did not commit or reveal in time
+var ErrSeedExchangeTimeout = errors.New("client seed exchange timed out")
+
+// seedMessage is a commit or reveal forwarded from a player's connection
+type seedMessage struct {
+	client *Client
+	kind   string
+	value  string
+}
+
+// seedSlot tracks one player's side of the handshake
+type seedSlot struct {
+	commitment string
+	seed       string
+}
+
+// HandleSeedMessage forwards a CLIENT_SEED_COMMIT or CLIENT_SEED_REVEAL to
+// the running handshake. Messages outside the handshake are dropped.
+func (gr *GameRoom) HandleSeedMessage(c *Client, msg IncomingMessage) {
+	if msg.Payload == nil {
+		return
+	}
+	if matchID, _ := msg.Payload["matchId"].(string); matchID != gr.ID {
+		return
+	}
+
+	var value string
+	switch msg.Type {
+	case MsgTypeClientSeedCommit:
+		value, _ = msg.Payload["commitment"].(string)
+	case MsgTypeClientSeedReveal:
+		value, _ = msg.Payload["clientSeed"].(string)
+	}
+
+	select {
+	case gr.seedMsgs <- seedMessage{client: c, kind: msg.Type, value: value}:
+	default:
+	}
+}
+
+// exchangeClientSeeds runs the commit/reveal handshake. Each player commits
+// to SHA256 of a fresh client seed; once both commitments are in, both
+// reveal. Neither player sees the other's seed before committing, and the
+// server seed commitment is sent up front, so no party can bias the result.
+// Reveals are never relayed to the opponent, so the last player to reveal
+// cannot learn the outcome early and walk away from a losing match.
+func (gr *GameRoom) exchangeClientSeeds(serverSeedHashed string) (seedA, seedB string, err error) {
+	var a, b seedSlot
+	slotOf := func(c *Client) *seedSlot {
+		switch c {
+		case gr.PlayerA:
+			return &a
+		case gr.PlayerB:
+			return &b
+		}
+		return nil
+	}
+
+	gr.broadcast(map[string]interface{}{
+		"type":             MsgTypeSeedExchangeStart,
+		"matchId":          gr.ID,
+		"serverSeedHashed": serverSeedHashed,
+		"timeoutMs":        seedCommitTimeout.Milliseconds(),
+	})
+
+	// Commit phase
+	deadline := time.NewTimer(seedCommitTimeout)
+	defer deadline.Stop()
+	for a.commitment == "" || b.commitment == "" {
+		select {
+		case msg := <-gr.seedMsgs:
+			slot := slotOf(msg.client)
+			if slot == nil || msg.kind != MsgTypeClientSeedCommit || slot.commitment != "" {
+				continue
+			}
+			if !validCommitment(msg.value) {
+				gr.sendTo(msg.client, map[string]interface{}{"type": MsgTypeError, "error": "Invalid client seed commitment"})
+				continue
+			}
+			slot.commitment = msg.value
+		case <-deadline.C:
+			return "", "", fmt.Errorf("%w: %s did not commit", ErrSeedExchangeTimeout, missing(a.commitment, b.commitment))
+		}
+	}
+
+	gr.broadcast(map[string]interface{}{
+		"type":        MsgTypeClientSeedsCommitted,
+		"matchId":     gr.ID,
+		"commitmentA": a.commitment,
+		"commitmentB": b.commitment,
+		"timeoutMs":   seedRevealTimeout.Milliseconds(),
+	})
+
+	// Reveal phase
+	deadline.Reset(seedRevealTimeout)
+	for a.seed == "" || b.seed == "" {
+		select {
+		case msg := <-gr.seedMsgs:
+			slot := slotOf(msg.client)
+			if slot == nil || msg.kind != MsgTypeClientSeedReveal || slot.seed != "" {
+				continue
+			}
+			if msg.value == "" || len(msg.value) > maxClientSeedLen || fairness.HashClientSeed(msg.value) != slot.commitment {
+				gr.sendTo(msg.client, map[string]interface{}{"type": MsgTypeError, "error": "Client seed does not match commitment"})
+				continue
+			}
+			slot.seed = msg.value
+		case <-deadline.C:
+			return "", "", fmt.Errorf("%w: %s did not reveal", ErrSeedExchangeTimeout, missing(a.seed, b.seed))
+		}
+	}
+
+	return a.seed, b.seed, nil
+}
+
+// validCommitment checks for a hex SHA256 digest
+func validCommitment(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// missing names the players that have not completed a phase
+func missing(a, b string) string {
+	switch {
+	case a == "" && b == "":
+		return "both players"
+	case a == "":
+		return "player A"
+	default:
+		return "player B"
+	}
+}
+
+// broadcast sends a message to both players
+func (gr *GameRoom) broadcast(msg map[string]interface{}) {
+	data, _ := json.Marshal(msg)
+	gr.PlayerA.Send <- data
+	gr.PlayerB.Send <- data
+}
+
+// sendTo sends a message to one player
+func (gr *GameRoom) sendTo(c *Client, msg map[string]interface{}) {
+	data, _ := json.Marshal(msg)
+	c.Send <- data
+}
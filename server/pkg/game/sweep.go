@@ -0,0 +1,72 @@
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// staleMatchAge is well past the longest a live match stays in progress:
+// the seed handshake plus the beacon wait. An older match lost its room,
+// usually to a restart, and would block its seed from ever rotating.
+const staleMatchAge = 2 * (seedCommitTimeout + seedRevealTimeout + beaconTimeout)
+
+// SweepStaleMatches cancels stale matches now and then every interval.
+func SweepStaleMatches(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if cancelled, err := CancelStaleMatches(); err != nil {
+			log.Printf("Stale match sweep failed: %v", err)
+		} else if cancelled > 0 {
+			log.Printf("Cancelled %d stale matches", cancelled)
+		}
+		<-ticker.C
+	}
+}
+
+// CancelStaleMatches refunds any escrowed wagers of matches in progress for
+// longer than staleMatchAge and marks them cancelled. Returns the number of
+// matches cancelled.
+func CancelStaleMatches() (int, error) {
+	var matches []models.Match
+	err := db.DB.
+		Where("status = ? AND created_at < ?", models.MatchStatusInProgress, time.Now().Add(-staleMatchAge)).
+		Find(&matches).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i, match := range matches {
+		cancel := func(tx *gorm.DB) error {
+			return tx.Model(&models.Match{}).
+				Where("id = ? AND status = ?", match.ID, models.MatchStatusInProgress).
+				Update("status", models.MatchStatusCancelled).Error
+		}
+
+		// Wagers are only in escrow if the bets were booked
+		var bets int64
+		if err := db.DB.Model(&models.JournalEntry{}).
+			Where("idempotency_key = ?", ledger.BetKey(match.ID)).
+			Count(&bets).Error; err != nil {
+			return i, err
+		}
+		if bets == 0 {
+			err = cancel(db.DB)
+		} else {
+			players := []uuid.UUID{match.PlayerAID, match.PlayerBID}
+			err = ledger.Refund(match.ID, match.Currency, match.WagerAmount, players, cancel)
+		}
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(matches), nil
+}
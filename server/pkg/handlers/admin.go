@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/reconcile"
+	"github.com/hugolol/gamblefights/pkg/treasury"
+)
+
+// ProposalResponse is an open treasury proposal
+type ProposalResponse struct {
+	ID            string `json:"id"`
+	Currency      string `json:"currency"`
+	Kind          string `json:"kind"`
+	Amount        int64  `json:"amount"`
+	AmountDisplay string `json:"amountDisplay"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	HotBalance    int64  `json:"hotBalance"`
+	CreatedAt     string `json:"createdAt"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+// ResolveProposalRequest is the body of a treasury proposal resolution
+type ResolveProposalRequest struct {
+	Status string `json:"status"` // EXECUTED or DISMISSED
+	Note   string `json:"note"`
+}
+
+// RevenueResponse is the house rake collected in one currency
+type RevenueResponse struct {
+	Currency     string `json:"currency"`
+	Matches      int64  `json:"matches"` // Completed matches in the period
+	Volume       int64  `json:"volume"`  // Total pots
+	Rake         int64  `json:"rake"`    // Taken from those pots
+	RakeDisplay  string `json:"rakeDisplay"`
+	HouseBalance int64  `json:"houseBalance"` // HOUSE ledger account, all time
+}
+
+// GetRevenue reports the rake collected per currency, optionally since a
+// time
+// GET /api/admin/revenue?since=2024-01-01T00:00:00Z
+func GetRevenue(c echo.Context) error {
+	query := db.DB.Model(&models.Match{}).
+		Select("currency, COUNT(*) AS matches, COALESCE(SUM(wager_amount * 2), 0) AS volume, COALESCE(SUM(rake), 0) AS rake").
+		Where("status = ?", models.MatchStatusCompleted).
+		Group("currency")
+	if since := c.QueryParam("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "since must be an RFC 3339 time"})
+		}
+		query = query.Where("finished_at >= ?", t)
+	}
+
+	var rows []struct {
+		Currency models.Currency
+		Matches  int64
+		Volume   int64
+		Rake     int64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch revenue"})
+	}
+
+	var house []models.LedgerAccount
+	if err := db.DB.Where("account = ?", models.AccountHouse).Find(&house).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch house account"})
+	}
+	balances := map[models.Currency]int64{}
+	for _, a := range house {
+		balances[a.Currency] = a.Balance
+	}
+
+	response := make([]RevenueResponse, len(rows))
+	for i, r := range rows {
+		response[i] = RevenueResponse{
+			Currency:     string(r.Currency),
+			Matches:      r.Matches,
+			Volume:       r.Volume,
+			Rake:         r.Rake,
+			RakeDisplay:  currency.Display(r.Rake, r.Currency),
+			HouseBalance: balances[r.Currency],
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"revenue": response,
+	})
+}
+
+// GetReconciliation returns the latest wallet reconciliation report
+// GET /api/admin/reconciliation
+func GetReconciliation(c echo.Context) error {
+	report := reconcile.Latest()
+	if report == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Reconciliation has not run yet"})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// RunReconciliation reconciles now and returns the fresh report
+// POST /api/admin/reconciliation/run
+func RunReconciliation(c echo.Context) error {
+	report, err := reconcile.RunOnce()
+	if err != nil {
+		log.Printf("Reconciliation failed: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Reconciliation failed"})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetTreasury returns the latest hot wallet report and open proposals
+// GET /api/admin/treasury
+func GetTreasury(c echo.Context) error {
+	report := treasury.Latest()
+	if report == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Treasury has not run yet"})
+	}
+	return treasuryResponse(c, report)
+}
+
+// RunTreasury re-evaluates the hot wallets now
+// POST /api/admin/treasury/run
+func RunTreasury(c echo.Context) error {
+	return treasuryResponse(c, treasury.RunOnce(c.Request().Context()))
+}
+
+func treasuryResponse(c echo.Context, report *treasury.Report) error {
+	var proposals []models.TreasuryProposal
+	err := db.DB.Where("status = ?", models.ProposalOpen).Order("created_at ASC").Find(&proposals).Error
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch proposals"})
+	}
+
+	response := make([]ProposalResponse, len(proposals))
+	for i, p := range proposals {
+		response[i] = ProposalResponse{
+			ID:            p.ID.String(),
+			Currency:      string(p.Currency),
+			Kind:          string(p.Kind),
+			Amount:        p.Amount,
+			AmountDisplay: currency.Display(p.Amount, p.Currency),
+			From:          p.FromAddress,
+			To:            p.ToAddress,
+			HotBalance:    p.HotBalance,
+			CreatedAt:     p.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:     p.UpdatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"report":    report,
+		"proposals": response,
+	})
+}
+
+// ResolveTreasuryProposal marks a proposal executed or dismissed
+// POST /api/admin/treasury/proposals/:id/resolve
+func ResolveTreasuryProposal(c echo.Context) error {
+	adminID, err := uuid.Parse(c.Get("uid").(string))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid proposal ID"})
+	}
+
+	var req ResolveProposalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	status := models.ProposalStatus(req.Status)
+	if status != models.ProposalExecuted && status != models.ProposalDismissed {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Status must be EXECUTED or DISMISSED"})
+	}
+
+	switch err := treasury.Resolve(id, adminID, status, req.Note); {
+	case err == nil:
+	case errors.Is(err, treasury.ErrNotOpen):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Proposal is not open"})
+	default:
+		log.Printf("Resolving treasury proposal %s failed: %v", id, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to resolve proposal"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Proposal resolved"})
+}
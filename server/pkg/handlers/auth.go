@@ -3,6 +3,7 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"log"
 	"net/http"
 	"strings"
 
@@ -11,6 +12,7 @@ import (
 
 	"github.com/hugolol/gamblefights/pkg/auth"
 	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
 	"github.com/hugolol/gamblefights/pkg/models"
 )
 
@@ -93,12 +95,9 @@ func WalletAuth(c echo.Context) error {
 		}
 
 		// Create default SOL wallet for new user with FREE test balance
-		wallet := models.Wallet{
-			UserID:   user.ID,
-			Currency: models.CurrencySOL,
-			Balance:  1_000_000_000, // 1 SOL free for testing
+		if _, err := ledger.Deposit(ledger.SignupBonusKey(user.ID), user.ID, models.CurrencySOL, 1_000_000_000, models.AccountFaucet, ""); err != nil {
+			log.Printf("Failed to credit test balance for %s: %v", user.ID, err)
 		}
-		db.DB.Create(&wallet)
 	}
 
 	// Generate JWT token
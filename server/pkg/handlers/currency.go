@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/rake"
+	"github.com/hugolol/gamblefights/pkg/withdrawal"
+)
+
+// WagerPreset is a suggested wager tier
+type WagerPreset struct {
+	Amount  int64  `json:"amount"`  // In atomic units
+	Display string `json:"display"` // Human readable (e.g., "0.10 SOL")
+}
+
+// RakeTier is the house rake on matches wagering at least MinWager
+type RakeTier struct {
+	MinWager int64 `json:"minWager"` // Per player, atomic units
+	BPS      int64 `json:"bps"`      // Basis points of the pot
+}
+
+// CurrencyResponse describes a currency to clients
+type CurrencyResponse struct {
+	Code         string        `json:"code"`
+	Name         string        `json:"name"`
+	Decimals     int           `json:"decimals"`
+	MinWager     int64         `json:"minWager"`
+	MaxWager     int64         `json:"maxWager"`
+	DefaultWager int64         `json:"defaultWager"`
+	Presets      []WagerPreset `json:"presets"`
+	Deposits     bool          `json:"deposits"`
+	Withdrawals  bool          `json:"withdrawals"`
+	Wagering     bool          `json:"wagering"`
+	RakeTiers    []RakeTier    `json:"rakeTiers"`
+	RakeCap      int64         `json:"rakeCap,omitempty"` // Most taken from one match
+}
+
+// GetCurrencies returns the supported currencies with their wager limits,
+// preset tiers, house rake and which operations are enabled
+// GET /api/currencies
+func GetCurrencies(c echo.Context) error {
+	infos := currency.All()
+	response := make([]CurrencyResponse, len(infos))
+	for i, info := range infos {
+		presets := make([]WagerPreset, len(info.Presets))
+		for j, amount := range info.Presets {
+			presets[j] = WagerPreset{Amount: amount, Display: info.Display(amount)}
+		}
+		schedule := rake.For(info.Code)
+		tiers := make([]RakeTier, len(schedule.Tiers))
+		for j, t := range schedule.Tiers {
+			tiers[j] = RakeTier{MinWager: t.MinWager, BPS: t.BPS}
+		}
+		response[i] = CurrencyResponse{
+			Code:         string(info.Code),
+			Name:         info.Name,
+			Decimals:     info.Decimals,
+			MinWager:     info.MinWager,
+			MaxWager:     info.MaxWager,
+			DefaultWager: info.DefaultWager,
+			Presets:      presets,
+			Deposits:     info.Deposits,
+			// Only when a chain is configured to pay them out
+			Withdrawals: info.Withdrawals && withdrawal.PayoutsFor(info.Code) != nil,
+			Wagering:    info.Wagering,
+			RakeTiers:   tiers,
+			RakeCap:     schedule.Cap,
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"currencies": response,
+	})
+}
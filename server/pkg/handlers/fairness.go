@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/hugolol/gamblefights/pkg/audit"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/merkle"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// GetFairnessAudit returns the latest statistical fairness audit
+// GET /api/fairness/audit
+func GetFairnessAudit(c echo.Context) error {
+	report := audit.Latest()
+	if report == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Audit has not run yet"})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetMerkleRoots returns the most recent daily match commitments
+// GET /api/fairness/roots
+func GetMerkleRoots(c echo.Context) error {
+	var roots []models.MerkleRoot
+	if err := db.DB.Order("day DESC").Limit(30).Find(&roots).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch roots"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"roots": roots,
+	})
+}
+
+// GetMerkleRoot returns the commitment for a single UTC day (YYYY-MM-DD)
+// GET /api/fairness/roots/:day
+func GetMerkleRoot(c echo.Context) error {
+	var root models.MerkleRoot
+	if err := db.DB.First(&root, "day = ?", c.Param("day")).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Root not found"})
+	}
+	return c.JSON(http.StatusOK, root)
+}
+
+// GetInclusionProof returns the Merkle path from a match to its daily root
+// GET /api/matches/:id/inclusion-proof
+func GetInclusionProof(c echo.Context) error {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid match ID"})
+	}
+
+	var match models.Match
+	if err := db.DB.First(&match, parsedID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+
+	inclusion, err := merkle.Prove(match)
+	switch {
+	case errors.Is(err, merkle.ErrTestFight):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Test fights are not committed"})
+	case errors.Is(err, merkle.ErrNotCommitted):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match day has not been committed yet"})
+	case errors.Is(err, merkle.ErrRootMismatch):
+		log.Printf("Match %s does not reproduce its published Merkle root", match.ID)
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match history does not match the published root"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to build inclusion proof"})
+	}
+
+	return c.JSON(http.StatusOK, inclusion)
+}
@@ -1,33 +1,50 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
+	"github.com/hugolol/gamblefights/pkg/currency"
 	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/fairness"
 	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/receipt"
 )
 
 // MatchResponse is the public match data
 type MatchResponse struct {
-	ID               string  `json:"id"`
-	PlayerA          string  `json:"playerA"`
-	PlayerAUsername  string  `json:"playerAUsername"`
-	PlayerB          string  `json:"playerB"`
-	PlayerBUsername  string  `json:"playerBUsername"`
-	WagerAmount      int64   `json:"wagerAmount"`
-	WagerDisplay     string  `json:"wagerDisplay"`
-	Currency         string  `json:"currency"`
-	Winner           *string `json:"winner"`
-	WinnerUsername   *string `json:"winnerUsername"`
-	Status           string  `json:"status"`
-	ServerSeedHashed string  `json:"serverSeedHashed"`
-	ServerSeed       *string `json:"serverSeed,omitempty"` // Only revealed after match
-	FightScript      *string `json:"fightScript,omitempty"`
-	CreatedAt        string  `json:"createdAt"`
-	FinishedAt       *string `json:"finishedAt,omitempty"`
+	ID               string          `json:"id"`
+	PlayerA          string          `json:"playerA"`
+	PlayerAUsername  string          `json:"playerAUsername"`
+	PlayerB          string          `json:"playerB"`
+	PlayerBUsername  string          `json:"playerBUsername"`
+	WagerAmount      int64           `json:"wagerAmount"`
+	WagerDisplay     string          `json:"wagerDisplay"`
+	Currency         string          `json:"currency"`
+	TotalPot         int64           `json:"totalPot"`
+	Rake             int64           `json:"rake"` // House cut of the pot
+	IsTest           bool            `json:"isTest"`
+	RakeDisplay      string          `json:"rakeDisplay"`
+	Payout           int64           `json:"payout,omitempty"` // Paid to the winner once completed
+	Winner           *string         `json:"winner"`
+	WinnerUsername   *string         `json:"winnerUsername"`
+	Status           string          `json:"status"`
+	ServerSeedHashed string          `json:"serverSeedHashed"`
+	ServerSeed       *string         `json:"serverSeed,omitempty"` // Only revealed after seed rotation
+	SeedRevealed     bool            `json:"seedRevealed"`
+	ClientSeedA      string          `json:"clientSeedA"`
+	ClientSeedB      string          `json:"clientSeedB"`
+	Nonce            int64           `json:"nonce"`
+	FairnessVersion  int             `json:"fairnessVersion"`
+	BeaconRound      *int64          `json:"beaconRound,omitempty"`
+	BeaconRandomness string          `json:"beaconRandomness,omitempty"`
+	FightScript      *string         `json:"fightScript,omitempty"`
+	CreatedAt        string          `json:"createdAt"`
+	FinishedAt       *string         `json:"finishedAt,omitempty"`
+	Receipt          *receipt.Signed `json:"receipt,omitempty"` // Signed settlement receipt
 }
 
 // GetMatchHistory returns the user's match history
@@ -55,7 +72,7 @@ func GetMatchHistory(c echo.Context) error {
 
 	response := make([]MatchResponse, len(matches))
 	for i, m := range matches {
-		response[i] = matchToResponse(m, true) // Include server seed for own matches
+		response[i] = matchToResponse(m, true) // Include revealed server seeds for own matches
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -89,6 +106,74 @@ func GetMatch(c echo.Context) error {
 	return c.JSON(http.StatusOK, matchToResponse(match, includeServerSeed))
 }
 
+// VerifyMatchResponse is the machine-readable proof for a match
+type VerifyMatchResponse struct {
+	Proof    fairness.Proof  `json:"proof"`
+	Report   fairness.Report `json:"report"`
+	WinnerID string          `json:"winnerId"`
+}
+
+// VerifyMatch recomputes a completed match from its revealed seeds
+// GET /api/matches/:id/verify
+func VerifyMatch(c echo.Context) error {
+	matchID := c.Param("id")
+
+	parsedID, err := uuid.Parse(matchID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid match ID"})
+	}
+
+	var match models.Match
+	if err := db.DB.First(&match, parsedID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+
+	if match.Status != models.MatchStatusCompleted || match.WinnerID == nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is not completed"})
+	}
+
+	if !match.SeedRevealed {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error":            "Server seed not revealed yet; rotate the seed pair to verify",
+			"serverSeedHashed": match.ServerSeedHashed,
+		})
+	}
+
+	proof := matchProof(match)
+	return c.JSON(http.StatusOK, VerifyMatchResponse{
+		Proof:    proof,
+		Report:   fairness.Verify(proof),
+		WinnerID: match.WinnerID.String(),
+	})
+}
+
+// matchProof builds the portable proof bundle for a revealed match
+func matchProof(m models.Match) fairness.Proof {
+	winner := "playerB"
+	if m.WinnerID != nil && *m.WinnerID == m.PlayerAID {
+		winner = "playerA"
+	}
+
+	proof := fairness.Proof{
+		MatchID:          m.ID.String(),
+		FairnessVersion:  m.FairnessVersion,
+		ServerSeed:       m.ServerSeed,
+		ServerSeedHashed: m.ServerSeedHashed,
+		ClientSeedA:      m.ClientSeedA,
+		ClientSeedB:      m.ClientSeedB,
+		Nonce:            m.Nonce,
+		BeaconRandomness: m.BeaconRandomness,
+		Winner:           winner,
+	}
+	if m.BeaconRound != nil {
+		proof.BeaconRound = uint64(*m.BeaconRound)
+	}
+	if m.FightScript != "" {
+		proof.FightScript = json.RawMessage(m.FightScript)
+	}
+	return proof
+}
+
 // GetLiveMatches returns recent/live matches for the live feed
 // GET /api/matches/live
 func GetLiveMatches(c echo.Context) error {
@@ -129,11 +214,23 @@ func matchToResponse(m models.Match, includeServerSeed bool) MatchResponse {
 		PlayerB:          m.PlayerBID.String(),
 		PlayerBUsername:  m.PlayerB.Username,
 		WagerAmount:      m.WagerAmount,
-		WagerDisplay:     formatBalance(m.WagerAmount, m.Currency),
+		WagerDisplay:     currency.Display(m.WagerAmount, m.Currency),
 		Currency:         string(m.Currency),
+		TotalPot:         m.WagerAmount * 2,
+		Rake:             m.Rake,
+		IsTest:           m.IsTest,
+		RakeDisplay:      currency.Display(m.Rake, m.Currency),
 		Status:           string(m.Status),
 		ServerSeedHashed: m.ServerSeedHashed,
+		SeedRevealed:     m.SeedRevealed,
+		ClientSeedA:      m.ClientSeedA,
+		ClientSeedB:      m.ClientSeedB,
+		Nonce:            m.Nonce,
+		FairnessVersion:  m.FairnessVersion,
+		BeaconRound:      m.BeaconRound,
+		BeaconRandomness: m.BeaconRandomness,
 		CreatedAt:        m.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Receipt:          receipt.FromMatch(m),
 	}
 
 	if m.WinnerID != nil {
@@ -148,16 +245,24 @@ func matchToResponse(m models.Match, includeServerSeed bool) MatchResponse {
 		}
 	}
 
+	if m.Status == models.MatchStatusCompleted {
+		resp.Payout = resp.TotalPot - m.Rake
+	}
+
 	if m.FinishedAt != nil {
 		finishedStr := m.FinishedAt.Format("2006-01-02T15:04:05Z")
 		resp.FinishedAt = &finishedStr
 	}
 
-	// Only reveal server seed for completed matches
+	// Fight script is public once the match is completed
 	if includeServerSeed && m.Status == models.MatchStatusCompleted {
-		resp.ServerSeed = &m.ServerSeed
 		resp.FightScript = &m.FightScript
 	}
 
+	// Server seed is only revealed after the seed pair has been rotated
+	if includeServerSeed && m.Status == models.MatchStatusCompleted && m.SeedRevealed {
+		resp.ServerSeed = &m.ServerSeed
+	}
+
 	return resp
 }
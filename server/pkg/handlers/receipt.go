@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/hugolol/gamblefights/pkg/receipt"
+)
+
+// GetReceiptKeys publishes the ed25519 keys that sign settlement receipts
+// GET /.well-known/gamblefights-keys
+func GetReceiptKeys(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"keys": receipt.PublishedKeys(),
+	})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/seeds"
+)
+
+// ServerSeedResponse is the public view of a user's seed pair
+type ServerSeedResponse struct {
+	ServerSeedHashed     string `json:"serverSeedHashed"`
+	NextServerSeedHashed string `json:"nextServerSeedHashed"`
+	ClientSeed           string `json:"clientSeed"`
+	Nonce                int64  `json:"nonce"`
+}
+
+// RotateServerSeedResponse reveals the retired seed alongside the new pair
+type RotateServerSeedResponse struct {
+	RevealedServerSeed       string             `json:"revealedServerSeed"`
+	RevealedServerSeedHashed string             `json:"revealedServerSeedHashed"`
+	Current                  ServerSeedResponse `json:"current"`
+}
+
+// GetServerSeeds returns the hashes of the user's active and next server seeds
+// GET /api/user/server-seed
+func GetServerSeeds(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	userID, err := uuid.Parse(uid)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	pair, err := seeds.Get(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load server seeds"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	}
+
+	return c.JSON(http.StatusOK, ServerSeedResponse{
+		ServerSeedHashed:     pair.Active.SeedHashed,
+		NextServerSeedHashed: pair.Next.SeedHashed,
+		ClientSeed:           user.ClientSeed,
+		Nonce:                user.Nonce,
+	})
+}
+
+// RevealMatchSeedResponse reveals the seed a match drew from
+type RevealMatchSeedResponse struct {
+	RevealedServerSeed       string `json:"revealedServerSeed"`
+	RevealedServerSeedHashed string `json:"revealedServerSeedHashed"`
+}
+
+// RotateServerSeed reveals the active server seed and promotes the next one.
+// Matches draw from player A's pair, so this reveals the matches the user
+// played as player A; player B uses RevealMatchSeed.
+// POST /api/user/server-seed/rotate
+func RotateServerSeed(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	userID, err := uuid.Parse(uid)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	revealed, pair, err := seeds.Rotate(userID)
+	if errors.Is(err, seeds.ErrMatchInProgress) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Cannot rotate while a match is in progress"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to rotate server seed"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	}
+
+	return c.JSON(http.StatusOK, RotateServerSeedResponse{
+		RevealedServerSeed:       revealed.Seed,
+		RevealedServerSeedHashed: revealed.SeedHashed,
+		Current: ServerSeedResponse{
+			ServerSeedHashed:     pair.Active.SeedHashed,
+			NextServerSeedHashed: pair.Next.SeedHashed,
+			ClientSeed:           user.ClientSeed,
+			Nonce:                user.Nonce,
+		},
+	})
+}
+
+// RevealMatchSeed rotates the seed pair a finished match drew from, which is
+// player A's, so either player can verify the match without waiting on the
+// other to rotate
+// POST /api/matches/:id/reveal-seed
+func RevealMatchSeed(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	userID, err := uuid.Parse(uid)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	matchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid match ID"})
+	}
+
+	var match models.Match
+	if err := db.DB.First(&match, matchID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Match not found"})
+	}
+	if match.PlayerAID != userID && match.PlayerBID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Not a player in this match"})
+	}
+	if match.SeedRevealed {
+		return c.JSON(http.StatusOK, RevealMatchSeedResponse{
+			RevealedServerSeed:       match.ServerSeed,
+			RevealedServerSeedHashed: match.ServerSeedHashed,
+		})
+	}
+	if match.Status == models.MatchStatusInProgress {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Match is still in progress"})
+	}
+
+	// An unrevealed match always drew from player A's active seed: rotating
+	// it is what reveals the match
+	revealed, _, err := seeds.Rotate(match.PlayerAID)
+	if errors.Is(err, seeds.ErrMatchInProgress) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Cannot reveal while another match draws from this seed"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reveal server seed"})
+	}
+	if revealed.SeedHashed != match.ServerSeedHashed {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Match seed does not match the rotated seed"})
+	}
+
+	return c.JSON(http.StatusOK, RevealMatchSeedResponse{
+		RevealedServerSeed:       revealed.Seed,
+		RevealedServerSeedHashed: revealed.SeedHashed,
+	})
+}
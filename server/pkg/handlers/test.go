@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
-	"math/rand"
+	"log"
 	"net/http"
 	"time"
 
@@ -12,37 +12,11 @@ import (
 	"github.com/hugolol/gamblefights/pkg/db"
 	"github.com/hugolol/gamblefights/pkg/fairness"
 	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/receipt"
+	"github.com/hugolol/gamblefights/pkg/seeds"
+	"github.com/hugolol/gamblefights/pkg/seedvault"
 )
 
-// FightEvent for test
-type FightEvent struct {
-	Time   float64 `json:"time"`
-	Type   string  `json:"type"`
-	Actor  string  `json:"actor"`
-	Hit    bool    `json:"hit,omitempty"`
-	Crit   bool    `json:"crit,omitempty"`
-	Dodge  bool    `json:"dodge,omitempty"`
-	Damage int     `json:"damage,omitempty"`
-}
-
-// FightScript for test
-type FightScript struct {
-	MatchID  string       `json:"matchId"`
-	PlayerA  PlayerInfo   `json:"playerA"`
-	PlayerB  PlayerInfo   `json:"playerB"`
-	Winner   string       `json:"winner"`
-	Duration float64      `json:"duration"`
-	Events   []FightEvent `json:"events"`
-}
-
-// PlayerInfo for test
-type PlayerInfo struct {
-	ID        string `json:"id"`
-	Username  string `json:"username"`
-	Character string `json:"character"`
-	Skin      string `json:"skin"`
-}
-
 // TestFight simulates a complete fight for testing
 // POST /api/test/fight
 func TestFight(c echo.Context) error {
@@ -63,12 +37,27 @@ func TestFight(c echo.Context) error {
 	botID := uuid.New()
 	botUsername := "TestBot_" + botID.String()[:4]
 
-	// Generate server seed and calculate outcome
-	serverSeed, _ := fairness.GenerateServerSeed()
-	clientSeed := user.ClientSeed + "-bot"
-	nonce := user.Nonce
+	// Draw from the user's committed seed pair and calculate outcome
+	pair, err := seeds.Get(user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load server seed"})
+	}
+	nonce, err := seeds.ReserveNonce(user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reserve nonce"})
+	}
+	serverSeed, err := seedvault.Open(pair.Active.Seed)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load server seed"})
+	}
+	algo := fairness.Current()
+	input := fairness.Input{
+		ServerSeed: serverSeed,
+		ClientSeed: fairness.CombineClientSeeds(user.ClientSeed, "bot"),
+		Nonce:      nonce,
+	}
 
-	playerAWins, outcomeHash := fairness.CalculateOutcome(serverSeed, clientSeed, nonce)
+	playerAWins, outcomeHash := algo.Outcome(input)
 
 	// Determine winner
 	var winnerID uuid.UUID
@@ -81,32 +70,40 @@ func TestFight(c echo.Context) error {
 		winnerStr = "playerB"
 	}
 
-	// Generate fight script
-	fightScript := generateTestFightScript(user.Username, botUsername, winnerStr)
+	// Derive fight script from the same seeds
+	matchID := uuid.New()
+	playerA := fairness.PlayerInfo{ID: user.ID.String(), Username: user.Username, Character: "fighter", Skin: "default"}
+	playerB := fairness.PlayerInfo{ID: botID.String(), Username: botUsername, Character: "fighter", Skin: "default"}
+	fightScript := algo.FightScript(input, matchID.String(), playerA, playerB, winnerStr)
 	fightScriptJSON, _ := json.Marshal(fightScript)
 
 	// Create match record (no wager for test)
 	now := time.Now()
 	match := models.Match{
+		ID:               matchID,
 		PlayerAID:        user.ID,
 		PlayerBID:        user.ID, // Self-reference for test (bot doesn't exist in DB)
 		WagerAmount:      0,       // Free test fight
+		IsTest:           true,
 		Currency:         models.CurrencySOL,
-		ServerSeed:       serverSeed,
-		ServerSeedHashed: fairness.HashServerSeed(serverSeed),
+		ServerSeed:       pair.Active.Seed, // Stays sealed until rotation
+		ServerSeedHashed: pair.Active.SeedHashed,
 		ClientSeedA:      user.ClientSeed,
 		ClientSeedB:      "bot",
 		Nonce:            nonce,
+		FairnessVersion:  algo.Version,
 		WinnerID:         &winnerID,
 		Status:           models.MatchStatusCompleted,
 		FightScript:      string(fightScriptJSON),
 		FinishedAt:       &now,
 	}
 
-	db.DB.Create(&match)
+	signedReceipt, err := receipt.SignMatch(&match, 0)
+	if err != nil {
+		log.Printf("Failed to sign receipt for test match %s: %v", match.ID, err)
+	}
 
-	// Increment user nonce
-	db.DB.Model(&user).Update("nonce", user.Nonce+1)
+	db.DB.Create(&match)
 
 	// Update stats
 	if playerAWins {
@@ -125,142 +122,16 @@ func TestFight(c echo.Context) error {
 		"matchId":          match.ID.String(),
 		"winner":           winnerStr,
 		"winnerId":         winnerID.String(),
-		"serverSeed":       serverSeed,
 		"serverSeedHashed": match.ServerSeedHashed,
 		"clientSeedA":      user.ClientSeed,
 		"clientSeedB":      "bot",
 		"nonce":            nonce,
+		"fairnessVersion":  algo.Version,
 		"outcomeHash":      outcomeHash,
 		"fightScript":      fightScript,
 		"wagerAmount":      0,
 		"totalPot":         0,
+		"receipt":          signedReceipt,
 		"isTestFight":      true,
 	})
 }
-
-func generateTestFightScript(playerAName, playerBName, predeterminedWinner string) FightScript {
-	events := []FightEvent{}
-
-	// Track health for both players (99 HP like OSRS)
-	playerAHealth := 99
-	playerBHealth := 99
-
-	// Initial approach
-	events = append(events, FightEvent{Time: 0.2, Type: "move_fwd", Actor: "playerA"})
-	events = append(events, FightEvent{Time: 0.3, Type: "move_fwd", Actor: "playerB"})
-
-	currentTime := 0.5
-	attackTypes := []string{"attack_light", "attack_heavy", "attack_special"}
-	isPlayerATurn := true // Alternate turns, starting with Player A
-
-	// Continue until one player reaches 0 health
-	for playerAHealth > 0 && playerBHealth > 0 {
-		attacker := "playerA"
-		defender := "playerB"
-		if !isPlayerATurn {
-			attacker = "playerB"
-			defender = "playerA"
-		}
-
-		attackType := attackTypes[rand.Intn(len(attackTypes))]
-
-		// Bias hit chance towards predetermined winner
-		hitChance := float32(0.70) // Base 70% hit chance
-		if attacker == predeterminedWinner {
-			hitChance = 0.80 // Winner hits more often
-		} else {
-			hitChance = 0.60 // Loser hits less often
-		}
-
-		hit := rand.Float32() < hitChance
-		crit := hit && rand.Float32() > 0.85
-		dodge := !hit && rand.Float32() > 0.5
-
-		damage := 0
-		if hit {
-			baseDamage := 10 + rand.Intn(10) // 10-20 damage
-			if attacker == predeterminedWinner {
-				baseDamage += 3 // Winner does slightly more damage
-			}
-			damage = baseDamage
-			if crit {
-				damage = int(float64(damage) * 1.5)
-			}
-		}
-
-		// Apply damage to defender's health
-		if hit && damage > 0 {
-			if attacker == "playerA" {
-				playerBHealth -= damage
-				if playerBHealth < 0 {
-					playerBHealth = 0
-				}
-			} else {
-				playerAHealth -= damage
-				if playerAHealth < 0 {
-					playerAHealth = 0
-				}
-			}
-		}
-
-		events = append(events, FightEvent{
-			Time:   currentTime,
-			Type:   attackType,
-			Actor:  attacker,
-			Hit:    hit,
-			Crit:   crit,
-			Damage: damage,
-		})
-
-		reactionType := "react_hit"
-		if dodge {
-			reactionType = "react_dodge"
-		} else if !hit {
-			reactionType = "react_block"
-		}
-		events = append(events, FightEvent{
-			Time:  currentTime + 0.1,
-			Type:  reactionType,
-			Actor: defender,
-			Dodge: dodge,
-		})
-
-		// Alternate turns
-		isPlayerATurn = !isPlayerATurn
-		currentTime += 1.8 + rand.Float64()*0.6 // Slower pace like OSRS (about 2 seconds per attack)
-	}
-
-	// Determine actual winner based on health (should match predetermined due to bias)
-	actualWinner := "playerA"
-	loser := "playerB"
-	if playerAHealth <= 0 {
-		actualWinner = "playerB"
-		loser = "playerA"
-	}
-
-	// Finish move
-	events = append(events, FightEvent{Time: currentTime + 0.3, Type: "finish_move", Actor: actualWinner})
-	events = append(events, FightEvent{Time: currentTime + 0.8, Type: "ko", Actor: loser})
-	events = append(events, FightEvent{Time: currentTime + 1.3, Type: "victory", Actor: actualWinner})
-
-	duration := currentTime + 1.5
-
-	return FightScript{
-		MatchID: uuid.New().String(),
-		PlayerA: PlayerInfo{
-			ID:        uuid.New().String(),
-			Username:  playerAName,
-			Character: "fighter",
-			Skin:      "default",
-		},
-		PlayerB: PlayerInfo{
-			ID:        uuid.New().String(),
-			Username:  playerBName,
-			Character: "fighter",
-			Skin:      "default",
-		},
-		Winner:   actualWinner,
-		Duration: duration,
-		Events:   events,
-	}
-}
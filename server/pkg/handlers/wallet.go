@@ -1,13 +1,18 @@
 package handlers
 
 import (
-	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
+	"github.com/hugolol/gamblefights/pkg/chain/solana"
+	"github.com/hugolol/gamblefights/pkg/chain/ton"
+	"github.com/hugolol/gamblefights/pkg/currency"
 	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/idempotency"
+	"github.com/hugolol/gamblefights/pkg/ledger"
 	"github.com/hugolol/gamblefights/pkg/models"
 )
 
@@ -29,19 +34,39 @@ func GetBalances(c echo.Context) error {
 	}
 
 	var wallets []models.Wallet
-	if err := db.DB.Where("user_id = ?", userID).Find(&wallets).Error; err != nil {
+	if err := db.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&wallets).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch wallets"})
 	}
 
+	held := map[models.Currency]bool{}
+	for _, w := range wallets {
+		held[w.Currency] = true
+	}
+	created := false
+
 	// If no wallets exist, create a default SOL wallet with 1 SOL free for testing
 	if len(wallets) == 0 {
-		wallet := models.Wallet{
-			UserID:   userID,
-			Currency: models.CurrencySOL,
-			Balance:  1_000_000_000, // 1 SOL free for testing
+		if _, err := ledger.Deposit(ledger.SignupBonusKey(userID), userID, models.CurrencySOL, 1_000_000_000, models.AccountFaucet, ""); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create wallet"})
+		}
+		held[models.CurrencySOL] = true
+		created = true
+	}
+	// Every registered currency gets a wallet, empty until the user deposits
+	for _, info := range currency.All() {
+		if held[info.Code] {
+			continue
+		}
+		if err := ledger.OpenWallet(userID, info.Code); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create wallet"})
+		}
+		created = true
+	}
+
+	if created {
+		if err := db.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&wallets).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch wallets"})
 		}
-		db.DB.Create(&wallet)
-		wallets = append(wallets, wallet)
 	}
 
 	balances := make([]WalletBalance, len(wallets))
@@ -49,7 +74,7 @@ func GetBalances(c echo.Context) error {
 		balances[i] = WalletBalance{
 			Currency: string(w.Currency),
 			Balance:  w.Balance,
-			Display:  formatBalance(w.Balance, w.Currency),
+			Display:  currency.Display(w.Balance, w.Currency),
 		}
 	}
 
@@ -58,24 +83,9 @@ func GetBalances(c echo.Context) error {
 	})
 }
 
-// formatBalance converts atomic units to human readable format
-func formatBalance(amount int64, currency models.Currency) string {
-	switch currency {
-	case models.CurrencySOL:
-		// 1 SOL = 1,000,000,000 lamports
-		sol := float64(amount) / 1_000_000_000
-		return fmt.Sprintf("%.2f SOL", sol)
-	case models.CurrencyTON:
-		// 1 TON = 1,000,000,000 nanotons
-		ton := float64(amount) / 1_000_000_000
-		return fmt.Sprintf("%.2f TON", ton)
-	case models.CurrencyUSDT:
-		// 1 USDT = 1,000,000 (6 decimals)
-		usdt := float64(amount) / 1_000_000
-		return fmt.Sprintf("%.2f USDT", usdt)
-	default:
-		return "0"
-	}
+// DepositAddressRequest is the body of a deposit address request
+type DepositAddressRequest struct {
+	Currency string `json:"currency"` // Defaults to SOL
 }
 
 // GetDepositAddress returns a deposit address for the specified currency
@@ -88,20 +98,97 @@ func GetDepositAddress(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
 
+	var req DepositAddressRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
 	// Get the user's wallet address (for SOL, the deposit address is their wallet)
 	var user models.User
 	if err := db.DB.First(&user, userID).Error; err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 	}
 
-	// For MVP, we use a custodial model where users deposit to a master wallet
-	// In production, you'd generate unique deposit addresses per user
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"currency":       "SOL",
-		"depositAddress": "MASTER_WALLET_ADDRESS_PLACEHOLDER", // Replace with real master wallet
-		"memo":           user.ID.String(), // User ID as memo for tracking
-		"note":           "Send SOL to this address with your user ID as memo",
-	})
+	code := models.Currency(req.Currency)
+	if code == "" {
+		code = models.CurrencySOL
+	}
+	if !currency.Enabled(code, currency.Deposits) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Deposits are not available for this currency"})
+	}
+
+	switch code {
+	case models.CurrencySOL:
+		return solDepositAddress(c, user, nil)
+	case models.CurrencyUSDT:
+		tokens, _ := solana.Tokens()
+		for i := range tokens {
+			if tokens[i].Currency == models.CurrencyUSDT {
+				return solDepositAddress(c, user, &tokens[i])
+			}
+		}
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Deposits are not available"})
+	case models.CurrencyTON:
+		// Users share the hot wallet and the comment attributes deposits
+		address := ton.HotWallet()
+		if address == "" {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Deposits are not available"})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"currency":       "TON",
+			"depositAddress": address,
+			"comment":        user.ID.String(), // User ID as comment for tracking
+			"note":           "Send TON to this address with your user ID as comment",
+		})
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Deposits are not available for this currency"})
+	}
+}
+
+// solDepositAddress returns where to send SOL or, with token, an SPL token.
+// Tokens go to the same wallet address; wallets derive the associated token
+// account from it, which is returned for reference.
+func solDepositAddress(c echo.Context, user models.User, token *solana.Token) error {
+	currency, asset := "SOL", "SOL"
+	if token != nil {
+		currency, asset = string(token.Currency), string(token.Currency)+" (SPL)"
+	}
+
+	// Each user gets their own address derived from the deposit seed
+	address, memo := "", ""
+	if solana.DepositWallet() != nil {
+		var err error
+		address, err = solana.AssignDepositAddress(user.ID)
+		if err != nil {
+			log.Printf("Failed to assign deposit address for %s: %v", user.ID, err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to assign deposit address"})
+		}
+	} else {
+		// Otherwise users share the hot wallet and the memo attributes deposits
+		address, memo = solana.HotWallet(), user.ID.String()
+		if address == "" {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Deposits are not available"})
+		}
+	}
+
+	resp := map[string]interface{}{
+		"currency":       currency,
+		"depositAddress": address,
+		"note":           "Send " + asset + " to this address; it is yours alone, no memo needed",
+	}
+	if memo != "" {
+		resp["memo"] = memo // User ID as memo for tracking
+		resp["note"] = "Send " + asset + " to this address with your user ID as memo"
+	}
+	if token != nil {
+		owner, err := solana.ParsePublicKey(address)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Invalid deposit address"})
+		}
+		resp["mint"] = token.Mint.String()
+		resp["tokenAccount"] = token.AccountOf(owner).String()
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
 // AddTestBalance adds test balance for development (remove in production)
@@ -117,36 +204,19 @@ func AddTestBalance(c echo.Context) error {
 	// Add 1 SOL (in lamports) for testing
 	testAmount := int64(1_000_000_000) // 1 SOL
 
-	var wallet models.Wallet
-	result := db.DB.Where("user_id = ? AND currency = ?", userID, models.CurrencySOL).First(&wallet)
-
-	if result.Error != nil {
-		// Create wallet if doesn't exist
-		wallet = models.Wallet{
-			UserID:   userID,
-			Currency: models.CurrencySOL,
-			Balance:  testAmount,
-		}
-		db.DB.Create(&wallet)
-	} else {
-		// Update existing wallet
-		db.DB.Model(&wallet).Update("balance", wallet.Balance+testAmount)
+	// Credit from the faucet account (creates the wallet if needed)
+	if _, err := ledger.Deposit(idempotency.Key(c), userID, models.CurrencySOL, testAmount, models.AccountFaucet, ""); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Test deposit failed"})
 	}
 
-	// Create transaction record
-	tx := models.Transaction{
-		UserID:   userID,
-		WalletID: wallet.ID,
-		Type:     models.TxTypeDeposit,
-		Amount:   testAmount,
-		Currency: models.CurrencySOL,
-		Status:   models.TxStatusCompleted,
+	newBalance, err := ledger.Balance(userID, models.CurrencySOL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch balance"})
 	}
-	db.DB.Create(&tx)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message":    "Test deposit successful",
 		"amount":     testAmount,
-		"newBalance": wallet.Balance + testAmount,
+		"newBalance": newBalance,
 	})
 }
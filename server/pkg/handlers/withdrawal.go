@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/withdrawal"
+)
+
+// WithdrawRequest is the body of a withdrawal request
+type WithdrawRequest struct {
+	Currency    string `json:"currency"`
+	Amount      int64  `json:"amount"` // In atomic units
+	Destination string `json:"destination"`
+}
+
+// ReviewRequest is the body of an admin review decision
+type ReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// WithdrawalResponse is a withdrawal as shown to its owner and to admins
+type WithdrawalResponse struct {
+	ID            string  `json:"id"`
+	UserID        string  `json:"userId"`
+	Currency      string  `json:"currency"`
+	Amount        int64   `json:"amount"`
+	AmountDisplay string  `json:"amountDisplay"`
+	Destination   string  `json:"destination"`
+	Status        string  `json:"status"`
+	TxHash        string  `json:"txHash,omitempty"`
+	ReviewNote    string  `json:"reviewNote,omitempty"`
+	FailureReason string  `json:"failureReason,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+	FinishedAt    *string `json:"finishedAt,omitempty"`
+}
+
+func withdrawalToResponse(w models.Withdrawal) WithdrawalResponse {
+	resp := WithdrawalResponse{
+		ID:            w.ID.String(),
+		UserID:        w.UserID.String(),
+		Currency:      string(w.Currency),
+		Amount:        w.Amount,
+		AmountDisplay: currency.Display(w.Amount, w.Currency),
+		Destination:   w.Destination,
+		Status:        string(w.Status),
+		TxHash:        w.TxHash,
+		ReviewNote:    w.ReviewNote,
+		FailureReason: w.FailureReason,
+		CreatedAt:     w.CreatedAt.Format(time.RFC3339),
+	}
+	if w.FinishedAt != nil {
+		finished := w.FinishedAt.Format(time.RFC3339)
+		resp.FinishedAt = &finished
+	}
+	return resp
+}
+
+// Withdraw holds funds and queues a withdrawal to an external address
+// POST /api/wallet/withdraw
+func Withdraw(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	userID, err := uuid.Parse(uid)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	var req WithdrawRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.Amount <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Amount must be positive"})
+	}
+	if !currency.Enabled(models.Currency(req.Currency), currency.Withdrawals) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Withdrawals are not available for this currency"})
+	}
+
+	w, err := withdrawal.Request(userID, models.Currency(req.Currency), req.Amount, req.Destination)
+	switch {
+	case err == nil:
+	case errors.Is(err, withdrawal.ErrUnsupportedCurrency):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Withdrawals are not available for this currency"})
+	case errors.Is(err, withdrawal.ErrInvalidDestination):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid destination address"})
+	case errors.Is(err, withdrawal.ErrBelowMinimum):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Amount is below the minimum withdrawal"})
+	case errors.Is(err, ledger.ErrInsufficientFunds):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance"})
+	default:
+		log.Printf("Withdrawal request failed for %s: %v", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Withdrawal failed"})
+	}
+
+	return c.JSON(http.StatusAccepted, withdrawalToResponse(*w))
+}
+
+// GetWithdrawals returns the user's recent withdrawals
+// GET /api/wallet/withdrawals
+func GetWithdrawals(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	userID, err := uuid.Parse(uid)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	var withdrawals []models.Withdrawal
+	err = db.DB.Where("user_id = ?", userID).Order("created_at DESC").Limit(50).Find(&withdrawals).Error
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch withdrawals"})
+	}
+
+	response := make([]WithdrawalResponse, len(withdrawals))
+	for i, w := range withdrawals {
+		response[i] = withdrawalToResponse(w)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"withdrawals": response,
+	})
+}
+
+// GetAdminWithdrawals lists withdrawals, by default those awaiting review
+// GET /api/admin/withdrawals?status=PENDING_REVIEW
+func GetAdminWithdrawals(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = string(models.WithdrawalPendingReview)
+	}
+
+	var withdrawals []models.Withdrawal
+	err := db.DB.Where("status = ?", status).Order("created_at ASC").Limit(200).Find(&withdrawals).Error
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch withdrawals"})
+	}
+
+	response := make([]WithdrawalResponse, len(withdrawals))
+	for i, w := range withdrawals {
+		response[i] = withdrawalToResponse(w)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"withdrawals": response,
+	})
+}
+
+// ApproveWithdrawal releases a withdrawal from review
+// POST /api/admin/withdrawals/:id/approve
+func ApproveWithdrawal(c echo.Context) error {
+	return reviewWithdrawal(c, withdrawal.Approve)
+}
+
+// RejectWithdrawal fails a withdrawal under review and refunds the user
+// POST /api/admin/withdrawals/:id/reject
+func RejectWithdrawal(c echo.Context) error {
+	return reviewWithdrawal(c, withdrawal.Reject)
+}
+
+func reviewWithdrawal(c echo.Context, decide func(id, adminID uuid.UUID, note string) error) error {
+	adminID, err := uuid.Parse(c.Get("uid").(string))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid withdrawal ID"})
+	}
+
+	var req ReviewRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	switch err := decide(id, adminID, req.Note); {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Withdrawal not found"})
+	case errors.Is(err, withdrawal.ErrNotReviewable):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Withdrawal is not awaiting review"})
+	default:
+		log.Printf("Withdrawal review of %s failed: %v", id, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Review failed"})
+	}
+
+	var w models.Withdrawal
+	if err := db.DB.First(&w, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch withdrawal"})
+	}
+	return c.JSON(http.StatusOK, withdrawalToResponse(w))
+}
@@ -0,0 +1,154 @@
+// Package idempotency makes money-moving HTTP endpoints safe to retry.
+//
+// Clients send an Idempotency-Key header. The first request with a key runs
+// normally and its response is stored; any later request with the same key
+// gets the stored response replayed instead of running again.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+const (
+	// HeaderKey is the request header carrying the client's key
+	HeaderKey = "Idempotency-Key"
+
+	// HeaderReplayed is set on responses served from the cache
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	contextKey   = "idempotencyKey"
+)
+
+// Key returns a ledger idempotency key for the request, scoped to the user
+// so keys from different users never collide.
+func Key(c echo.Context) string {
+	key, _ := c.Get(contextKey).(string)
+	uid, _ := c.Get("uid").(string)
+	return "http:" + uid + ":" + key
+}
+
+// Middleware requires an Idempotency-Key header and replays the stored
+// response for a key that was already used. It must run after
+// auth.Middleware.
+func Middleware() echo.MiddlewareFunc {
+	return WithStore(DBStore{})
+}
+
+// WithStore is Middleware keeping its records in store.
+func WithStore(store Store) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderKey)
+			if key == "" || len(key) > maxKeyLength {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Idempotency-Key header required"})
+			}
+
+			userID, err := uuid.Parse(c.Get("uid").(string))
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read request"})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			record := models.IdempotencyRecord{
+				UserID:      userID,
+				Key:         key,
+				Method:      c.Request().Method,
+				Path:        c.Path(),
+				RequestHash: requestHash(c.Request().Method, c.Path(), body),
+			}
+
+			// Claim the key
+			if err := store.Claim(&record); err != nil {
+				if !errors.Is(err, ErrKeyUsed) {
+					return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record request"})
+				}
+				return replay(c, store, record)
+			}
+
+			// Unless the handler completes, release the key so the client can
+			// retry with it; this includes 5xx responses and panics
+			completed := false
+			defer func() {
+				if !completed {
+					store.Release(&record)
+				}
+			}()
+
+			c.Set(contextKey, key)
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			err = next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			if status == 0 || status >= http.StatusInternalServerError {
+				return nil
+			}
+
+			store.Complete(&record, status, recorder.body.String())
+			completed = true
+			return nil
+		}
+	}
+}
+
+// replay answers a request whose key was already claimed
+func replay(c echo.Context, store Store, attempt models.IdempotencyRecord) error {
+	stored, err := store.Load(attempt.UserID, attempt.Key)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load request"})
+	}
+
+	if stored.RequestHash != attempt.RequestHash {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Idempotency-Key was used for a different request"})
+	}
+	if stored.StatusCode == 0 {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "A request with this Idempotency-Key is still in progress"})
+	}
+
+	c.Response().Header().Set(HeaderReplayed, "true")
+	return c.Blob(stored.StatusCode, echo.MIMEApplicationJSONCharsetUTF8, []byte(stored.ResponseBody))
+}
+
+// requestHash fingerprints a request so a key cannot be reused for another
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder tees the response body for caching
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,215 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"gorm.io/gorm"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// memStore is an in-memory Store
+type memStore struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+}
+
+func newMemStore() *memStore {
+	return &memStore{records: map[string]models.IdempotencyRecord{}}
+}
+
+func recordID(userID uuid.UUID, key string) string { return userID.String() + ":" + key }
+
+func (s *memStore) Claim(record *models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := recordID(record.UserID, record.Key)
+	if _, ok := s.records[id]; ok {
+		return ErrKeyUsed
+	}
+	s.records[id] = *record
+	return nil
+}
+
+func (s *memStore) Load(userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[recordID(userID, key)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (s *memStore) Complete(record *models.IdempotencyRecord, status int, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.records[recordID(record.UserID, record.Key)]
+	stored.StatusCode = status
+	stored.ResponseBody = body
+	s.records[recordID(record.UserID, record.Key)] = stored
+	return nil
+}
+
+func (s *memStore) Release(record *models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, recordID(record.UserID, record.Key))
+	return nil
+}
+
+// newServer serves handler at POST /pay behind the middleware. The user is
+// taken from the X-User header in place of auth.Middleware.
+func newServer(store Store, handler echo.HandlerFunc) *echo.Echo {
+	e := echo.New()
+	e.Use(middleware.Recover())
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("uid", c.Request().Header.Get("X-User"))
+			return next(c)
+		}
+	})
+	e.POST("/pay", handler, WithStore(store))
+	return e
+}
+
+func send(e *echo.Echo, user uuid.UUID, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User", user.String())
+	req.Header.Set(HeaderKey, key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplaysCompletedResponse(t *testing.T) {
+	var calls int32
+	e := newServer(newMemStore(), func(c echo.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		return c.JSON(http.StatusCreated, map[string]int32{"call": n})
+	})
+	user := uuid.New()
+
+	first := send(e, user, "k1", `{"amount":1}`)
+	second := send(e, user, "k1", `{"amount":1}`)
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get(HeaderReplayed) != "true" {
+		t.Error("replayed response is not marked")
+	}
+	if first.Header().Get(HeaderReplayed) != "" {
+		t.Error("first response is marked as replayed")
+	}
+
+	if rec := send(e, user, "k1", `{"amount":2}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused for another body: got %d, want 422", rec.Code)
+	}
+}
+
+func TestKeysAreScopedToTheUser(t *testing.T) {
+	var calls int32
+	e := newServer(newMemStore(), func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.JSON(http.StatusOK, map[string]string{"key": Key(c)})
+	})
+	alice, bob := uuid.New(), uuid.New()
+
+	a := send(e, alice, "shared", `{}`)
+	b := send(e, bob, "shared", `{}`)
+
+	if calls != 2 {
+		t.Fatalf("handler ran %d times, want 2", calls)
+	}
+	if b.Header().Get(HeaderReplayed) != "" || a.Body.String() == b.Body.String() {
+		t.Errorf("bob got alice's response: %q", b.Body)
+	}
+}
+
+func TestReleasesKeyOnServerError(t *testing.T) {
+	var calls int32
+	e := newServer(newMemStore(), func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "boom"})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+	user := uuid.New()
+
+	if rec := send(e, user, "k1", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("first attempt = %d, want 500", rec.Code)
+	}
+	if rec := send(e, user, "k1", `{}`); rec.Code != http.StatusOK || rec.Header().Get(HeaderReplayed) != "" {
+		t.Errorf("retry = %d (replayed %q), want a fresh 200", rec.Code, rec.Header().Get(HeaderReplayed))
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
+
+func TestReleasesKeyOnPanic(t *testing.T) {
+	var calls int32
+	store := newMemStore()
+	e := newServer(store, func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("handler bug")
+		}
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+	user := uuid.New()
+
+	if rec := send(e, user, "k1", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking attempt = %d, want 500", rec.Code)
+	}
+	if len(store.records) != 0 {
+		t.Fatal("key still claimed after the handler panicked")
+	}
+	if rec := send(e, user, "k1", `{}`); rec.Code != http.StatusOK {
+		t.Errorf("retry = %d, want 200", rec.Code)
+	}
+}
+
+func TestConcurrentRequestsRunOnce(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	e := newServer(newMemStore(), func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-finish
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+	user := uuid.New()
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send(e, user, "k1", `{}`) }()
+	<-started
+
+	if rec := send(e, user, "k1", `{}`); rec.Code != http.StatusConflict {
+		t.Errorf("request during the first = %d, want 409", rec.Code)
+	}
+	close(finish)
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Errorf("first request = %d, want 200", rec.Code)
+	}
+
+	if rec := send(e, user, "k1", `{}`); rec.Code != http.StatusOK || rec.Header().Get(HeaderReplayed) != "true" {
+		t.Errorf("request after the first = %d (replayed %q), want a replayed 200", rec.Code, rec.Header().Get(HeaderReplayed))
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
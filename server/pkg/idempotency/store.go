@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// ErrKeyUsed is returned by Store.Claim when the user already used the key
+var ErrKeyUsed = errors.New("idempotency: key already used")
+
+// Store persists the requests made under each user's keys
+type Store interface {
+	// Claim records a request under its key, failing with ErrKeyUsed if
+	// the user already claimed the key
+	Claim(record *models.IdempotencyRecord) error
+	// Load returns the request the user made with key
+	Load(userID uuid.UUID, key string) (*models.IdempotencyRecord, error)
+	// Complete stores the response to replay for the claimed key
+	Complete(record *models.IdempotencyRecord, status int, body string) error
+	// Release drops a claim so the key can be used again
+	Release(record *models.IdempotencyRecord) error
+}
+
+// DBStore keeps idempotency records in the database
+type DBStore struct{}
+
+func (DBStore) Claim(record *models.IdempotencyRecord) error {
+	// The primary key rejects a second claim
+	err := db.DB.Create(record).Error
+	if uniqueViolation(err) {
+		return ErrKeyUsed
+	}
+	return err
+}
+
+func (DBStore) Load(userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	var stored models.IdempotencyRecord
+	if err := db.DB.Where("user_id = ? AND key = ?", userID, key).First(&stored).Error; err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (DBStore) Complete(record *models.IdempotencyRecord, status int, body string) error {
+	now := time.Now()
+	return db.DB.Model(record).Updates(map[string]interface{}{
+		"status_code":   status,
+		"response_body": body,
+		"completed_at":  &now,
+	}).Error
+}
+
+func (DBStore) Release(record *models.IdempotencyRecord) error {
+	return db.DB.Delete(record).Error
+}
+
+// uniqueViolation reports a duplicate key error
+func uniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
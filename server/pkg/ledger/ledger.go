@@ -0,0 +1,332 @@
+// Package ledger moves money between accounts as double-entry journal
+// entries. Every balance change is a posting; the postings of an entry sum
+// to zero, and the entry, its postings, the affected balances and the
+// user-facing Transaction rows are written in one serializable transaction.
+//
+// Every entry carries an idempotency key. Posting an entry whose key already
+// exists returns the original entry without moving funds again.
+//
+// User balances live on models.Wallet. System accounts (escrow, house,
+// external, faucet) live on models.LedgerAccount.
+package ledger
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// maxAttempts bounds retries after serialization failures
+const maxAttempts = 5
+
+var (
+	// ErrInsufficientFunds is returned when a debit would overdraw an account
+	ErrInsufficientFunds = errors.New("ledger: insufficient funds")
+
+	// ErrUnbalanced means the postings of an entry do not sum to zero
+	ErrUnbalanced = errors.New("ledger: entry does not balance")
+
+	// ErrNotPending means a leg referenced a transaction that is not pending
+	ErrNotPending = errors.New("ledger: transaction is not pending")
+
+	// ErrIdempotencyConflict means the key was already used for a different operation
+	ErrIdempotencyConflict = errors.New("ledger: idempotency key reused for a different operation")
+)
+
+// FundsError names the user whose wallet could not cover a debit
+type FundsError struct {
+	UserID uuid.UUID
+}
+
+func (e *FundsError) Error() string {
+	return fmt.Sprintf("ledger: insufficient funds for user %s", e.UserID)
+}
+
+func (e *FundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+// Leg is one side of an entry before it is written as a posting. It either
+// targets a user's wallet (UserID) or a system account (Account).
+type Leg struct {
+	UserID  *uuid.UUID
+	Account models.SystemAccount
+	Amount  int64
+	TxType  models.TransactionType // Transaction row type for wallet legs
+
+	// Pending completes this PENDING Transaction row instead of creating a
+	// new one, for funds that were announced before they moved.
+	Pending *uuid.UUID
+}
+
+// Entry is a balanced ledger operation in a single currency
+type Entry struct {
+	Key         string // Idempotency key, unique across all entries
+	Type        models.TransactionType
+	MatchID     *uuid.UUID
+	Currency    models.Currency
+	Description string
+	TxHash      string // On-chain hash recorded on wallet Transaction rows
+	Legs        []Leg
+}
+
+// Validate checks that an entry is well formed and balances.
+func (e Entry) Validate() error {
+	if e.Key == "" {
+		return fmt.Errorf("ledger: entry needs an idempotency key")
+	}
+	if _, ok := currency.Lookup(e.Currency); !ok {
+		return fmt.Errorf("ledger: unknown currency %q", e.Currency)
+	}
+	if len(e.Legs) < 2 {
+		return fmt.Errorf("%w: needs at least two legs", ErrUnbalanced)
+	}
+	var sum int64
+	for _, l := range e.Legs {
+		if (l.UserID == nil) == (l.Account == "") {
+			return fmt.Errorf("ledger: leg must target exactly one of a wallet or a system account")
+		}
+		if l.Amount == 0 {
+			return fmt.Errorf("ledger: zero amount leg")
+		}
+		sum += l.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("%w: postings sum to %d", ErrUnbalanced, sum)
+	}
+	return nil
+}
+
+// mayOverdraw reports whether a system account can go negative. Sources of
+// funds (the chain, the faucet) carry a negative balance by design.
+func mayOverdraw(account models.SystemAccount) bool {
+	return account == models.AccountExternal || account == models.AccountFaucet
+}
+
+// Post writes an entry. then, if set, runs inside the same transaction so
+// callers can update related rows atomically with the money movement.
+//
+// If an entry with the same key exists it is returned instead and then is
+// not run; a key reused for a different entry type is a conflict.
+func Post(e Entry, then func(tx *gorm.DB) error) (*models.JournalEntry, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	var journal *models.JournalEntry
+	err := serializable(func(tx *gorm.DB) error {
+		existing, err := replay(tx, e)
+		if err != nil || existing != nil {
+			journal = existing
+			return err
+		}
+
+		journal, err = write(tx, e)
+		if err != nil {
+			return err
+		}
+		if then != nil {
+			return then(tx)
+		}
+		return nil
+	})
+
+	// A concurrent post with the same key won the race
+	if uniqueViolation(err) {
+		existing, replayErr := replay(db.DB, e)
+		if existing != nil || replayErr != nil {
+			return existing, replayErr
+		}
+	}
+	return journal, err
+}
+
+// replay returns the existing entry for e's key, if any
+func replay(tx *gorm.DB, e Entry) (*models.JournalEntry, error) {
+	var existing models.JournalEntry
+	err := tx.Preload("Postings").Where("idempotency_key = ?", e.Key).Limit(1).Find(&existing).Error
+	if err != nil || existing.ID == uuid.Nil {
+		return nil, err
+	}
+	if existing.Type != e.Type {
+		return nil, fmt.Errorf("%w: %q is a %s entry", ErrIdempotencyConflict, e.Key, existing.Type)
+	}
+	return &existing, nil
+}
+
+// write applies an entry inside a transaction
+func write(tx *gorm.DB, e Entry) (*models.JournalEntry, error) {
+	journal := models.JournalEntry{
+		IdempotencyKey: e.Key,
+		Type:           e.Type,
+		MatchID:        e.MatchID,
+		Description:    e.Description,
+	}
+	if err := tx.Create(&journal).Error; err != nil {
+		return nil, err
+	}
+
+	// Lock wallets in a stable order so concurrent entries cannot deadlock
+	legs := append([]Leg(nil), e.Legs...)
+	sort.SliceStable(legs, func(i, j int) bool {
+		return legKey(legs[i]) < legKey(legs[j])
+	})
+
+	for _, l := range legs {
+		posting := models.Posting{
+			EntryID:  journal.ID,
+			Currency: e.Currency,
+			Amount:   l.Amount,
+		}
+
+		if l.UserID != nil {
+			wallet, err := lockWallet(tx, *l.UserID, e.Currency, l.Amount > 0)
+			if err != nil {
+				return nil, err
+			}
+			if wallet.Balance+l.Amount < 0 {
+				return nil, &FundsError{UserID: *l.UserID}
+			}
+			if err := tx.Model(wallet).Update("balance", gorm.Expr("balance + ?", l.Amount)).Error; err != nil {
+				return nil, err
+			}
+			posting.WalletID = &wallet.ID
+
+			if l.Pending != nil {
+				if err := completePending(tx, *l.Pending, wallet, journal.ID, l.Amount); err != nil {
+					return nil, err
+				}
+			} else if err := tx.Create(&models.Transaction{
+				UserID:   *l.UserID,
+				WalletID: wallet.ID,
+				MatchID:  e.MatchID,
+				EntryID:  &journal.ID,
+				Type:     l.TxType,
+				Amount:   l.Amount,
+				Currency: e.Currency,
+				TxHash:   e.TxHash,
+				Status:   models.TxStatusCompleted,
+			}).Error; err != nil {
+				return nil, err
+			}
+		} else {
+			if err := adjustAccount(tx, l.Account, e.Currency, l.Amount); err != nil {
+				return nil, err
+			}
+			posting.Account = l.Account
+		}
+
+		if err := tx.Create(&posting).Error; err != nil {
+			return nil, err
+		}
+		journal.Postings = append(journal.Postings, posting)
+	}
+
+	return &journal, nil
+}
+
+// completePending marks an announced transaction as completed by an entry
+func completePending(tx *gorm.DB, id uuid.UUID, wallet *models.Wallet, entryID uuid.UUID, amount int64) error {
+	res := tx.Model(&models.Transaction{}).
+		Where("id = ? AND wallet_id = ? AND amount = ? AND status = ?", id, wallet.ID, amount, models.TxStatusPending).
+		Updates(map[string]interface{}{
+			"status":   models.TxStatusCompleted,
+			"entry_id": entryID,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected != 1 {
+		return fmt.Errorf("%w: %s", ErrNotPending, id)
+	}
+	return nil
+}
+
+func legKey(l Leg) string {
+	if l.UserID != nil {
+		return "0:" + l.UserID.String()
+	}
+	return "1:" + string(l.Account)
+}
+
+// lockWallet selects a user's wallet FOR UPDATE, creating it for credits
+func lockWallet(tx *gorm.DB, userID uuid.UUID, currency models.Currency, create bool) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND currency = ?", userID, currency).
+		First(&wallet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !create {
+			return nil, &FundsError{UserID: userID}
+		}
+		wallet = models.Wallet{UserID: userID, Currency: currency}
+		err = tx.Create(&wallet).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// adjustAccount applies a posting to a system account balance
+func adjustAccount(tx *gorm.DB, account models.SystemAccount, currency models.Currency, amount int64) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "account"}, {Name: "currency"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"balance":    gorm.Expr("ledger_accounts.balance + ?", amount),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&models.LedgerAccount{Account: account, Currency: currency, Balance: amount}).Error
+	if err != nil || mayOverdraw(account) {
+		return err
+	}
+
+	var row models.LedgerAccount
+	if err := tx.First(&row, "account = ? AND currency = ?", account, currency).Error; err != nil {
+		return err
+	}
+	if row.Balance < 0 {
+		return fmt.Errorf("%w: %s %s would go negative", ErrInsufficientFunds, account, currency)
+	}
+	return nil
+}
+
+// serializable runs fn in a SERIALIZABLE transaction, retrying when
+// Postgres aborts it to preserve serializability.
+func serializable(fn func(tx *gorm.DB) error) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = db.DB.Transaction(fn, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		if !retryable(err) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt*attempt) * 10 * time.Millisecond)
+	}
+	return err
+}
+
+// retryable reports serialization failures and deadlocks
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
+// uniqueViolation reports a duplicate key error
+func uniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
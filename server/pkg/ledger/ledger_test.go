@@ -0,0 +1,96 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+func TestEntryValidate(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		noKey   bool
+		legs    []Leg
+		wantErr bool
+	}{
+		{
+			name: "bets into escrow",
+			legs: []Leg{
+				{UserID: &alice, Amount: -100},
+				{UserID: &bob, Amount: -100},
+				{Account: models.AccountEscrow, Amount: 200},
+			},
+		},
+		{
+			name: "settlement with house cut",
+			legs: []Leg{
+				{Account: models.AccountEscrow, Amount: -200},
+				{UserID: &alice, Amount: 190},
+				{Account: models.AccountHouse, Amount: 10},
+			},
+		},
+		{
+			name:    "missing idempotency key",
+			noKey:   true,
+			legs:    []Leg{{UserID: &alice, Amount: -100}, {Account: models.AccountEscrow, Amount: 100}},
+			wantErr: true,
+		},
+		{
+			name:    "unbalanced",
+			legs:    []Leg{{UserID: &alice, Amount: -100}, {Account: models.AccountEscrow, Amount: 99}},
+			wantErr: true,
+		},
+		{
+			name:    "single leg",
+			legs:    []Leg{{Account: models.AccountEscrow, Amount: 0}},
+			wantErr: true,
+		},
+		{
+			name:    "zero leg",
+			legs:    []Leg{{UserID: &alice, Amount: 0}, {Account: models.AccountEscrow, Amount: 0}},
+			wantErr: true,
+		},
+		{
+			name:    "leg with both targets",
+			legs:    []Leg{{UserID: &alice, Account: models.AccountHouse, Amount: -5}, {Account: models.AccountEscrow, Amount: 5}},
+			wantErr: true,
+		},
+		{
+			name:    "leg with no target",
+			legs:    []Leg{{Amount: -5}, {Account: models.AccountEscrow, Amount: 5}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		key := "test:" + tt.name
+		if tt.noKey {
+			key = ""
+		}
+		err := Entry{Key: key, Currency: models.CurrencySOL, Legs: tt.legs}.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	legs := []Leg{{UserID: &alice, Amount: -100}, {Account: models.AccountEscrow, Amount: 100}}
+	if err := (Entry{Key: "test:currency", Currency: "DOGE", Legs: legs}).Validate(); err == nil {
+		t.Error("Validate() accepted an unknown currency")
+	}
+}
+
+func TestFundsErrorUnwraps(t *testing.T) {
+	err := error(&FundsError{UserID: uuid.New()})
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Error("FundsError does not match ErrInsufficientFunds")
+	}
+	var fe *FundsError
+	if !errors.As(err, &fe) {
+		t.Error("errors.As failed for FundsError")
+	}
+}
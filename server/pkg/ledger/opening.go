@@ -0,0 +1,119 @@
+package ledger
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// OpeningKey is the idempotency key of a wallet's opening balance
+func OpeningKey(walletID uuid.UUID) string { return "opening:" + walletID.String() }
+
+// OpenLegacyBalances gives every wallet whose balance its postings do not
+// explain an opening entry from EXTERNAL for the difference. That covers
+// wallets funded before the ledger existed, including those holding only a
+// signup credit that never had a Transaction row. The entry gets an OPENING
+// Transaction row for whatever the wallet's completed transactions do not
+// explain, and takes over the legacy rows no entry accounts for, so its
+// rows sum to its posting. Wallet balances are not touched. A wallet that
+// already has an opening entry is skipped, so re-running is a no-op;
+// returns the number of wallets opened.
+func OpenLegacyBalances() (int, error) {
+	var walletIDs []uuid.UUID
+	err := db.DB.Model(&models.Wallet{}).
+		Where(`balance <> COALESCE((SELECT SUM(p.amount) FROM postings p
+			WHERE p.wallet_id = wallets.id), 0)`).
+		Pluck("id", &walletIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	opened := 0
+	for _, walletID := range walletIDs {
+		var done bool
+		if err := serializable(func(tx *gorm.DB) (err error) {
+			done, err = openWallet(tx, walletID)
+			return err
+		}); err != nil {
+			return opened, err
+		}
+		if done {
+			opened++
+		}
+	}
+	return opened, nil
+}
+
+// openWallet writes the opening entry of one wallet, reporting false if it
+// already has one or its postings now explain its balance
+func openWallet(tx *gorm.DB, walletID uuid.UUID) (bool, error) {
+	var existing models.JournalEntry
+	err := tx.Select("id").Where("idempotency_key = ?", OpeningKey(walletID)).First(&existing).Error
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "id = ?", walletID).Error; err != nil {
+		return false, err
+	}
+	var posted, recorded int64
+	if err := tx.Model(&models.Posting{}).Where("wallet_id = ?", walletID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&posted).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.Transaction{}).Where("wallet_id = ? AND status = ?", walletID, models.TxStatusCompleted).
+		Select("COALESCE(SUM(amount), 0)").Scan(&recorded).Error; err != nil {
+		return false, err
+	}
+	amount := wallet.Balance - posted
+	if amount == 0 {
+		return false, nil
+	}
+
+	journal := models.JournalEntry{
+		IdempotencyKey: OpeningKey(walletID),
+		Type:           models.TxTypeOpening,
+		Description:    "Opening balance from before the ledger",
+	}
+	if err := tx.Create(&journal).Error; err != nil {
+		return false, err
+	}
+	postings := []models.Posting{
+		{EntryID: journal.ID, WalletID: &wallet.ID, Currency: wallet.Currency, Amount: amount},
+		{EntryID: journal.ID, Account: models.AccountExternal, Currency: wallet.Currency, Amount: -amount},
+	}
+	if err := tx.Create(&postings).Error; err != nil {
+		return false, err
+	}
+	if err := adjustAccount(tx, models.AccountExternal, wallet.Currency, -amount); err != nil {
+		return false, err
+	}
+
+	err = tx.Model(&models.Transaction{}).
+		Where("wallet_id = ? AND entry_id IS NULL AND status = ?", walletID, models.TxStatusCompleted).
+		Update("entry_id", journal.ID).Error
+	if err != nil {
+		return false, err
+	}
+	if unexplained := wallet.Balance - recorded; unexplained != 0 {
+		err = tx.Create(&models.Transaction{
+			UserID:   wallet.UserID,
+			WalletID: wallet.ID,
+			EntryID:  &journal.ID,
+			Type:     models.TxTypeOpening,
+			Amount:   unexplained,
+			Currency: wallet.Currency,
+			Status:   models.TxStatusCompleted,
+		}).Error
+	}
+	return err == nil, err
+}
@@ -0,0 +1,277 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// BetKey and ReleaseKey are the idempotency keys of a match's entries.
+// Settlement and refund share ReleaseKey, so escrow for a match can only
+// ever be released once, one way or the other.
+func BetKey(matchID uuid.UUID) string     { return "bet:" + matchID.String() }
+func ReleaseKey(matchID uuid.UUID) string { return "release:" + matchID.String() }
+
+// SignupBonusKey is the key of a user's one-off starting balance
+func SignupBonusKey(userID uuid.UUID) string { return "faucet:signup:" + userID.String() }
+
+// PlaceBets moves each player's wager into escrow as one entry: either
+// every wager is taken or none is. A *FundsError names the player who
+// could not cover it. The wager must be within the currency's limits.
+func PlaceBets(matchID uuid.UUID, cur models.Currency, amount int64, userIDs ...uuid.UUID) error {
+	if err := currency.CheckWager(cur, amount); err != nil {
+		return fmt.Errorf("ledger: %w", err)
+	}
+	entry := Entry{
+		Key:         BetKey(matchID),
+		Type:        models.TxTypeBet,
+		MatchID:     &matchID,
+		Currency:    cur,
+		Description: "Wagers for match " + matchID.String(),
+	}
+	for i := range userIDs {
+		entry.Legs = append(entry.Legs, Leg{UserID: &userIDs[i], Amount: -amount, TxType: models.TxTypeBet})
+	}
+	entry.Legs = append(entry.Legs, Leg{Account: models.AccountEscrow, Amount: amount * int64(len(userIDs))})
+
+	_, err := Post(entry, nil)
+	return err
+}
+
+// Settle releases the pot from escrow: payout to the winner and the rest,
+// if any, to the house. complete runs in the same transaction so the match
+// is marked settled atomically with the payout.
+func Settle(matchID uuid.UUID, currency models.Currency, winnerID uuid.UUID, pot, payout int64, complete func(tx *gorm.DB) error) error {
+	if payout < 0 || payout > pot {
+		return fmt.Errorf("ledger: payout %d outside pot %d", payout, pot)
+	}
+	entry := Entry{
+		Key:         ReleaseKey(matchID),
+		Type:        models.TxTypeWin,
+		MatchID:     &matchID,
+		Currency:    currency,
+		Description: "Settlement for match " + matchID.String(),
+		Legs: []Leg{
+			{Account: models.AccountEscrow, Amount: -pot},
+		},
+	}
+	if payout > 0 {
+		entry.Legs = append(entry.Legs, Leg{UserID: &winnerID, Amount: payout, TxType: models.TxTypeWin})
+	}
+	if pot > payout {
+		entry.Legs = append(entry.Legs, Leg{Account: models.AccountHouse, Amount: pot - payout})
+	}
+
+	_, err := Post(entry, complete)
+	return err
+}
+
+// Refund returns each player's wager held in escrow. cancel runs in the
+// same transaction.
+func Refund(matchID uuid.UUID, currency models.Currency, amount int64, userIDs []uuid.UUID, cancel func(tx *gorm.DB) error) error {
+	entry := Entry{
+		Key:         ReleaseKey(matchID),
+		Type:        models.TxTypeRefund,
+		MatchID:     &matchID,
+		Currency:    currency,
+		Description: "Refund for match " + matchID.String(),
+	}
+	for i := range userIDs {
+		entry.Legs = append(entry.Legs, Leg{UserID: &userIDs[i], Amount: amount, TxType: models.TxTypeRefund})
+	}
+	entry.Legs = append(entry.Legs, Leg{Account: models.AccountEscrow, Amount: -amount * int64(len(userIDs))})
+
+	_, err := Post(entry, cancel)
+	return err
+}
+
+// Deposit credits a user's wallet from a source account: AccountExternal for
+// on-chain deposits, AccountFaucet for free test balances. The wallet is
+// created if needed. Replaying a key returns the original transaction.
+func Deposit(key string, userID uuid.UUID, currency models.Currency, amount int64, source models.SystemAccount, txHash string) (*models.Transaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("ledger: deposit must be positive")
+	}
+	journal, err := Post(Entry{
+		Key:         key,
+		Type:        models.TxTypeDeposit,
+		Currency:    currency,
+		Description: fmt.Sprintf("Deposit from %s", source),
+		TxHash:      txHash,
+		Legs: []Leg{
+			{Account: source, Amount: -amount},
+			{UserID: &userID, Amount: amount, TxType: models.TxTypeDeposit},
+		},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var record models.Transaction
+	if err := db.DB.Where("entry_id = ?", journal.ID).First(&record).Error; err != nil {
+		return nil, err
+	}
+	if record.UserID != userID {
+		return nil, fmt.Errorf("%w: %q credited another user", ErrIdempotencyConflict, key)
+	}
+	return &record, nil
+}
+
+// DepositKey is the idempotency key of an on-chain deposit to a user. One
+// transaction can pay several users, e.g. an exchange batching withdrawals
+// to many deposit addresses, so the user is part of the key.
+func DepositKey(currency models.Currency, txHash string, userID uuid.UUID) string {
+	return "deposit:" + string(currency) + ":" + txHash + ":" + userID.String()
+}
+
+// AnnounceDeposit records an on-chain deposit that has been seen but not yet
+// confirmed, as a PENDING transaction that does not move funds. Announcing
+// the same hash to the same user again returns the existing transaction;
+// other users paid by the same hash get their own.
+func AnnounceDeposit(userID uuid.UUID, currency models.Currency, amount int64, txHash string) (*models.Transaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("ledger: deposit must be positive")
+	}
+	var record models.Transaction
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("tx_hash = ? AND type = ? AND currency = ? AND user_id = ?", txHash, models.TxTypeDeposit, currency, userID).
+			Limit(1).Find(&record).Error
+		if err != nil || record.ID != uuid.Nil {
+			return err
+		}
+
+		wallet, err := lockWallet(tx, userID, currency, true)
+		if err != nil {
+			return err
+		}
+		record = models.Transaction{
+			UserID:   userID,
+			WalletID: wallet.ID,
+			Type:     models.TxTypeDeposit,
+			Amount:   amount,
+			Currency: currency,
+			TxHash:   txHash,
+			Status:   models.TxStatusPending,
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ConfirmDeposit credits an announced deposit from AccountExternal and marks
+// its transaction COMPLETED.
+func ConfirmDeposit(pending *models.Transaction) error {
+	journal, err := Post(Entry{
+		Key:         DepositKey(pending.Currency, pending.TxHash, pending.UserID),
+		Type:        models.TxTypeDeposit,
+		Currency:    pending.Currency,
+		Description: "On-chain deposit " + pending.TxHash,
+		TxHash:      pending.TxHash,
+		Legs: []Leg{
+			{Account: models.AccountExternal, Amount: -pending.Amount},
+			{UserID: &pending.UserID, Amount: pending.Amount, TxType: models.TxTypeDeposit, Pending: &pending.ID},
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	for _, p := range journal.Postings {
+		if p.WalletID != nil && *p.WalletID != pending.WalletID {
+			return fmt.Errorf("%w: deposit %s credited another wallet", ErrIdempotencyConflict, pending.TxHash)
+		}
+	}
+	return nil
+}
+
+// FailDeposit marks an announced deposit that never confirmed as FAILED.
+func FailDeposit(pending *models.Transaction) error {
+	return db.DB.Model(&models.Transaction{}).
+		Where("id = ? AND status = ?", pending.ID, models.TxStatusPending).
+		Update("status", models.TxStatusFailed).Error
+}
+
+// HoldKey and PayoutKey are the idempotency keys of a withdrawal's entries.
+// Completion and refund share PayoutKey, so held funds are released once.
+func HoldKey(withdrawalID uuid.UUID) string   { return "withdraw:" + withdrawalID.String() }
+func PayoutKey(withdrawalID uuid.UUID) string { return "payout:" + withdrawalID.String() }
+
+// HoldWithdrawal moves a withdrawal's amount from the user's wallet into
+// AccountPayouts. create runs in the same transaction to record the
+// withdrawal, so funds are never held without one.
+func HoldWithdrawal(withdrawalID, userID uuid.UUID, currency models.Currency, amount int64, create func(tx *gorm.DB) error) error {
+	if amount <= 0 {
+		return fmt.Errorf("ledger: withdrawal must be positive")
+	}
+	_, err := Post(Entry{
+		Key:         HoldKey(withdrawalID),
+		Type:        models.TxTypeWithdrawal,
+		Currency:    currency,
+		Description: "Withdrawal " + withdrawalID.String(),
+		Legs: []Leg{
+			{UserID: &userID, Amount: -amount, TxType: models.TxTypeWithdrawal},
+			{Account: models.AccountPayouts, Amount: amount},
+		},
+	}, create)
+	return err
+}
+
+// CompleteWithdrawal releases held funds to AccountExternal once the payout
+// confirmed on chain. complete runs in the same transaction.
+func CompleteWithdrawal(w *models.Withdrawal, complete func(tx *gorm.DB) error) error {
+	_, err := Post(Entry{
+		Key:         PayoutKey(w.ID),
+		Type:        models.TxTypeWithdrawal,
+		Currency:    w.Currency,
+		Description: "Payout " + w.TxHash,
+		Legs: []Leg{
+			{Account: models.AccountPayouts, Amount: -w.Amount},
+			{Account: models.AccountExternal, Amount: w.Amount},
+		},
+	}, complete)
+	return err
+}
+
+// RefundWithdrawal returns held funds to the user. fail runs in the same
+// transaction.
+func RefundWithdrawal(w *models.Withdrawal, fail func(tx *gorm.DB) error) error {
+	_, err := Post(Entry{
+		Key:         PayoutKey(w.ID),
+		Type:        models.TxTypeRefund,
+		Currency:    w.Currency,
+		Description: "Refund of withdrawal " + w.ID.String(),
+		Legs: []Leg{
+			{Account: models.AccountPayouts, Amount: -w.Amount},
+			{UserID: &w.UserID, Amount: w.Amount, TxType: models.TxTypeRefund},
+		},
+	}, fail)
+	return err
+}
+
+// SetTxHash records an on-chain hash on the wallet transactions of an entry.
+func SetTxHash(tx *gorm.DB, key, txHash string) error {
+	return tx.Model(&models.Transaction{}).
+		Where("entry_id IN (?)", tx.Model(&models.JournalEntry{}).Select("id").Where("idempotency_key = ?", key)).
+		Update("tx_hash", txHash).Error
+}
+
+// OpenWallet creates an empty wallet for the user in currency unless they
+// already have one.
+func OpenWallet(userID uuid.UUID, currency models.Currency) error {
+	var wallet models.Wallet
+	return db.DB.Where(models.Wallet{UserID: userID, Currency: currency}).FirstOrCreate(&wallet).Error
+}
+
+// Balance returns a user's wallet balance, zero if they have no wallet.
+func Balance(userID uuid.UUID, currency models.Currency) (int64, error) {
+	var wallet models.Wallet
+	err := db.DB.Where("user_id = ? AND currency = ?", userID, currency).Limit(1).Find(&wallet).Error
+	return wallet.Balance, err
+}
@@ -0,0 +1,210 @@
+package merkle
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+const dayLayout = "2006-01-02"
+
+var (
+	// ErrNotCommitted is returned when the match's day has no published root yet
+	ErrNotCommitted = errors.New("merkle: day not committed yet")
+
+	// ErrTestFight is returned for test fights, which no root commits to
+	ErrTestFight = errors.New("merkle: test fights are not committed")
+
+	// ErrRootMismatch means the stored matches no longer hash to the published root
+	ErrRootMismatch = errors.New("merkle: match history does not match published root")
+)
+
+// Inclusion proves a single match is part of a published daily root
+type Inclusion struct {
+	MatchID  string      `json:"matchId"`
+	Day      string      `json:"day"`
+	LeafData string      `json:"leafData"` // Canonical JSON array that was hashed
+	LeafHash string      `json:"leafHash"`
+	Index    int         `json:"index"`
+	Proof    []ProofStep `json:"proof"`
+	Root     string      `json:"root"`
+}
+
+// LeafData is the canonical encoding of a match leaf:
+// [id, serverSeedHashed, clientSeedA, clientSeedB, nonce, winnerId]
+func LeafData(m models.Match) []byte {
+	winnerID := ""
+	if m.WinnerID != nil {
+		winnerID = m.WinnerID.String()
+	}
+	data, _ := json.Marshal([]interface{}{
+		m.ID.String(),
+		m.ServerSeedHashed,
+		m.ClientSeedA,
+		m.ClientSeedB,
+		m.Nonce,
+		winnerID,
+	})
+	return data
+}
+
+// DayOf returns the UTC day key for a timestamp
+func DayOf(t time.Time) string {
+	return t.UTC().Format(dayLayout)
+}
+
+// dayMatches loads every completed match of a UTC day in commitment order.
+// Test fights are not committed.
+func dayMatches(day string) ([]models.Match, error) {
+	start, err := time.Parse(dayLayout, day)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []models.Match
+	err = db.DB.
+		Where("status = ? AND finished_at >= ? AND finished_at < ? AND is_test = ?",
+			models.MatchStatusCompleted, start, start.Add(24*time.Hour), false).
+		Order("finished_at ASC, id ASC").
+		Find(&matches).Error
+	return matches, err
+}
+
+func leafHashes(matches []models.Match) []Hash {
+	leaves := make([]Hash, len(matches))
+	for i, m := range matches {
+		leaves[i] = LeafHash(LeafData(m))
+	}
+	return leaves
+}
+
+// Commit builds and stores the root for a finished UTC day. An existing
+// root is returned untouched; published roots are never rewritten.
+func Commit(day string) (*models.MerkleRoot, error) {
+	var existing models.MerkleRoot
+	err := db.DB.First(&existing, "day = ?", day).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	matches, err := dayMatches(day)
+	if err != nil {
+		return nil, err
+	}
+
+	root := models.MerkleRoot{
+		Day:       day,
+		Root:      Root(leafHashes(matches)).String(),
+		LeafCount: len(matches),
+	}
+	if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&root).Error; err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// Prove returns the inclusion proof of a match in its day's published root.
+func Prove(match models.Match) (*Inclusion, error) {
+	if match.IsTest {
+		return nil, ErrTestFight
+	}
+	if match.FinishedAt == nil {
+		return nil, ErrNotCommitted
+	}
+	day := DayOf(*match.FinishedAt)
+
+	var root models.MerkleRoot
+	if err := db.DB.First(&root, "day = ?", day).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotCommitted
+		}
+		return nil, err
+	}
+
+	matches, err := dayMatches(day)
+	if err != nil {
+		return nil, err
+	}
+	leaves := leafHashes(matches)
+	if Root(leaves).String() != root.Root {
+		return nil, ErrRootMismatch
+	}
+
+	for i, m := range matches {
+		if m.ID != match.ID {
+			continue
+		}
+		return &Inclusion{
+			MatchID:  m.ID.String(),
+			Day:      day,
+			LeafData: string(LeafData(m)),
+			LeafHash: leaves[i].String(),
+			Index:    i,
+			Proof:    Proof(leaves, i),
+			Root:     root.Root,
+		}, nil
+	}
+	return nil, ErrRootMismatch
+}
+
+// Run commits every finished day since the first match, then keeps
+// committing each day shortly after UTC midnight.
+func Run() {
+	for {
+		if err := commitPending(); err != nil {
+			log.Printf("Merkle commitment failed: %v", err)
+		}
+
+		now := time.Now().UTC()
+		nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		time.Sleep(nextMidnight.Sub(now) + time.Minute)
+	}
+}
+
+// commitPending commits all days before today that have no root yet
+func commitPending() error {
+	var first models.Match
+	err := db.DB.
+		Where("status = ? AND finished_at IS NOT NULL AND is_test = ?", models.MatchStatusCompleted, false).
+		Order("finished_at ASC").
+		First(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var committed []string
+	if err := db.DB.Model(&models.MerkleRoot{}).Pluck("day", &committed).Error; err != nil {
+		return err
+	}
+	done := make(map[string]bool, len(committed))
+	for _, day := range committed {
+		done[day] = true
+	}
+
+	today := DayOf(time.Now())
+	for d := first.FinishedAt.UTC(); DayOf(d) < today; d = d.Add(24 * time.Hour) {
+		day := DayOf(d)
+		if done[day] {
+			continue
+		}
+		root, err := Commit(day)
+		if err != nil {
+			return err
+		}
+		log.Printf("Committed Merkle root for %s: %s (%d matches)", day, root.Root, root.LeafCount)
+	}
+	return nil
+}
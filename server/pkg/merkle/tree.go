@@ -0,0 +1,113 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Domain separation prefixes keep leaves and inner nodes from colliding
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// Hash is a SHA-256 digest
+type Hash [sha256.Size]byte
+
+// String returns the hex encoding of the hash
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// ProofStep is one sibling on the path from a leaf to the root
+type ProofStep struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"` // "left" or "right" of the running hash
+}
+
+// LeafHash hashes raw leaf data: SHA256(0x00 || data).
+func LeafHash(data []byte) Hash {
+	return sha256.Sum256(append([]byte{leafPrefix}, data...))
+}
+
+// nodeHash hashes two children: SHA256(0x01 || left || right).
+func nodeHash(left, right Hash) Hash {
+	buf := make([]byte, 0, 1+2*sha256.Size)
+	buf = append(buf, nodePrefix)
+	buf = append(buf, left[:]...)
+	buf = append(buf, right[:]...)
+	return sha256.Sum256(buf)
+}
+
+// Root computes the Merkle root of the leaf hashes. An odd node at the end
+// of a level is promoted unchanged. The root of an empty tree is SHA256("").
+func Root(leaves []Hash) Hash {
+	if len(leaves) == 0 {
+		return sha256.Sum256(nil)
+	}
+
+	level := leaves
+	for len(level) > 1 {
+		next := make([]Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, nodeHash(level[i], level[i+1]))
+			}
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// Proof returns the sibling path for the leaf at index.
+func Proof(leaves []Hash, index int) []ProofStep {
+	steps := []ProofStep{}
+	level := leaves
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			position := "right"
+			if sibling < index {
+				position = "left"
+			}
+			steps = append(steps, ProofStep{Hash: level[sibling].String(), Position: position})
+		}
+
+		next := make([]Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, nodeHash(level[i], level[i+1]))
+			}
+		}
+		level = next
+		index /= 2
+	}
+	return steps
+}
+
+// VerifyProof checks that leaf is included under root via the given path.
+func VerifyProof(leaf Hash, steps []ProofStep, root Hash) bool {
+	current := leaf
+	for _, step := range steps {
+		raw, err := hex.DecodeString(step.Hash)
+		if err != nil || len(raw) != sha256.Size {
+			return false
+		}
+		var sibling Hash
+		copy(sibling[:], raw)
+
+		switch step.Position {
+		case "left":
+			current = nodeHash(sibling, current)
+		case "right":
+			current = nodeHash(current, sibling)
+		default:
+			return false
+		}
+	}
+	return current == root
+}
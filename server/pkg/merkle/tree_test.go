@@ -0,0 +1,38 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestProofRoundTrip(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		leaves := make([]Hash, size)
+		for i := range leaves {
+			leaves[i] = LeafHash([]byte(fmt.Sprintf("leaf-%d", i)))
+		}
+		root := Root(leaves)
+
+		for i := range leaves {
+			proof := Proof(leaves, i)
+			if !VerifyProof(leaves[i], proof, root) {
+				t.Fatalf("Proof failed for leaf %d of %d", i, size)
+			}
+		}
+
+		// A leaf that is not in the tree must not verify
+		if VerifyProof(LeafHash([]byte("intruder")), Proof(leaves, 0), root) {
+			t.Fatalf("Foreign leaf verified in tree of %d", size)
+		}
+	}
+}
+
+func TestRootChangesWithHistory(t *testing.T) {
+	leaves := []Hash{LeafHash([]byte("a")), LeafHash([]byte("b")), LeafHash([]byte("c"))}
+	root := Root(leaves)
+
+	leaves[1] = LeafHash([]byte("rewritten"))
+	if Root(leaves) == root {
+		t.Error("Root did not change after rewriting a leaf")
+	}
+}
@@ -30,8 +30,8 @@ type User struct {
 	Nonce      int64  `gorm:"default:0"` // Global nonce for user
 
 	// Stats
-	TotalWins   int64 `gorm:"default:0"`
-	TotalLosses int64 `gorm:"default:0"`
+	TotalWins    int64 `gorm:"default:0"`
+	TotalLosses  int64 `gorm:"default:0"`
 	TotalWagered int64 `gorm:"default:0"` // In lamports
 
 	// Relations
@@ -56,6 +56,7 @@ type Wallet struct {
 	Currency       Currency  `gorm:"type:varchar(10);not null"`
 	Balance        int64     `gorm:"not null;default:0"` // Lowest atomic unit (lamports for SOL)
 	DepositAddress string    `gorm:"type:varchar(255)"`
+	DepositIndex   *int64    `gorm:"uniqueIndex"` // HD derivation index of DepositAddress
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -78,21 +79,32 @@ type Match struct {
 	PlayerBID uuid.UUID `gorm:"type:uuid;not null;index"`
 
 	// Wager details
-	WagerAmount int64    `gorm:"not null"`          // In atomic units
+	WagerAmount int64    `gorm:"not null"` // In atomic units
 	Currency    Currency `gorm:"type:varchar(10);not null"`
+	Rake        int64    `gorm:"not null;default:0"`     // House cut of the pot, posted to HOUSE at settlement
+	IsTest      bool     `gorm:"not null;default:false"` // Free fight against a bot; never committed or audited
 
 	// Provably fair data
-	ServerSeed       string `gorm:"type:varchar(128);not null"`
-	ServerSeedHashed string `gorm:"type:varchar(128);not null"`
+	ServerSeed       string `gorm:"type:text;not null"` // Sealed by seedvault until revealed
+	ServerSeedHashed string `gorm:"type:varchar(128);not null;index"`
+	SeedRevealed     bool   `gorm:"not null;default:false"` // Set once the seed pair is rotated
 	ClientSeedA      string `gorm:"type:varchar(128)"`
 	ClientSeedB      string `gorm:"type:varchar(128)"`
 	Nonce            int64  `gorm:"not null;default:0"`
+	FairnessVersion  int    `gorm:"not null;default:1"` // Algorithm used to derive outcome and fight
+	BeaconRound      *int64 // Public beacon round mixed in, if any
+	BeaconRandomness string `gorm:"type:varchar(128)"`
 
 	// Result
 	WinnerID    *uuid.UUID  `gorm:"type:uuid;index"`
 	Status      MatchStatus `gorm:"type:varchar(20);default:'WAITING'"`
 	FightScript string      `gorm:"type:jsonb"` // JSON animation script
 
+	// Signed settlement receipt (exact signed bytes, so not jsonb)
+	Receipt          string `gorm:"type:text"`
+	ReceiptSignature string `gorm:"type:varchar(128)"`
+	ReceiptKeyID     string `gorm:"type:varchar(16)"`
+
 	// Timestamps
 	CreatedAt  time.Time
 	FinishedAt *time.Time
@@ -102,6 +114,38 @@ type Match struct {
 	PlayerB User `gorm:"foreignKey:PlayerBID"`
 }
 
+// Server Seed Status
+type SeedStatus string
+
+const (
+	SeedStatusActive   SeedStatus = "ACTIVE"   // Currently used for the user's matches
+	SeedStatusNext     SeedStatus = "NEXT"     // Pre-committed, promoted on rotation
+	SeedStatusRevealed SeedStatus = "REVEALED" // Retired and published
+)
+
+// ServerSeed is a server seed committed to a user ahead of time.
+// Only its hash is shown until the user rotates the pair.
+type ServerSeed struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Seed       string     `gorm:"type:text;not null"` // Sealed by seedvault until revealed
+	SeedHashed string     `gorm:"type:varchar(128);not null;uniqueIndex"`
+	Status     SeedStatus `gorm:"type:varchar(20);not null;index"`
+
+	CreatedAt  time.Time
+	RevealedAt *time.Time
+}
+
+// MerkleRoot is the daily commitment over every match completed on a UTC day.
+// Once published it is never rewritten.
+type MerkleRoot struct {
+	Day       string `gorm:"type:varchar(10);primaryKey"` // YYYY-MM-DD (UTC)
+	Root      string `gorm:"type:varchar(64);not null"`
+	LeafCount int    `gorm:"not null"`
+
+	CreatedAt time.Time
+}
+
 // Transaction Type
 type TransactionType string
 
@@ -111,6 +155,7 @@ const (
 	TxTypeBet        TransactionType = "BET"
 	TxTypeWin        TransactionType = "WIN"
 	TxTypeRefund     TransactionType = "REFUND"
+	TxTypeOpening    TransactionType = "OPENING" // Ledger entry for a balance from before the ledger
 )
 
 // Transaction Status
@@ -128,12 +173,161 @@ type Transaction struct {
 	UserID   uuid.UUID         `gorm:"type:uuid;not null;index"`
 	WalletID uuid.UUID         `gorm:"type:uuid;not null;index"`
 	MatchID  *uuid.UUID        `gorm:"type:uuid;index"` // Optional, for bet/win transactions
+	EntryID  *uuid.UUID        `gorm:"type:uuid;index"` // Ledger journal entry that moved the funds
 	Type     TransactionType   `gorm:"type:varchar(20);not null"`
 	Amount   int64             `gorm:"not null"` // Positive for credit, negative for debit
 	Currency Currency          `gorm:"type:varchar(10);not null"`
-	TxHash   string            `gorm:"type:varchar(128)"` // Blockchain tx hash
+	TxHash   string            `gorm:"type:varchar(128);index"` // Blockchain tx hash
 	Status   TransactionStatus `gorm:"type:varchar(20);default:'PENDING'"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// Withdrawal Status
+type WithdrawalStatus string
+
+const (
+	WithdrawalPending       WithdrawalStatus = "PENDING"        // Funds held, awaiting automatic approval
+	WithdrawalPendingReview WithdrawalStatus = "PENDING_REVIEW" // Above the review threshold, awaiting an admin
+	WithdrawalApproved      WithdrawalStatus = "APPROVED"       // Ready to be signed and sent
+	WithdrawalBroadcast     WithdrawalStatus = "BROADCAST"      // Signed and sent, awaiting confirmation
+	WithdrawalConfirmed     WithdrawalStatus = "CONFIRMED"      // Final; funds left the hot wallet
+	WithdrawalFailed        WithdrawalStatus = "FAILED"         // Final; held funds were refunded
+)
+
+// Withdrawal is a user's request to send funds to an external address. The
+// amount is held in AccountPayouts from the moment it is requested.
+type Withdrawal struct {
+	ID          uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      uuid.UUID        `gorm:"type:uuid;not null;index"`
+	Currency    Currency         `gorm:"type:varchar(10);not null"`
+	Amount      int64            `gorm:"not null"` // Atomic units sent to Destination
+	Destination string           `gorm:"type:varchar(128);not null"`
+	Status      WithdrawalStatus `gorm:"type:varchar(20);not null;index"`
+
+	// Set when signed, before sending, so a crash can never pay twice
+	TxHash string `gorm:"type:varchar(128);index"`
+	RawTx  string `gorm:"type:text"` // Base64 signed transaction, rebroadcast until it lands
+
+	ReviewedBy    *uuid.UUID `gorm:"type:uuid"`
+	ReviewNote    string     `gorm:"type:varchar(255)"`
+	FailureReason string     `gorm:"type:varchar(255)"`
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	BroadcastAt *time.Time
+	FinishedAt  *time.Time
+}
+
+// Treasury Proposal Kind
+type ProposalKind string
+
+const (
+	ProposalSweepToCold ProposalKind = "SWEEP_TO_COLD" // Hot wallet above its band
+	ProposalRefill      ProposalKind = "REFILL"        // Hot wallet below its band
+)
+
+// Treasury Proposal Status
+type ProposalStatus string
+
+const (
+	ProposalOpen      ProposalStatus = "OPEN"      // Awaiting an operator
+	ProposalExecuted  ProposalStatus = "EXECUTED"  // Funds were moved
+	ProposalDismissed ProposalStatus = "DISMISSED" // Declined by an operator
+	ProposalExpired   ProposalStatus = "EXPIRED"   // The balance returned to the band first
+)
+
+// TreasuryProposal asks an operator to move funds between the hot wallet
+// and cold storage. At most one is open per currency; it is updated as the
+// hot wallet balance changes.
+type TreasuryProposal struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Currency    Currency       `gorm:"type:varchar(10);not null;index"`
+	Kind        ProposalKind   `gorm:"type:varchar(20);not null"`
+	Status      ProposalStatus `gorm:"type:varchar(20);not null;index"`
+	Amount      int64          `gorm:"not null"` // Atomic units to move
+	FromAddress string         `gorm:"type:varchar(128)"`
+	ToAddress   string         `gorm:"type:varchar(128)"`
+	HotBalance  int64          `gorm:"not null"` // When last evaluated
+
+	ResolvedBy *uuid.UUID `gorm:"type:uuid"`
+	Note       string     `gorm:"type:varchar(255)"`
+
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ResolvedAt *time.Time
+}
+
+// System Account
+type SystemAccount string
+
+const (
+	AccountEscrow   SystemAccount = "ESCROW"   // Wagers held while a match is in progress
+	AccountHouse    SystemAccount = "HOUSE"    // House revenue
+	AccountExternal SystemAccount = "EXTERNAL" // Funds entering or leaving via the chain
+	AccountFaucet   SystemAccount = "FAUCET"   // Free test balances (dev only)
+	AccountPayouts  SystemAccount = "PAYOUTS"  // Withdrawals held until they confirm on chain
+)
+
+// LedgerAccount is the balance of a non-user account in one currency.
+// User balances live on Wallet.
+type LedgerAccount struct {
+	Account  SystemAccount `gorm:"type:varchar(20);primaryKey"`
+	Currency Currency      `gorm:"type:varchar(10);primaryKey"`
+	Balance  int64         `gorm:"not null;default:0"`
+
+	UpdatedAt time.Time
+}
+
+// JournalEntry groups the postings of one balanced ledger operation.
+type JournalEntry struct {
+	ID             uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	IdempotencyKey string          `gorm:"type:varchar(255);not null;uniqueIndex"` // Replays return this entry
+	Type           TransactionType `gorm:"type:varchar(20);not null;index"`
+	MatchID        *uuid.UUID      `gorm:"type:uuid;index"`
+	Description    string          `gorm:"type:varchar(255)"`
+
+	Postings []Posting `gorm:"foreignKey:EntryID"`
+
+	CreatedAt time.Time
+}
+
+// Posting moves Amount into (positive) or out of (negative) one account.
+// Exactly one of WalletID and Account is set. The postings of an entry sum
+// to zero per currency.
+type Posting struct {
+	ID       uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	EntryID  uuid.UUID     `gorm:"type:uuid;not null;index"`
+	WalletID *uuid.UUID    `gorm:"type:uuid;index"`
+	Account  SystemAccount `gorm:"type:varchar(20);index"`
+	Currency Currency      `gorm:"type:varchar(10);not null"`
+	Amount   int64         `gorm:"not null"`
+
+	CreatedAt time.Time
+}
+
+// IdempotencyRecord caches the response of a money-moving HTTP request so a
+// retry with the same Idempotency-Key replays it instead of running twice.
+type IdempotencyRecord struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Key          string    `gorm:"type:varchar(255);primaryKey"`
+	Method       string    `gorm:"type:varchar(10);not null"`
+	Path         string    `gorm:"type:varchar(255);not null"`
+	RequestHash  string    `gorm:"type:varchar(64);not null"` // SHA256 of method, path and body
+	StatusCode   int       `gorm:"not null;default:0"`        // 0 while the request is in flight
+	ResponseBody string    `gorm:"type:text"`
+
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// ChainCursor is the newest on-chain transaction a watcher has processed for
+// an address, so it resumes where it left off after a restart.
+type ChainCursor struct {
+	Chain     string `gorm:"type:varchar(20);primaryKey"`
+	Address   string `gorm:"type:varchar(128);primaryKey"`
+	Signature string `gorm:"type:varchar(128);not null"`
+
+	UpdatedAt time.Time
+}
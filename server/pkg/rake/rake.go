@@ -0,0 +1,161 @@
+// Package rake computes the house's cut of a match pot.
+//
+// Each currency has a schedule of wager tiers, each charging a rate in
+// basis points of the pot, and an optional cap per match. The rake is taken
+// at settlement: the winner is paid the pot minus the rake, which the
+// ledger posts to the HOUSE account.
+package rake
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// MaxBPS is a rate of 100%
+const MaxBPS = 10_000
+
+// Tier charges BPS basis points of the pot on matches wagering at least
+// MinWager per player
+type Tier struct {
+	MinWager int64
+	BPS      int64
+}
+
+// Schedule is the rake of one currency
+type Schedule struct {
+	Tiers []Tier // Ascending by MinWager
+	Cap   int64  // Most taken from one match, in atomic units; 0 for no cap
+}
+
+var (
+	schedules = map[models.Currency]Schedule{}
+	mu        sync.RWMutex
+)
+
+// BPS returns the rate charged on a match wagering wager per player: that
+// of the highest tier the wager reaches, 0 below every tier.
+func (s Schedule) BPS(wager int64) int64 {
+	var bps int64
+	for _, t := range s.Tiers {
+		if wager >= t.MinWager {
+			bps = t.BPS
+		}
+	}
+	return bps
+}
+
+// Amount returns the rake on pot for a match wagering wager per player,
+// rounded down in the players' favour and capped.
+func (s Schedule) Amount(wager, pot int64) int64 {
+	bps := s.BPS(wager)
+	// Split so pot*bps cannot overflow
+	amount := pot/MaxBPS*bps + pot%MaxBPS*bps/MaxBPS
+	if s.Cap > 0 && amount > s.Cap {
+		amount = s.Cap
+	}
+	return amount
+}
+
+// Set replaces the schedules. Currencies without one are not raked.
+func Set(s map[models.Currency]Schedule) {
+	mu.Lock()
+	schedules = s
+	mu.Unlock()
+}
+
+// For returns the schedule of currency.
+func For(currency models.Currency) Schedule {
+	mu.RLock()
+	defer mu.RUnlock()
+	return schedules[currency]
+}
+
+// Amount returns the rake on pot for a match in currency wagering wager per
+// player.
+func Amount(currency models.Currency, wager, pot int64) int64 {
+	return For(currency).Amount(wager, pot)
+}
+
+// LoadFromEnv reads schedules from RAKE_TIERS and RAKE_CAPS. Without
+// RAKE_TIERS nothing is raked.
+func LoadFromEnv() error {
+	s, err := Parse(os.Getenv("RAKE_TIERS"), os.Getenv("RAKE_CAPS"))
+	if err != nil {
+		return err
+	}
+	Set(s)
+	return nil
+}
+
+// Parse builds schedules from comma-separated CURRENCY:minWager:bps tiers,
+// e.g. "SOL:0:500,SOL:1000000000:300", and CURRENCY:cap caps, e.g.
+// "SOL:500000000", amounts in atomic units. Currencies must be in the
+// currency registry.
+func Parse(tiers, caps string) (map[models.Currency]Schedule, error) {
+	s := map[models.Currency]Schedule{}
+	for _, part := range currency.SplitList(tiers) {
+		fields := strings.Split(part, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("rake: tier %q is not CURRENCY:minWager:bps", part)
+		}
+		minWager, err1 := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+		bps, err2 := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+		if err1 != nil || err2 != nil || minWager < 0 || bps < 0 || bps > MaxBPS {
+			return nil, fmt.Errorf("rake: tier %q needs minWager >= 0 and 0 <= bps <= %d", part, MaxBPS)
+		}
+		code, err := lookup(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("rake: tier %q: %w", part, err)
+		}
+		schedule := s[code]
+		for _, t := range schedule.Tiers {
+			if t.MinWager == minWager {
+				return nil, fmt.Errorf("rake: duplicate tier %q", part)
+			}
+		}
+		schedule.Tiers = append(schedule.Tiers, Tier{MinWager: minWager, BPS: bps})
+		s[code] = schedule
+	}
+	for code, schedule := range s {
+		sort.Slice(schedule.Tiers, func(i, j int) bool { return schedule.Tiers[i].MinWager < schedule.Tiers[j].MinWager })
+		s[code] = schedule
+	}
+
+	for _, part := range currency.SplitList(caps) {
+		name, amount, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("rake: cap %q is not CURRENCY:cap", part)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("rake: invalid cap in %q", part)
+		}
+		code, err := lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("rake: cap %q: %w", part, err)
+		}
+		schedule, ok := s[code]
+		if !ok {
+			return nil, fmt.Errorf("rake: cap %q has no tiers", part)
+		}
+		schedule.Cap = n
+		s[code] = schedule
+	}
+	return s, nil
+}
+
+// lookup returns the registered currency named by s
+func lookup(s string) (models.Currency, error) {
+	info, ok := currency.Lookup(models.Currency(strings.ToUpper(strings.TrimSpace(s))))
+	if !ok {
+		return "", fmt.Errorf("%w %q", currency.ErrUnknown, strings.TrimSpace(s))
+	}
+	return info.Code, nil
+}
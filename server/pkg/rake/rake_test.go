@@ -0,0 +1,79 @@
+package rake
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse(" sol:1000000000:300, SOL:0:500 ,USDT:0:250", "SOL:50000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sol := s[models.CurrencySOL]
+	if len(sol.Tiers) != 2 || sol.Tiers[0].MinWager != 0 || sol.Tiers[1].BPS != 300 || sol.Cap != 50_000_000 {
+		t.Errorf("SOL schedule = %+v", sol)
+	}
+	if usdt := s[models.CurrencyUSDT]; len(usdt.Tiers) != 1 || usdt.Cap != 0 {
+		t.Errorf("USDT schedule = %+v", usdt)
+	}
+
+	for _, bad := range [][2]string{
+		{"SOL:0", ""},
+		{"SOL:0:10001", ""},
+		{"SOL:-1:100", ""},
+		{"SOL:0:abc", ""},
+		{"SOL:0:100,SOL:0:200", ""},
+		{"SOL:0:100", "SOL"},
+		{"SOL:0:100", "SOL:-5"},
+		{"SOL:0:100", "TON:5"},
+		{"DOGE:0:100", ""},
+		{"SOL:0:100", "DOGE:5"},
+	} {
+		if _, err := Parse(bad[0], bad[1]); err == nil {
+			t.Errorf("Parse(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
+
+func TestAmount(t *testing.T) {
+	s := Schedule{
+		Tiers: []Tier{{MinWager: 100, BPS: 500}, {MinWager: 1_000_000_000, BPS: 300}},
+		Cap:   40_000_000,
+	}
+
+	tests := []struct {
+		name       string
+		wager, pot int64
+		want       int64
+	}{
+		{"below every tier", 50, 100, 0},
+		{"first tier", 100_000_000, 200_000_000, 10_000_000},
+		{"rounds down", 333, 666, 33},
+		{"second tier", 1_000_000_000, 2_000_000_000, 40_000_000},
+		{"capped", 5_000_000_000, 10_000_000_000, 40_000_000},
+	}
+	for _, tt := range tests {
+		if got := s.Amount(tt.wager, tt.pot); got != tt.want {
+			t.Errorf("%s: Amount(%d, %d) = %d, want %d", tt.name, tt.wager, tt.pot, got, tt.want)
+		}
+	}
+
+	// No overflow on huge pots
+	full := Schedule{Tiers: []Tier{{BPS: MaxBPS}}}
+	if got := full.Amount(math.MaxInt64/2, math.MaxInt64); got != math.MaxInt64 {
+		t.Errorf("100%% of MaxInt64 = %d", got)
+	}
+
+	// Unconfigured currencies are not raked
+	defer Set(map[models.Currency]Schedule{})
+	Set(map[models.Currency]Schedule{models.CurrencySOL: s})
+	if Amount(models.CurrencyTON, 1_000_000_000, 2_000_000_000) != 0 {
+		t.Error("raked a currency without a schedule")
+	}
+	if Amount(models.CurrencySOL, 100_000_000, 200_000_000) != 10_000_000 {
+		t.Error("SOL not raked")
+	}
+}
@@ -0,0 +1,173 @@
+package receipt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// PublishedKey is one entry of /.well-known/gamblefights-keys
+type PublishedKey struct {
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	PublicKey string `json:"publicKey"` // Base58
+	Use       string `json:"use"`
+	Current   bool   `json:"current"`
+}
+
+var (
+	defaultSigner *Signer
+	retiredKeys   []ed25519.PublicKey
+	defaultMu     sync.RWMutex
+)
+
+// LoadFromEnv loads the signing key from RECEIPT_SIGNING_KEY_FILE (a Solana
+// CLI keypair JSON file) or RECEIPT_SIGNING_KEY (base58 secret key or seed).
+// Retired public keys listed in RECEIPT_RETIRED_KEYS stay published so old
+// receipts remain verifiable. Without a key an ephemeral one is generated.
+func LoadFromEnv() error {
+	key, err := keyFromEnv()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		log.Println("WARNING: RECEIPT_SIGNING_KEY not set, signing receipts with an ephemeral key")
+		if _, key, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return err
+		}
+	}
+
+	var retired []ed25519.PublicKey
+	for _, encoded := range strings.Split(os.Getenv("RECEIPT_RETIRED_KEYS"), ",") {
+		encoded = strings.TrimSpace(encoded)
+		if encoded == "" {
+			continue
+		}
+		pub, err := base58.Decode(encoded)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("receipt: invalid retired key %q", encoded)
+		}
+		retired = append(retired, pub)
+	}
+
+	defaultMu.Lock()
+	defaultSigner = NewSigner(key)
+	retiredKeys = retired
+	defaultMu.Unlock()
+	return nil
+}
+
+func keyFromEnv() (ed25519.PrivateKey, error) {
+	if path := os.Getenv("RECEIPT_SIGNING_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("receipt: reading key file: %w", err)
+		}
+		var numbers []int
+		if err := json.Unmarshal(data, &numbers); err != nil {
+			return nil, fmt.Errorf("receipt: key file must be a JSON keypair array: %w", err)
+		}
+		raw := make([]byte, len(numbers))
+		for i, n := range numbers {
+			raw[i] = byte(n)
+		}
+		return parseKey(raw)
+	}
+
+	if encoded := os.Getenv("RECEIPT_SIGNING_KEY"); encoded != "" {
+		raw, err := base58.Decode(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("receipt: RECEIPT_SIGNING_KEY is not base58: %w", err)
+		}
+		return parseKey(raw)
+	}
+	return nil, nil
+}
+
+// parseKey accepts a 64-byte secret key or a 32-byte seed
+func parseKey(raw []byte) (ed25519.PrivateKey, error) {
+	switch len(raw) {
+	case ed25519.PrivateKeySize:
+		key := ed25519.PrivateKey(raw)
+		// A Solana keypair is seed||publicKey; reject mismatched halves
+		if !key.Public().(ed25519.PublicKey).Equal(ed25519.NewKeyFromSeed(raw[:ed25519.SeedSize]).Public()) {
+			return nil, fmt.Errorf("receipt: secret key does not match its public key")
+		}
+		return key, nil
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	default:
+		return nil, fmt.Errorf("receipt: signing key must be 32 or 64 bytes, got %d", len(raw))
+	}
+}
+
+// Default returns the loaded signer, or nil before LoadFromEnv.
+func Default() *Signer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultSigner
+}
+
+// PublishedKeys lists the current and retired receipt verification keys.
+func PublishedKeys() []PublishedKey {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	keys := make([]PublishedKey, 0, len(retiredKeys)+1)
+	if defaultSigner != nil {
+		keys = append(keys, publishedKey(defaultSigner.PublicKey(), true))
+	}
+	for _, pub := range retiredKeys {
+		keys = append(keys, publishedKey(pub, false))
+	}
+	return keys
+}
+
+func publishedKey(pub ed25519.PublicKey, current bool) PublishedKey {
+	return PublishedKey{
+		KeyID:     KeyID(pub),
+		Algorithm: Algorithm,
+		PublicKey: base58.Encode(pub),
+		Use:       "match-receipts",
+		Current:   current,
+	}
+}
+
+// SignMatch signs the settlement receipt for a match with the default
+// signer and stores it on the match.
+func SignMatch(m *models.Match, payout int64) (*Signed, error) {
+	signer := Default()
+	if signer == nil {
+		return nil, fmt.Errorf("receipt: no signing key loaded")
+	}
+	signed, err := signer.Sign(ForMatch(*m, payout))
+	if err != nil {
+		return nil, err
+	}
+	m.Receipt = string(signed.Receipt)
+	m.ReceiptSignature = signed.Signature
+	m.ReceiptKeyID = signed.KeyID
+	return &signed, nil
+}
+
+// FromMatch returns the stored signed receipt, or nil if the match has none.
+func FromMatch(m models.Match) *Signed {
+	if m.Receipt == "" {
+		return nil
+	}
+	return &Signed{
+		Receipt:   json.RawMessage(m.Receipt),
+		Signature: m.ReceiptSignature,
+		KeyID:     m.ReceiptKeyID,
+		Algorithm: Algorithm,
+	}
+}
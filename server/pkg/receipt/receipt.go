@@ -0,0 +1,140 @@
+// Package receipt signs settlement receipts for completed matches.
+//
+// A receipt is a canonical JSON document covering everything a player would
+// need to dispute a settlement off-platform. It is signed with the server's
+// ed25519 key; the public keys are published at /.well-known/gamblefights-keys.
+// Verifying a receipt is ed25519.Verify(publicKey, receipt bytes, signature)
+// over the exact bytes stored, with no re-encoding.
+package receipt
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// Version is the receipt schema version
+const Version = 1
+
+// Algorithm is the signature scheme advertised alongside receipts
+const Algorithm = "Ed25519"
+
+// ErrBadSignature is returned when a receipt does not verify
+var ErrBadSignature = errors.New("receipt: signature does not verify")
+
+// Receipt is the signed settlement statement. Field order is part of the
+// canonical encoding; append new fields and bump Version.
+type Receipt struct {
+	Version          int    `json:"v"`
+	MatchID          string `json:"matchId"`
+	PlayerA          string `json:"playerA"`
+	PlayerB          string `json:"playerB"`
+	WagerAmount      int64  `json:"wagerAmount"` // Per player, atomic units
+	Currency         string `json:"currency"`
+	ServerSeedHashed string `json:"serverSeedHashed"`
+	ClientSeedA      string `json:"clientSeedA"`
+	ClientSeedB      string `json:"clientSeedB"`
+	Nonce            int64  `json:"nonce"`
+	FairnessVersion  int    `json:"fairnessVersion"`
+	BeaconRandomness string `json:"beaconRandomness,omitempty"`
+	Winner           string `json:"winner"`
+	Payout           int64  `json:"payout"` // Credited to the winner, atomic units
+	SettledAt        string `json:"settledAt"`
+}
+
+// Signed is a receipt together with its detached signature
+type Signed struct {
+	Receipt   json.RawMessage `json:"receipt"`   // Canonical bytes that were signed
+	Signature string          `json:"signature"` // Base58, like Solana signatures
+	KeyID     string          `json:"keyId"`
+	Algorithm string          `json:"alg"`
+}
+
+// ForMatch builds the receipt for a settled match.
+func ForMatch(m models.Match, payout int64) Receipt {
+	winner := ""
+	if m.WinnerID != nil {
+		winner = m.WinnerID.String()
+	}
+	settledAt := time.Now()
+	if m.FinishedAt != nil {
+		settledAt = *m.FinishedAt
+	}
+	return Receipt{
+		Version:          Version,
+		MatchID:          m.ID.String(),
+		PlayerA:          m.PlayerAID.String(),
+		PlayerB:          m.PlayerBID.String(),
+		WagerAmount:      m.WagerAmount,
+		Currency:         string(m.Currency),
+		ServerSeedHashed: m.ServerSeedHashed,
+		ClientSeedA:      m.ClientSeedA,
+		ClientSeedB:      m.ClientSeedB,
+		Nonce:            m.Nonce,
+		FairnessVersion:  m.FairnessVersion,
+		BeaconRandomness: m.BeaconRandomness,
+		Winner:           winner,
+		Payout:           payout,
+		SettledAt:        settledAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// Signer holds the server's receipt signing key
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner wraps an ed25519 private key.
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{key: key, keyID: KeyID(key.Public().(ed25519.PublicKey))}
+}
+
+// PublicKey returns the verification key for receipts from this signer.
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// KeyID identifies the signing key in receipts and the published key set.
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// Sign encodes the receipt canonically and signs it.
+func (s *Signer) Sign(r Receipt) (Signed, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return Signed{}, err
+	}
+	return Signed{
+		Receipt:   data,
+		Signature: base58.Encode(ed25519.Sign(s.key, data)),
+		KeyID:     s.keyID,
+		Algorithm: Algorithm,
+	}, nil
+}
+
+// Verify checks a signed receipt against a public key.
+func Verify(pub ed25519.PublicKey, signed Signed) error {
+	sig, err := base58.Decode(signed.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return ErrBadSignature
+	}
+	if !ed25519.Verify(pub, signed.Receipt, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// KeyID is the first 8 bytes of SHA256(publicKey), hex encoded.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
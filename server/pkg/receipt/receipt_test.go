@@ -0,0 +1,103 @@
+package receipt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+func testSigner() *Signer {
+	return NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize)))
+}
+
+func testMatch() models.Match {
+	winner := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	finished := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return models.Match{
+		ID:               uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		PlayerAID:        uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		PlayerBID:        winner,
+		WagerAmount:      100_000_000,
+		Currency:         models.CurrencySOL,
+		ServerSeedHashed: "abc",
+		ClientSeedA:      "a",
+		ClientSeedB:      "b",
+		Nonce:            4,
+		FairnessVersion:  1,
+		WinnerID:         &winner,
+		FinishedAt:       &finished,
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	s := testSigner()
+	signed, err := s.Sign(ForMatch(testMatch(), 200_000_000))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := Verify(s.PublicKey(), signed); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if signed.KeyID != KeyID(s.PublicKey()) || len(signed.KeyID) != 16 {
+		t.Errorf("KeyID = %q", signed.KeyID)
+	}
+
+	// Canonical bytes are stable
+	want := `{"v":1,"matchId":"00000000-0000-0000-0000-000000000001","playerA":"11111111-1111-1111-1111-111111111111","playerB":"22222222-2222-2222-2222-222222222222","wagerAmount":100000000,"currency":"SOL","serverSeedHashed":"abc","clientSeedA":"a","clientSeedB":"b","nonce":4,"fairnessVersion":1,"winner":"22222222-2222-2222-2222-222222222222","payout":200000000,"settledAt":"2026-01-02T03:04:05Z"}`
+	if string(signed.Receipt) != want {
+		t.Errorf("Receipt =\n%s\nwant\n%s", signed.Receipt, want)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	s := testSigner()
+	signed, _ := s.Sign(ForMatch(testMatch(), 200_000_000))
+
+	tampered := signed
+	tampered.Receipt = bytes.Replace(signed.Receipt, []byte("200000000"), []byte("900000000"), 1)
+	if err := Verify(s.PublicKey(), tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Verify(tampered) = %v, want ErrBadSignature", err)
+	}
+
+	other := NewSigner(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{8}, ed25519.SeedSize)))
+	if err := Verify(other.PublicKey(), signed); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Verify(wrong key) = %v, want ErrBadSignature", err)
+	}
+
+	// The stored form round-trips through JSON untouched
+	data, _ := json.Marshal(signed)
+	var decoded Signed
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(s.PublicKey(), decoded); err != nil {
+		t.Errorf("Verify after round trip: %v", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	full := ed25519.NewKeyFromSeed(seed)
+
+	for _, raw := range [][]byte{seed, full} {
+		key, err := parseKey(raw)
+		if err != nil || !key.Equal(full) {
+			t.Errorf("parseKey(%d bytes) = %v", len(raw), err)
+		}
+	}
+
+	mismatched := append(append([]byte{}, seed...), bytes.Repeat([]byte{1}, ed25519.PublicKeySize)...)
+	if _, err := parseKey(mismatched); err == nil {
+		t.Error("parseKey accepted a keypair with the wrong public half")
+	}
+	if _, err := parseKey([]byte{1, 2, 3}); err == nil {
+		t.Error("parseKey accepted a short key")
+	}
+}
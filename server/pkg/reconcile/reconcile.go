@@ -0,0 +1,320 @@
+// Package reconcile periodically checks that balances agree with the
+// records that are supposed to explain them.
+//
+// A wallet's balance must equal the sum of its completed transactions and
+// the sum of its ledger postings; a system account's balance must equal the
+// sum of its postings; every journal entry must sum to zero; and for each
+// currency, user balances plus the house and escrow accounts must equal net
+// deposits minus withdrawals.
+package reconcile
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// Report is the result of one reconciliation pass
+type Report struct {
+	GeneratedAt       time.Time       `json:"generatedAt"`
+	WalletsChecked    int             `json:"walletsChecked"`
+	AccountsChecked   int             `json:"accountsChecked"`
+	Healthy           bool            `json:"healthy"`
+	WalletDrift       []WalletDrift   `json:"walletDrift"`
+	AccountDrift      []AccountDrift  `json:"accountDrift"`
+	UnbalancedEntries []EntryDrift    `json:"unbalancedEntries"`
+	Totals            []CurrencyTotal `json:"totals"`
+}
+
+// WalletDrift is a wallet whose balance disagrees with its history
+type WalletDrift struct {
+	WalletID       uuid.UUID       `json:"walletId"`
+	UserID         uuid.UUID       `json:"userId"`
+	Currency       models.Currency `json:"currency"`
+	Balance        int64           `json:"balance"`
+	TransactionSum int64           `json:"transactionSum"`
+	PostingSum     int64           `json:"postingSum"`
+}
+
+// AccountDrift is a system account whose balance disagrees with its postings
+type AccountDrift struct {
+	Account    models.SystemAccount `json:"account"`
+	Currency   models.Currency      `json:"currency"`
+	Balance    int64                `json:"balance"`
+	PostingSum int64                `json:"postingSum"`
+}
+
+// EntryDrift is a journal entry whose postings do not sum to zero
+type EntryDrift struct {
+	EntryID uuid.UUID `json:"entryId"`
+	Sum     int64     `json:"sum"`
+}
+
+// CurrencyTotal checks that funds held match funds received for a currency.
+// Drift is Held minus NetDeposits and should be zero.
+type CurrencyTotal struct {
+	Currency     models.Currency `json:"currency"`
+	UserBalances int64           `json:"userBalances"`
+	House        int64           `json:"house"`
+	Escrow       int64           `json:"escrow"`
+	Payouts      int64           `json:"payouts"` // Withdrawals awaiting confirmation
+	Held         int64           `json:"held"`
+	Deposits     int64           `json:"deposits"`
+	Withdrawals  int64           `json:"withdrawals"`
+	NetDeposits  int64           `json:"netDeposits"`
+	Drift        int64           `json:"drift"`
+}
+
+// Snapshot is the aggregated data a report is computed from
+type Snapshot struct {
+	Wallets     []WalletSums
+	Accounts    []AccountSums
+	EntrySums   []EntryDrift              // Only entries with a non-zero sum
+	Deposits    map[models.Currency]int64 // Posted out of EXTERNAL and FAUCET
+	Withdrawals map[models.Currency]int64 // Posted into them, as positive amounts
+}
+
+// WalletSums is a wallet's balance next to the sums that should explain it
+type WalletSums struct {
+	WalletID       uuid.UUID
+	UserID         uuid.UUID
+	Currency       models.Currency
+	Balance        int64
+	TransactionSum int64
+	PostingSum     int64
+}
+
+// AccountSums is a system account's balance next to its posting sum
+type AccountSums struct {
+	Account    models.SystemAccount
+	Currency   models.Currency
+	Balance    int64
+	PostingSum int64
+}
+
+var (
+	latest   *Report
+	latestMu sync.RWMutex
+)
+
+// Latest returns the most recent report, or nil before the first run.
+func Latest() *Report {
+	latestMu.RLock()
+	defer latestMu.RUnlock()
+	return latest
+}
+
+// Run reconciles immediately and then on every interval.
+func Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := RunOnce(); err != nil {
+			log.Printf("Reconciliation failed: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// RunOnce generates a report, logs its findings and publishes it as Latest.
+func RunOnce() (*Report, error) {
+	report, err := Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	latestMu.Lock()
+	latest = report
+	latestMu.Unlock()
+
+	logReport(report)
+	return report, nil
+}
+
+// Generate loads the current sums and reconciles them.
+func Generate() (*Report, error) {
+	snap, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return Analyze(snap), nil
+}
+
+// load aggregates balances, transactions and postings in one read-only
+// repeatable-read transaction so the sums are consistent with each other.
+func load() (*Snapshot, error) {
+	snap := &Snapshot{
+		Deposits:    map[models.Currency]int64{},
+		Withdrawals: map[models.Currency]int64{},
+	}
+
+	tx := db.DB.Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	err := tx.Raw(`
+		SELECT w.id AS wallet_id, w.user_id, w.currency, w.balance,
+			COALESCE((SELECT SUM(t.amount) FROM transactions t
+				WHERE t.wallet_id = w.id AND t.status = ?), 0) AS transaction_sum,
+			COALESCE((SELECT SUM(p.amount) FROM postings p
+				WHERE p.wallet_id = w.id), 0) AS posting_sum
+		FROM wallets w`, models.TxStatusCompleted).
+		Scan(&snap.Wallets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Accounts with postings but no balance row show up with a zero balance
+	err = tx.Raw(`
+		SELECT k.account, k.currency,
+			COALESCE(a.balance, 0) AS balance,
+			COALESCE((SELECT SUM(p.amount) FROM postings p
+				WHERE p.account = k.account AND p.currency = k.currency), 0) AS posting_sum
+		FROM (
+			SELECT account, currency FROM ledger_accounts
+			UNION
+			SELECT DISTINCT account, currency FROM postings WHERE account IS NOT NULL
+		) k
+		LEFT JOIN ledger_accounts a ON a.account = k.account AND a.currency = k.currency`).
+		Scan(&snap.Accounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Raw(`
+		SELECT entry_id, SUM(amount) AS sum FROM postings
+		GROUP BY entry_id HAVING SUM(amount) <> 0`).
+		Scan(&snap.EntrySums).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Funds enter and leave only through the source accounts, so their
+	// postings are the deposits and withdrawals
+	var flows []struct {
+		Currency    models.Currency
+		Deposits    int64
+		Withdrawals int64
+	}
+	err = tx.Raw(`
+		SELECT currency,
+			COALESCE(SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END), 0) AS deposits,
+			COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) AS withdrawals
+		FROM postings WHERE account IN (?, ?)
+		GROUP BY currency`,
+		models.AccountExternal, models.AccountFaucet).
+		Scan(&flows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flows {
+		snap.Deposits[f.Currency] += f.Deposits
+		snap.Withdrawals[f.Currency] += f.Withdrawals
+	}
+
+	return snap, nil
+}
+
+// Analyze reconciles a snapshot.
+func Analyze(snap *Snapshot) *Report {
+	report := &Report{
+		GeneratedAt:       time.Now().UTC(),
+		WalletsChecked:    len(snap.Wallets),
+		AccountsChecked:   len(snap.Accounts),
+		WalletDrift:       []WalletDrift{},
+		AccountDrift:      []AccountDrift{},
+		UnbalancedEntries: append([]EntryDrift{}, snap.EntrySums...),
+		Totals:            []CurrencyTotal{},
+	}
+
+	totals := map[models.Currency]*CurrencyTotal{}
+	total := func(c models.Currency) *CurrencyTotal {
+		if totals[c] == nil {
+			totals[c] = &CurrencyTotal{Currency: c}
+		}
+		return totals[c]
+	}
+
+	for _, w := range snap.Wallets {
+		total(w.Currency).UserBalances += w.Balance
+		if w.Balance != w.TransactionSum || w.Balance != w.PostingSum {
+			report.WalletDrift = append(report.WalletDrift, WalletDrift(w))
+		}
+	}
+
+	for _, a := range snap.Accounts {
+		switch a.Account {
+		case models.AccountHouse:
+			total(a.Currency).House += a.Balance
+		case models.AccountEscrow:
+			total(a.Currency).Escrow += a.Balance
+		case models.AccountPayouts:
+			total(a.Currency).Payouts += a.Balance
+		}
+		if a.Balance != a.PostingSum {
+			report.AccountDrift = append(report.AccountDrift, AccountDrift(a))
+		}
+	}
+
+	for c, amount := range snap.Deposits {
+		total(c).Deposits += amount
+	}
+	for c, amount := range snap.Withdrawals {
+		total(c).Withdrawals += amount
+	}
+
+	driftFree := true
+	for _, t := range totals {
+		t.Held = t.UserBalances + t.House + t.Escrow + t.Payouts
+		t.NetDeposits = t.Deposits - t.Withdrawals
+		t.Drift = t.Held - t.NetDeposits
+		if t.Drift != 0 {
+			driftFree = false
+		}
+		report.Totals = append(report.Totals, *t)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool {
+		return report.Totals[i].Currency < report.Totals[j].Currency
+	})
+
+	report.Healthy = driftFree &&
+		len(report.WalletDrift) == 0 &&
+		len(report.AccountDrift) == 0 &&
+		len(report.UnbalancedEntries) == 0
+	return report
+}
+
+func logReport(r *Report) {
+	if r.Healthy {
+		log.Printf("Reconciliation: %d wallets, %d accounts, no drift", r.WalletsChecked, r.AccountsChecked)
+		return
+	}
+
+	for _, w := range r.WalletDrift {
+		log.Printf("Reconciliation: wallet %s (user %s, %s) balance %d, transactions %d, postings %d",
+			w.WalletID, w.UserID, w.Currency, w.Balance, w.TransactionSum, w.PostingSum)
+	}
+	for _, a := range r.AccountDrift {
+		log.Printf("Reconciliation: account %s %s balance %d, postings %d",
+			a.Account, a.Currency, a.Balance, a.PostingSum)
+	}
+	for _, e := range r.UnbalancedEntries {
+		log.Printf("Reconciliation: journal entry %s sums to %d", e.EntryID, e.Sum)
+	}
+	for _, t := range r.Totals {
+		if t.Drift != 0 {
+			log.Printf("Reconciliation: %s held %d but net deposits are %d (drift %d)",
+				t.Currency, t.Held, t.NetDeposits, t.Drift)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package reconcile
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+func TestAnalyzeHealthy(t *testing.T) {
+	// Two players deposited 1000 each, played one match for 100 with a 10 rake,
+	// and the winner withdrew 600, of which 200 is still awaiting confirmation.
+	snap := &Snapshot{
+		Wallets: []WalletSums{
+			{WalletID: uuid.New(), UserID: uuid.New(), Currency: models.CurrencySOL, Balance: 490, TransactionSum: 490, PostingSum: 490},
+			{WalletID: uuid.New(), UserID: uuid.New(), Currency: models.CurrencySOL, Balance: 900, TransactionSum: 900, PostingSum: 900},
+		},
+		Accounts: []AccountSums{
+			{Account: models.AccountHouse, Currency: models.CurrencySOL, Balance: 10, PostingSum: 10},
+			{Account: models.AccountEscrow, Currency: models.CurrencySOL, Balance: 0, PostingSum: 0},
+			{Account: models.AccountPayouts, Currency: models.CurrencySOL, Balance: 200, PostingSum: 200},
+			{Account: models.AccountExternal, Currency: models.CurrencySOL, Balance: -1600, PostingSum: -1600},
+		},
+		Deposits:    map[models.Currency]int64{models.CurrencySOL: 2000},
+		Withdrawals: map[models.Currency]int64{models.CurrencySOL: 400},
+	}
+
+	report := Analyze(snap)
+	if len(report.Totals) != 1 {
+		t.Fatalf("got %d currency totals, want 1", len(report.Totals))
+	}
+	total := report.Totals[0]
+	if total.Held != 1600 || total.NetDeposits != 1600 || total.Drift != 0 {
+		t.Errorf("totals = %+v, want held and net deposits of 1600", total)
+	}
+	if !report.Healthy {
+		t.Errorf("report unhealthy: %+v", report)
+	}
+}
+
+func TestAnalyzeDrift(t *testing.T) {
+	walletID := uuid.New()
+	entryID := uuid.New()
+	snap := &Snapshot{
+		Wallets: []WalletSums{
+			// Balance bumped without a transaction or posting
+			{WalletID: walletID, UserID: uuid.New(), Currency: models.CurrencySOL, Balance: 1500, TransactionSum: 1000, PostingSum: 1000},
+		},
+		Accounts: []AccountSums{
+			{Account: models.AccountHouse, Currency: models.CurrencySOL, Balance: 5, PostingSum: 0},
+		},
+		EntrySums:   []EntryDrift{{EntryID: entryID, Sum: 3}},
+		Deposits:    map[models.Currency]int64{models.CurrencySOL: 1000},
+		Withdrawals: map[models.Currency]int64{},
+	}
+
+	report := Analyze(snap)
+	if report.Healthy {
+		t.Fatal("report should be unhealthy")
+	}
+	if len(report.WalletDrift) != 1 || report.WalletDrift[0].WalletID != walletID {
+		t.Errorf("wallet drift = %+v", report.WalletDrift)
+	}
+	if len(report.AccountDrift) != 1 || report.AccountDrift[0].Account != models.AccountHouse {
+		t.Errorf("account drift = %+v", report.AccountDrift)
+	}
+	if len(report.UnbalancedEntries) != 1 || report.UnbalancedEntries[0].EntryID != entryID {
+		t.Errorf("unbalanced entries = %+v", report.UnbalancedEntries)
+	}
+	if report.Totals[0].Drift != 505 {
+		t.Errorf("currency drift = %d, want 505", report.Totals[0].Drift)
+	}
+}
@@ -0,0 +1,201 @@
+package seeds
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/fairness"
+	"github.com/hugolol/gamblefights/pkg/models"
+	"github.com/hugolol/gamblefights/pkg/seedvault"
+)
+
+// ErrMatchInProgress is returned when rotating a seed that a match in
+// progress is drawing from; revealing it would expose the outcome early.
+var ErrMatchInProgress = errors.New("seeds: a match in progress draws from the active seed")
+
+// Pair is a user's active server seed plus the pre-committed next one.
+// Unrevealed seeds are sealed with seedvault; use seedvault.Open to draw
+// from them.
+type Pair struct {
+	Active models.ServerSeed
+	Next   models.ServerSeed
+}
+
+// Get returns the user's seed pair, committing a new one on first use.
+func Get(userID uuid.UUID) (*Pair, error) {
+	return Draw(userID, nil)
+}
+
+// Draw returns the user's seed pair like Get, and calls record, if not nil,
+// in the same transaction under the user's seed lock. A match recorded
+// there cannot be passed by a rotation of the seed it draws from.
+func Draw(userID uuid.UUID, record func(tx *gorm.DB, pair *Pair) error) (*Pair, error) {
+	var pair *Pair
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, userID); err != nil {
+			return err
+		}
+		var err error
+		if pair, err = load(tx, userID); err != nil {
+			return err
+		}
+		if record != nil {
+			return record(tx, pair)
+		}
+		return nil
+	})
+	return pair, err
+}
+
+// Rotate reveals the active seed, promotes the next seed and commits a
+// fresh next seed. The revealed seed is decrypted and written back in the
+// clear, and matches played on it become verifiable. It fails with
+// ErrMatchInProgress while a match drawing from the active seed is unsettled.
+func Rotate(userID uuid.UUID) (revealed models.ServerSeed, pair *Pair, err error) {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, userID); err != nil {
+			return err
+		}
+
+		current, err := load(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var live int64
+		if err := tx.Model(&models.Match{}).
+			Where("server_seed_hashed = ? AND status = ?", current.Active.SeedHashed, models.MatchStatusInProgress).
+			Count(&live).Error; err != nil {
+			return err
+		}
+		if live > 0 {
+			return ErrMatchInProgress
+		}
+
+		plaintext, err := seedvault.Open(current.Active.Seed)
+		if err != nil {
+			return err
+		}
+		if fairness.HashServerSeed(plaintext) != current.Active.SeedHashed {
+			return fmt.Errorf("seeds: seed %d does not match its commitment", current.Active.ID)
+		}
+
+		now := time.Now()
+		revealed = current.Active
+		revealed.Seed = plaintext
+		revealed.Status = models.SeedStatusRevealed
+		revealed.RevealedAt = &now
+		if err := tx.Save(&revealed).Error; err != nil {
+			return err
+		}
+
+		active := current.Next
+		active.Status = models.SeedStatusActive
+		if err := tx.Save(&active).Error; err != nil {
+			return err
+		}
+
+		next, err := commit(tx, userID, models.SeedStatusNext)
+		if err != nil {
+			return err
+		}
+
+		// Publish the seed on every match that drew from it
+		if err := tx.Model(&models.Match{}).
+			Where("server_seed_hashed = ?", revealed.SeedHashed).
+			Updates(map[string]interface{}{
+				"server_seed":   plaintext,
+				"seed_revealed": true,
+			}).Error; err != nil {
+			return err
+		}
+
+		pair = &Pair{Active: active, Next: *next}
+		return nil
+	})
+	return revealed, pair, err
+}
+
+// lockUser serializes seed operations for a single user.
+func lockUser(tx *gorm.DB, userID uuid.UUID) error {
+	var user models.User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error
+}
+
+// load fetches the active and next seeds, committing any that are missing.
+func load(tx *gorm.DB, userID uuid.UUID) (*Pair, error) {
+	var seeds []models.ServerSeed
+	err := tx.Where("user_id = ? AND status IN ?", userID, []models.SeedStatus{
+		models.SeedStatusActive,
+		models.SeedStatusNext,
+	}).Find(&seeds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var active, next *models.ServerSeed
+	for i := range seeds {
+		switch seeds[i].Status {
+		case models.SeedStatusActive:
+			active = &seeds[i]
+		case models.SeedStatusNext:
+			next = &seeds[i]
+		}
+	}
+
+	if active == nil {
+		if active, err = commit(tx, userID, models.SeedStatusActive); err != nil {
+			return nil, err
+		}
+	}
+	if next == nil {
+		if next, err = commit(tx, userID, models.SeedStatusNext); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Pair{Active: *active, Next: *next}, nil
+}
+
+// commit generates a new server seed and stores it sealed, with its public hash.
+func commit(tx *gorm.DB, userID uuid.UUID, status models.SeedStatus) (*models.ServerSeed, error) {
+	serverSeed, err := fairness.GenerateServerSeed()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := seedvault.Seal(serverSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := models.ServerSeed{
+		UserID:     userID,
+		Seed:       sealed,
+		SeedHashed: fairness.HashServerSeed(serverSeed),
+		Status:     status,
+	}
+	if err := tx.Create(&seed).Error; err != nil {
+		return nil, err
+	}
+	return &seed, nil
+}
+
+// ReserveNonce advances the user's nonce and returns the value to use.
+// Each nonce is handed out once, so no two draws share seed and nonce.
+func ReserveNonce(userID uuid.UUID) (int64, error) {
+	user := models.User{ID: userID}
+	err := db.DB.Model(&user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "nonce"}}}).
+		Update("nonce", gorm.Expr("nonce + 1")).Error
+	if err != nil {
+		return 0, err
+	}
+	return user.Nonce - 1, nil
+}
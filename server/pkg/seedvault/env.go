@@ -0,0 +1,81 @@
+package seedvault
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	defaultKeyring *Keyring
+	defaultMu      sync.RWMutex
+)
+
+// LoadFromEnv loads the master keys from SEED_MASTER_KEY_FILE, or from
+// SEED_MASTER_KEYS when no file is configured. Returns false when neither
+// is set, in which case new seeds are stored unencrypted.
+func LoadFromEnv() (bool, error) {
+	spec := os.Getenv("SEED_MASTER_KEYS")
+	if path := os.Getenv("SEED_MASTER_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("seedvault: reading key file: %w", err)
+		}
+		spec = string(data)
+	}
+	if spec == "" {
+		return false, nil
+	}
+
+	keyring, err := ParseKeys(spec)
+	if err != nil {
+		return false, err
+	}
+	SetDefault(keyring)
+	return true, nil
+}
+
+// SetDefault replaces the keyring used by Seal, Open and Rewrap.
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defaultKeyring = k
+	defaultMu.Unlock()
+}
+
+// Default returns the loaded keyring, or nil if encryption is disabled.
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// Seal encrypts a seed with the default keyring. Without a keyring the
+// seed is returned as-is (development only).
+func Seal(plaintext string) (string, error) {
+	k := Default()
+	if k == nil {
+		return plaintext, nil
+	}
+	return k.Seal(plaintext)
+}
+
+// Open decrypts a stored seed with the default keyring.
+func Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	k := Default()
+	if k == nil {
+		return "", ErrNoKey
+	}
+	return k.Open(value)
+}
+
+// Rewrap re-wraps a stored seed under the default primary key.
+func Rewrap(value string) (string, bool, error) {
+	k := Default()
+	if k == nil {
+		return "", false, ErrNoKey
+	}
+	return k.Rewrap(value)
+}
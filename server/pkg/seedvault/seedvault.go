@@ -0,0 +1,236 @@
+// Package seedvault encrypts unrevealed server seeds at rest.
+//
+// Each seed is sealed with its own random data key (AES-256-GCM), and the
+// data key is wrapped by a master key. Stored values look like
+//
+//	enc:v1:<keyID>:<wrapped data key>:<sealed seed>
+//
+// Master keys are identified by an ID so they can be rotated: the primary
+// key wraps new data keys, older keys stay loaded to unwrap existing rows
+// until they are re-wrapped (see cmd/seedkeys).
+package seedvault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix starts every sealed value
+const Prefix = "enc:v1:"
+
+const keySize = 32
+
+// seedAAD binds sealed values to their purpose
+var seedAAD = []byte("gamblefights:server-seed")
+
+var (
+	// ErrNoKey is returned when a sealed value is read without a master key loaded
+	ErrNoKey = errors.New("seedvault: no master key loaded")
+
+	// ErrUnknownKey means the value was wrapped by a master key that is not loaded
+	ErrUnknownKey = errors.New("seedvault: unknown master key")
+
+	// ErrMalformed means the stored value is not a valid envelope
+	ErrMalformed = errors.New("seedvault: malformed envelope")
+)
+
+// Keyring holds the master keys. The primary key wraps new data keys.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring builds a keyring from 32-byte master keys.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("seedvault: primary key %q not in keyring", primary)
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("seedvault: invalid key id %q", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("seedvault: key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+	}
+	return &Keyring{primary: primary, keys: keys}, nil
+}
+
+// ParseKeys reads master keys from "id:base64key" entries separated by
+// commas or newlines. The first entry is the primary key. Blank lines and
+// lines starting with # are ignored.
+func ParseKeys(spec string) (*Keyring, error) {
+	keys := make(map[string][]byte)
+	primary := ""
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("seedvault: key entry must be id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("seedvault: key %q: %w", id, err)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("seedvault: duplicate key id %q", id)
+		}
+		keys[id] = key
+		if primary == "" {
+			primary = id
+		}
+	}
+	if primary == "" {
+		return nil, ErrNoKey
+	}
+	return NewKeyring(primary, keys)
+}
+
+// GenerateKey returns a new random master key, base64 encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Primary returns the ID of the key used to wrap new data keys.
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Seal encrypts a seed under a fresh data key wrapped by the primary key.
+func (k *Keyring) Seal(plaintext string) (string, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	sealed, err := seal(dek, []byte(plaintext), seedAAD)
+	if err != nil {
+		return "", err
+	}
+	return k.envelope(k.primary, dek, sealed)
+}
+
+// Open decrypts a sealed seed. Values without the envelope prefix are
+// returned unchanged: they were stored before encryption was enabled, or
+// they have already been revealed.
+func (k *Keyring) Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	dek, sealed, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dek, sealed, seedAAD)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap re-wraps the data key of a sealed value under the primary key,
+// leaving the sealed seed untouched. Plaintext values are sealed. Reports
+// whether the value changed.
+func (k *Keyring) Rewrap(value string) (string, bool, error) {
+	if !IsSealed(value) {
+		sealed, err := k.Seal(value)
+		return sealed, err == nil, err
+	}
+	keyID, _, _ := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if keyID == k.primary {
+		return value, false, nil
+	}
+	dek, sealed, err := k.unwrap(value)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err := k.envelope(k.primary, dek, sealed)
+	return rewrapped, err == nil, err
+}
+
+// envelope wraps the data key and assembles the stored value
+func (k *Keyring) envelope(keyID string, dek, sealed []byte) (string, error) {
+	wrapped, err := seal(k.keys[keyID], dek, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	return Prefix + keyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// unwrap splits an envelope and recovers its data key
+func (k *Keyring) unwrap(value string) (dek, sealed []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 {
+		return nil, nil, ErrMalformed
+	}
+	master, ok := k.keys[parts[0]]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w %q", ErrUnknownKey, parts[0])
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, ErrMalformed
+	}
+	if sealed, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return nil, nil, ErrMalformed
+	}
+	if dek, err = open(master, wrapped, []byte(parts[0])); err != nil {
+		return nil, nil, err
+	}
+	return dek, sealed, nil
+}
+
+// IsSealed reports whether a stored value is an encrypted envelope.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// seal encrypts with AES-256-GCM, prefixing the random nonce
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open reverses seal
+func open(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, fmt.Errorf("seedvault: decryption failed: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
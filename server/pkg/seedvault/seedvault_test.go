@@ -0,0 +1,133 @@
+package seedvault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, spec string) *Keyring {
+	t.Helper()
+	k, err := ParseKeys(spec)
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	return k
+}
+
+func entry(id string, fill byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, keySize))
+}
+
+func TestSealOpen(t *testing.T) {
+	k := testKeyring(t, entry("1", 0xAA))
+	seed := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	sealed, err := k.Seal(seed)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, seed) {
+		t.Fatalf("Seal did not encrypt: %s", sealed)
+	}
+
+	again, _ := k.Seal(seed)
+	if again == sealed {
+		t.Error("Sealing twice produced identical envelopes")
+	}
+
+	opened, err := k.Open(sealed)
+	if err != nil || opened != seed {
+		t.Fatalf("Open = %q, %v; want %q", opened, err, seed)
+	}
+
+	// Legacy plaintext passes through
+	if opened, err := k.Open(seed); err != nil || opened != seed {
+		t.Errorf("Open(plaintext) = %q, %v", opened, err)
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	k := testKeyring(t, entry("1", 0xAA))
+	sealed, _ := k.Seal("seed")
+
+	body := []byte(sealed)
+	i := strings.LastIndex(sealed, ":") + 5
+	if body[i] == 'A' {
+		body[i] = 'B'
+	} else {
+		body[i] = 'A'
+	}
+	if _, err := k.Open(string(body)); err == nil {
+		t.Error("Open accepted a tampered envelope")
+	}
+
+	other := testKeyring(t, entry("1", 0xBB))
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("Open succeeded with the wrong master key")
+	}
+
+	unknown := testKeyring(t, entry("2", 0xAA))
+	if _, err := unknown.Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open with missing key id = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestRewrapRotatesMasterKey(t *testing.T) {
+	old := testKeyring(t, entry("1", 0xAA))
+	sealed, _ := old.Seal("seed")
+
+	rotated := testKeyring(t, entry("2", 0xBB)+","+entry("1", 0xAA))
+	if rotated.Primary() != "2" {
+		t.Fatalf("Primary = %q, want 2", rotated.Primary())
+	}
+
+	rewrapped, changed, err := rotated.Rewrap(sealed)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v", changed, err)
+	}
+	if !strings.HasPrefix(rewrapped, Prefix+"2:") {
+		t.Errorf("Rewrap kept the old key id: %s", rewrapped)
+	}
+	// The sealed seed itself is untouched, only the data key is re-wrapped
+	if sealed[strings.LastIndex(sealed, ":"):] != rewrapped[strings.LastIndex(rewrapped, ":"):] {
+		t.Error("Rewrap re-encrypted the seed instead of the data key")
+	}
+
+	// The new key alone can open it
+	newOnly := testKeyring(t, entry("2", 0xBB))
+	if opened, err := newOnly.Open(rewrapped); err != nil || opened != "seed" {
+		t.Errorf("Open after rewrap = %q, %v", opened, err)
+	}
+
+	if _, changed, _ := rotated.Rewrap(rewrapped); changed {
+		t.Error("Rewrap changed a value already under the primary key")
+	}
+
+	// Plaintext is sealed
+	value, changed, err := rotated.Rewrap("legacy")
+	if err != nil || !changed || !IsSealed(value) {
+		t.Errorf("Rewrap(plaintext) = %q, %v, %v", value, changed, err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	k := testKeyring(t, "# master keys\n"+entry("b", 1)+"\n\n"+entry("a", 2)+"\n")
+	if k.Primary() != "b" || len(k.keys) != 2 {
+		t.Errorf("ParseKeys = primary %q, %d keys", k.Primary(), len(k.keys))
+	}
+
+	bad := []string{
+		"",
+		"nocolon",
+		"1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		entry("1", 1) + "," + entry("1", 2),
+	}
+	for _, spec := range bad {
+		if _, err := ParseKeys(spec); err == nil {
+			t.Errorf("ParseKeys(%q) succeeded", spec)
+		}
+	}
+}
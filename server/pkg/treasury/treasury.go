@@ -0,0 +1,362 @@
+// Package treasury keeps the hot wallet within a target band.
+//
+// Each pass reads the hot wallet balance of every registered currency and
+// subtracts the withdrawal queue (PENDING and APPROVED withdrawals, which
+// go out without further review). Above the band's maximum it proposes a
+// sweep of the excess to cold storage; below the minimum it requests a
+// refill, both back to the target. Proposals are carried out by an
+// operator, since only they hold the cold keys. When the hot wallet cannot
+// cover the queue at all, automatic withdrawals of that currency pause
+// until it can.
+package treasury
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hugolol/gamblefights/pkg/chain"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// ErrNotOpen means the proposal was already resolved
+var ErrNotOpen = errors.New("treasury: proposal is not open")
+
+// Band is the range the hot wallet balance, net of queued withdrawals,
+// should stay in. Amounts are atomic units.
+type Band struct {
+	Min    int64 `json:"min"`
+	Target int64 `json:"target"`
+	Max    int64 `json:"max"`
+}
+
+// Decision is what one evaluation of a hot wallet calls for
+type Decision struct {
+	Paused bool                `json:"paused"`           // The queue exceeds the balance
+	Kind   models.ProposalKind `json:"action,omitempty"` // Empty inside the band
+	Amount int64               `json:"amount,omitempty"` // To move to get back to the target
+}
+
+// Evaluate compares a hot wallet balance and its withdrawal queue against
+// band. Without a band only the pause is decided.
+func Evaluate(band *Band, balance, queued int64) Decision {
+	d := Decision{Paused: queued > balance}
+	if band == nil {
+		return d
+	}
+
+	available := balance - queued
+	switch {
+	case available > band.Max:
+		d.Kind, d.Amount = models.ProposalSweepToCold, available-band.Target
+	case available < band.Min:
+		d.Kind, d.Amount = models.ProposalRefill, band.Target-available
+	}
+	return d
+}
+
+// Report is the result of one treasury pass
+type Report struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Wallets     []WalletStatus `json:"wallets"`
+}
+
+// WalletStatus is one currency's hot wallet as of the last pass
+type WalletStatus struct {
+	Currency   models.Currency `json:"currency"`
+	HotWallet  string          `json:"hotWallet"`
+	ColdWallet string          `json:"coldWallet,omitempty"`
+	Balance    int64           `json:"balance"`
+	Queued     int64           `json:"queued"`   // PENDING and APPROVED withdrawals
+	InReview   int64           `json:"inReview"` // PENDING_REVIEW withdrawals, not yet owed
+	Available  int64           `json:"available"`
+	Band       *Band           `json:"band,omitempty"`
+	Decision
+	Error string `json:"error,omitempty"`
+}
+
+type wallet struct {
+	hot  chain.HotWallet
+	cold string
+}
+
+var (
+	wallets = map[models.Currency]wallet{}
+	bands   = map[models.Currency]Band{}
+	paused  = map[models.Currency]bool{}
+	latest  *Report
+	mu      sync.RWMutex
+)
+
+// Register tracks currency's hot wallet, with cold as the address sweeps
+// are proposed to and refills come from.
+func Register(currency models.Currency, hot chain.HotWallet, cold string) {
+	mu.Lock()
+	wallets[currency] = wallet{hot: hot, cold: cold}
+	mu.Unlock()
+}
+
+// SetBands replaces the target bands. Currencies without one are only
+// checked against their queue.
+func SetBands(b map[models.Currency]Band) {
+	mu.Lock()
+	bands = b
+	mu.Unlock()
+}
+
+// LoadFromEnv reads target bands from TREASURY_BANDS.
+func LoadFromEnv() error {
+	b, err := ParseBands(os.Getenv("TREASURY_BANDS"))
+	if err != nil {
+		return err
+	}
+	SetBands(b)
+	return nil
+}
+
+// ParseBands parses comma-separated CURRENCY:min:target:max entries,
+// amounts in atomic units, e.g. "SOL:5000000000:20000000000:50000000000".
+func ParseBands(s string) (map[models.Currency]Band, error) {
+	b := map[models.Currency]Band{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("treasury: band %q is not CURRENCY:min:target:max", part)
+		}
+		var amounts [3]int64
+		for i, f := range fields[1:] {
+			n, err := strconv.ParseInt(strings.TrimSpace(f), 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("treasury: invalid amount in band %q", part)
+			}
+			amounts[i] = n
+		}
+		band := Band{Min: amounts[0], Target: amounts[1], Max: amounts[2]}
+		if band.Min > band.Target || band.Target > band.Max {
+			return nil, fmt.Errorf("treasury: band %q must satisfy min <= target <= max", part)
+		}
+		b[models.Currency(strings.ToUpper(strings.TrimSpace(fields[0])))] = band
+	}
+	return b, nil
+}
+
+// Paused reports whether automatic withdrawals of currency are on hold.
+// A registered hot wallet counts as paused until its first pass.
+func Paused(currency models.Currency) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	if _, ok := wallets[currency]; !ok {
+		return false
+	}
+	p, evaluated := paused[currency]
+	return p || !evaluated
+}
+
+// Latest returns the most recent report, or nil before the first run.
+func Latest() *Report {
+	mu.RLock()
+	defer mu.RUnlock()
+	return latest
+}
+
+// Run evaluates the hot wallets immediately and then on every interval.
+func Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		RunOnce(context.Background())
+		<-ticker.C
+	}
+}
+
+// RunOnce evaluates every registered hot wallet, updates the pause state
+// and proposals, and publishes the report as Latest.
+func RunOnce(ctx context.Context) *Report {
+	mu.RLock()
+	currencies := make([]models.Currency, 0, len(wallets))
+	for c := range wallets {
+		currencies = append(currencies, c)
+	}
+	mu.RUnlock()
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i] < currencies[j] })
+
+	report := &Report{GeneratedAt: time.Now().UTC(), Wallets: []WalletStatus{}}
+	for _, c := range currencies {
+		status := evaluate(ctx, c)
+		report.Wallets = append(report.Wallets, status)
+
+		mu.Lock()
+		was, evaluated := paused[c]
+		paused[c] = status.Paused
+		mu.Unlock()
+		switch {
+		case status.Paused && status.Error != "":
+			log.Printf("Treasury: %s withdrawals paused, hot wallet check failed: %s", c, status.Error)
+		case status.Paused && (!was || !evaluated):
+			log.Printf("Treasury: pausing %s withdrawals, hot wallet holds %d but %d is queued", c, status.Balance, status.Queued)
+		case !status.Paused && was:
+			log.Printf("Treasury: resuming %s withdrawals", c)
+		}
+	}
+
+	mu.Lock()
+	latest = report
+	mu.Unlock()
+	return report
+}
+
+// evaluate checks one currency. Errors pause withdrawals, since coverage
+// cannot be confirmed.
+func evaluate(ctx context.Context, currency models.Currency) WalletStatus {
+	mu.RLock()
+	w := wallets[currency]
+	band, hasBand := bands[currency]
+	mu.RUnlock()
+
+	status := WalletStatus{Currency: currency, HotWallet: w.hot.Address(), ColdWallet: w.cold}
+	if hasBand {
+		status.Band = &band
+	}
+	fail := func(err error) WalletStatus {
+		status.Paused = true
+		status.Error = err.Error()
+		return status
+	}
+
+	balance, err := w.hot.Balance(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	if err := queueTotals(currency, &status); err != nil {
+		return fail(err)
+	}
+	status.Balance = balance
+	status.Available = balance - status.Queued
+	status.Decision = Evaluate(status.Band, balance, status.Queued)
+
+	if err := propose(currency, status, w); err != nil {
+		log.Printf("Treasury: updating %s proposal failed: %v", currency, err)
+	}
+	return status
+}
+
+// queueTotals sums the withdrawals not yet sent
+func queueTotals(currency models.Currency, status *WalletStatus) error {
+	var totals []struct {
+		Status models.WithdrawalStatus
+		Total  int64
+	}
+	err := db.DB.Model(&models.Withdrawal{}).
+		Select("status, SUM(amount) AS total").
+		Where("currency = ? AND status IN ?", currency, []models.WithdrawalStatus{
+			models.WithdrawalPending, models.WithdrawalApproved, models.WithdrawalPendingReview,
+		}).
+		Group("status").
+		Scan(&totals).Error
+	if err != nil {
+		return err
+	}
+	for _, t := range totals {
+		if t.Status == models.WithdrawalPendingReview {
+			status.InReview += t.Total
+		} else {
+			status.Queued += t.Total
+		}
+	}
+	return nil
+}
+
+// propose keeps the open proposal in line with the decision: updated while
+// the same action is needed, expired once it is not, replaced when the
+// action flips.
+func propose(currency models.Currency, status WalletStatus, w wallet) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var open []models.TreasuryProposal
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("currency = ? AND status = ?", currency, models.ProposalOpen).
+			Find(&open).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		current := false
+		for _, p := range open {
+			if p.Kind == status.Kind && !current {
+				current = true
+				err = tx.Model(&p).Updates(map[string]interface{}{
+					"amount":      status.Amount,
+					"hot_balance": status.Balance,
+				}).Error
+			} else {
+				err = tx.Model(&p).Updates(map[string]interface{}{
+					"status":      models.ProposalExpired,
+					"resolved_at": &now,
+				}).Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if current || status.Kind == "" {
+			return nil
+		}
+
+		p := models.TreasuryProposal{
+			Currency:    currency,
+			Kind:        status.Kind,
+			Status:      models.ProposalOpen,
+			Amount:      status.Amount,
+			FromAddress: w.hot.Address(),
+			ToAddress:   w.cold,
+			HotBalance:  status.Balance,
+		}
+		if status.Kind == models.ProposalRefill {
+			p.FromAddress, p.ToAddress = w.cold, w.hot.Address()
+		}
+		log.Printf("Treasury: proposing %s of %d %s from %q to %q", p.Kind, p.Amount, currency, p.FromAddress, p.ToAddress)
+		return tx.Create(&p).Error
+	})
+}
+
+// Resolve closes an open proposal as executed or dismissed. A dismissed
+// proposal is raised again on the next pass if the balance is still outside
+// the band.
+func Resolve(id, adminID uuid.UUID, status models.ProposalStatus, note string) error {
+	if status != models.ProposalExecuted && status != models.ProposalDismissed {
+		return fmt.Errorf("treasury: cannot resolve a proposal as %s", status)
+	}
+	now := time.Now()
+	result := db.DB.Model(&models.TreasuryProposal{}).
+		Where("id = ? AND status = ?", id, models.ProposalOpen).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"resolved_by": adminID,
+			"note":        note,
+			"resolved_at": &now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotOpen
+	}
+	return nil
+}
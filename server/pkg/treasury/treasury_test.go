@@ -0,0 +1,46 @@
+package treasury
+
+import (
+	"testing"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	band := &Band{Min: 100, Target: 500, Max: 1000}
+
+	cases := []struct {
+		name            string
+		band            *Band
+		balance, queued int64
+		want            Decision
+	}{
+		{"inside band", band, 700, 100, Decision{}},
+		{"above band", band, 1500, 200, Decision{Kind: models.ProposalSweepToCold, Amount: 800}},
+		{"below band", band, 150, 100, Decision{Kind: models.ProposalRefill, Amount: 450}},
+		{"queue exceeds balance", band, 300, 400, Decision{Paused: true, Kind: models.ProposalRefill, Amount: 600}},
+		{"queue exactly covered", band, 400, 400, Decision{Kind: models.ProposalRefill, Amount: 500}},
+		{"no band", nil, 10, 20, Decision{Paused: true}},
+	}
+	for _, c := range cases {
+		if got := Evaluate(c.band, c.balance, c.queued); got != c.want {
+			t.Errorf("%s: Evaluate = %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseBands(t *testing.T) {
+	got, err := ParseBands("sol:1:2:3, TON:0:0:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[models.CurrencySOL] != (Band{1, 2, 3}) || got[models.CurrencyTON] != (Band{0, 0, 10}) {
+		t.Errorf("bands = %v", got)
+	}
+
+	for _, bad := range []string{"SOL:1:2", "SOL:3:2:1", "SOL:a:2:3", "SOL:-1:2:3"} {
+		if _, err := ParseBands(bad); err == nil {
+			t.Errorf("ParseBands(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package withdrawal
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/hugolol/gamblefights/pkg/chain"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+// errMoved means another pass changed the withdrawal's status first
+var errMoved = errors.New("withdrawal: status changed concurrently")
+
+// Processor moves withdrawals through approval, broadcast and confirmation
+type Processor struct {
+	Interval time.Duration
+
+	// Paused, if set, holds back approving and sending withdrawals of a
+	// currency, e.g. while the hot wallet cannot cover them. Broadcast ones
+	// are still settled.
+	Paused func(currency models.Currency) bool
+}
+
+// NewProcessor creates a processor polling every interval.
+func NewProcessor(interval time.Duration) *Processor {
+	return &Processor{Interval: interval}
+}
+
+// Run processes withdrawals on every interval until ctx is cancelled.
+func (p *Processor) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Process(ctx); err != nil {
+			log.Printf("Withdrawal processing failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process runs one pass: approve PENDING withdrawals, send APPROVED ones
+// and settle BROADCAST ones. A withdrawal that fails is logged and retried
+// on the next pass.
+func (p *Processor) Process(ctx context.Context) error {
+	var queued []models.Withdrawal
+	err := db.DB.
+		Where("status IN ?", []models.WithdrawalStatus{models.WithdrawalPending, models.WithdrawalApproved}).
+		Order("created_at ASC").
+		Find(&queued).Error
+	if err != nil {
+		return err
+	}
+	for i := range queued {
+		w := &queued[i]
+		if p.Paused != nil && p.Paused(w.Currency) {
+			continue
+		}
+		if w.Status == models.WithdrawalPending {
+			if err := approve(w); err != nil {
+				log.Printf("Withdrawal %s: approval failed: %v", w.ID, err)
+				continue
+			}
+		}
+		switch err := p.send(ctx, w); {
+		case errors.Is(err, chain.ErrBusy):
+			// Sent on a later pass, once the chain is ready
+		case err != nil:
+			log.Printf("Withdrawal %s: send failed: %v", w.ID, err)
+		}
+	}
+
+	var broadcast []models.Withdrawal
+	if err := db.DB.Where("status = ?", models.WithdrawalBroadcast).Order("broadcast_at ASC").Find(&broadcast).Error; err != nil {
+		return err
+	}
+	for i := range broadcast {
+		if err := p.check(ctx, &broadcast[i]); err != nil {
+			log.Printf("Withdrawal %s: check failed: %v", broadcast[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// approve moves a PENDING withdrawal, which is below the review threshold,
+// to APPROVED
+func approve(w *models.Withdrawal) error {
+	result := db.DB.Model(&models.Withdrawal{}).
+		Where("id = ? AND status = ?", w.ID, models.WithdrawalPending).
+		Update("status", models.WithdrawalApproved)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errMoved
+	}
+	w.Status = models.WithdrawalApproved
+	return nil
+}
+
+// send signs an approved withdrawal, records it as BROADCAST with its hash,
+// then broadcasts it. Recording first means a crash after sending is
+// picked up by check rather than signed and paid again.
+func (p *Processor) send(ctx context.Context, w *models.Withdrawal) error {
+	payouts := PayoutsFor(w.Currency)
+	if payouts == nil {
+		return ErrUnsupportedCurrency
+	}
+
+	prepared, err := payouts.Prepare(ctx, w.Destination, w.Amount)
+	if err != nil {
+		return err
+	}
+	raw := base64.StdEncoding.EncodeToString(prepared.Raw)
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Withdrawal{}).
+			Where("id = ? AND status = ?", w.ID, models.WithdrawalApproved).
+			Updates(map[string]interface{}{
+				"status":       models.WithdrawalBroadcast,
+				"tx_hash":      prepared.TxHash,
+				"raw_tx":       raw,
+				"broadcast_at": &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errMoved
+		}
+		return ledger.SetTxHash(tx, ledger.HoldKey(w.ID), prepared.TxHash)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Withdrawal %s: sending %d %s to %s in %s", w.ID, w.Amount, w.Currency, w.Destination, prepared.TxHash)
+	if err := payouts.Broadcast(ctx, prepared.Raw); err != nil {
+		// check retries, or refunds once the transaction can no longer land
+		log.Printf("Withdrawal %s: broadcast failed: %v", w.ID, err)
+	}
+	return nil
+}
+
+// check settles a broadcast withdrawal once the chain has decided it, and
+// rebroadcasts it while it can still land.
+func (p *Processor) check(ctx context.Context, w *models.Withdrawal) error {
+	payouts := PayoutsFor(w.Currency)
+	if payouts == nil {
+		return ErrUnsupportedCurrency
+	}
+	raw, err := base64.StdEncoding.DecodeString(w.RawTx)
+	if err != nil {
+		return err
+	}
+	prepared := &chain.Prepared{TxHash: w.TxHash, Raw: raw}
+
+	// Expiry first: if it expired before the status lookup saw nothing, it
+	// can never land
+	expired, err := payouts.Expired(ctx, prepared)
+	if err != nil {
+		return err
+	}
+	status, err := payouts.Status(ctx, prepared)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status.Confirmed:
+		return ledger.CompleteWithdrawal(w, func(tx *gorm.DB) error {
+			return finish(tx, w, models.WithdrawalConfirmed, "")
+		})
+	case status.Failed:
+		return refund(w, "Transaction failed on chain")
+	case !status.Found && expired:
+		return refund(w, "Transaction expired before landing")
+	case !status.Found:
+		if err := payouts.Broadcast(ctx, raw); err != nil {
+			log.Printf("Withdrawal %s: rebroadcast failed: %v", w.ID, err)
+		}
+	}
+	return nil
+}
+
+func refund(w *models.Withdrawal, reason string) error {
+	log.Printf("Withdrawal %s: %s, refunding", w.ID, reason)
+	return ledger.RefundWithdrawal(w, func(tx *gorm.DB) error {
+		return finish(tx, w, models.WithdrawalFailed, reason)
+	})
+}
+
+// finish moves a BROADCAST withdrawal to a final status
+func finish(tx *gorm.DB, w *models.Withdrawal, status models.WithdrawalStatus, reason string) error {
+	now := time.Now()
+	result := tx.Model(&models.Withdrawal{}).
+		Where("id = ? AND status = ?", w.ID, models.WithdrawalBroadcast).
+		Updates(map[string]interface{}{
+			"status":         status,
+			"failure_reason": reason,
+			"finished_at":    &now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errMoved
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Package withdrawal pays user funds out to external addresses.
+//
+// Requesting a withdrawal holds the amount in the PAYOUTS ledger account
+// and records it as PENDING, or PENDING_REVIEW when it exceeds the review
+// threshold for its currency. PENDING withdrawals are approved
+// automatically; PENDING_REVIEW ones wait for an admin. The processor signs
+// APPROVED withdrawals, persists the transaction hash, and only then
+// broadcasts, so a crash can resend but never pay twice. BROADCAST
+// withdrawals end CONFIRMED once final on chain, or FAILED with the held
+// funds refunded when the transaction failed or expired without landing.
+package withdrawal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/hugolol/gamblefights/pkg/chain"
+	"github.com/hugolol/gamblefights/pkg/currency"
+	"github.com/hugolol/gamblefights/pkg/db"
+	"github.com/hugolol/gamblefights/pkg/ledger"
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+var (
+	// ErrUnsupportedCurrency means no chain is configured to pay the currency
+	ErrUnsupportedCurrency = errors.New("withdrawal: currency cannot be withdrawn")
+
+	// ErrInvalidDestination means the chain rejected the destination address
+	ErrInvalidDestination = errors.New("withdrawal: invalid destination address")
+
+	// ErrBelowMinimum means the amount is below what the chain can pay out
+	ErrBelowMinimum = errors.New("withdrawal: amount below minimum")
+
+	// ErrNotReviewable means the withdrawal has already left review
+	ErrNotReviewable = errors.New("withdrawal: not awaiting review")
+)
+
+// DefaultThresholds are the review thresholds used when none are configured
+var DefaultThresholds = map[models.Currency]int64{
+	models.CurrencySOL:  10_000_000_000,  // 10 SOL
+	models.CurrencyTON:  300_000_000_000, // 300 TON
+	models.CurrencyUSDT: 1_000_000_000,   // 1000 USDT
+}
+
+var (
+	payouts    = map[models.Currency]chain.Payouts{}
+	thresholds = DefaultThresholds
+	mu         sync.RWMutex
+)
+
+// Register enables withdrawals of currency through p.
+func Register(currency models.Currency, p chain.Payouts) {
+	mu.Lock()
+	payouts[currency] = p
+	mu.Unlock()
+}
+
+// PayoutsFor returns the chain that pays out currency, or nil.
+func PayoutsFor(currency models.Currency) chain.Payouts {
+	mu.RLock()
+	defer mu.RUnlock()
+	return payouts[currency]
+}
+
+// SetThresholds replaces the review thresholds. Withdrawals above the
+// threshold of their currency need an admin; currencies without one never
+// do.
+func SetThresholds(t map[models.Currency]int64) {
+	mu.Lock()
+	thresholds = t
+	mu.Unlock()
+}
+
+// NeedsReview reports whether a withdrawal must be approved by an admin.
+func NeedsReview(currency models.Currency, amount int64) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	limit, ok := thresholds[currency]
+	return ok && amount > limit
+}
+
+// LoadFromEnv reads review thresholds from WITHDRAWAL_REVIEW_THRESHOLDS,
+// keeping the defaults when unset.
+func LoadFromEnv() error {
+	value := os.Getenv("WITHDRAWAL_REVIEW_THRESHOLDS")
+	if value == "" {
+		return nil
+	}
+	t, err := ParseThresholds(value)
+	if err != nil {
+		return err
+	}
+	SetThresholds(t)
+	return nil
+}
+
+// ParseThresholds parses comma-separated CURRENCY:amount pairs, amounts in
+// atomic units, e.g. "SOL:10000000000,USDT:1000000000".
+func ParseThresholds(s string) (map[models.Currency]int64, error) {
+	t := map[models.Currency]int64{}
+	for _, part := range currency.SplitList(s) {
+		code, amount, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("withdrawal: threshold %q is not CURRENCY:amount", part)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("withdrawal: invalid threshold amount in %q", part)
+		}
+		t[models.Currency(strings.ToUpper(strings.TrimSpace(code)))] = n
+	}
+	return t, nil
+}
+
+// Request holds amount from the user's wallet and records a withdrawal to
+// destination.
+func Request(userID uuid.UUID, currency models.Currency, amount int64, destination string) (*models.Withdrawal, error) {
+	p := PayoutsFor(currency)
+	if p == nil {
+		return nil, ErrUnsupportedCurrency
+	}
+	if err := p.ValidateAddress(destination); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDestination, err)
+	}
+	if amount < p.MinAmount() {
+		return nil, fmt.Errorf("%w of %d", ErrBelowMinimum, p.MinAmount())
+	}
+
+	w := &models.Withdrawal{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Currency:    currency,
+		Amount:      amount,
+		Destination: destination,
+		Status:      models.WithdrawalPending,
+	}
+	if NeedsReview(currency, amount) {
+		w.Status = models.WithdrawalPendingReview
+	}
+
+	err := ledger.HoldWithdrawal(w.ID, userID, currency, amount, func(tx *gorm.DB) error {
+		return tx.Create(w).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Approve releases a withdrawal from review to be sent.
+func Approve(id, adminID uuid.UUID, note string) error {
+	result := db.DB.Model(&models.Withdrawal{}).
+		Where("id = ? AND status = ?", id, models.WithdrawalPendingReview).
+		Updates(map[string]interface{}{
+			"status":      models.WithdrawalApproved,
+			"reviewed_by": adminID,
+			"review_note": note,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotReviewable
+	}
+	return nil
+}
+
+// Reject fails a withdrawal that has not been sent yet and refunds it.
+func Reject(id, adminID uuid.UUID, note string) error {
+	var w models.Withdrawal
+	if err := db.DB.First(&w, "id = ?", id).Error; err != nil {
+		return err
+	}
+	if w.Status != models.WithdrawalPending && w.Status != models.WithdrawalPendingReview {
+		return ErrNotReviewable
+	}
+
+	return ledger.RefundWithdrawal(&w, func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Withdrawal{}).
+			Where("id = ? AND status IN ?", id, []models.WithdrawalStatus{models.WithdrawalPending, models.WithdrawalPendingReview}).
+			Updates(map[string]interface{}{
+				"status":         models.WithdrawalFailed,
+				"reviewed_by":    adminID,
+				"review_note":    note,
+				"failure_reason": "Rejected in review",
+				"finished_at":    &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotReviewable
+		}
+		return nil
+	})
+}
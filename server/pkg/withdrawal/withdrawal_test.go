@@ -0,0 +1,38 @@
+package withdrawal
+
+import (
+	"testing"
+
+	"github.com/hugolol/gamblefights/pkg/models"
+)
+
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds(" sol:10000000000, USDT:500000000 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[models.CurrencySOL] != 10_000_000_000 || got[models.CurrencyUSDT] != 500_000_000 {
+		t.Errorf("thresholds = %v", got)
+	}
+
+	for _, bad := range []string{"SOL", "SOL:abc", "SOL:-1", "SOL:1.5"} {
+		if _, err := ParseThresholds(bad); err == nil {
+			t.Errorf("ParseThresholds(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestNeedsReview(t *testing.T) {
+	defer SetThresholds(DefaultThresholds)
+	SetThresholds(map[models.Currency]int64{models.CurrencySOL: 1000})
+
+	if NeedsReview(models.CurrencySOL, 1000) {
+		t.Error("amount at the threshold needs review")
+	}
+	if !NeedsReview(models.CurrencySOL, 1001) {
+		t.Error("amount above the threshold skipped review")
+	}
+	if NeedsReview(models.CurrencyTON, 1<<40) {
+		t.Error("currency without a threshold needs review")
+	}
+}
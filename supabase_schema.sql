@@ -29,6 +29,7 @@ CREATE TABLE wallets (
     currency VARCHAR(10) NOT NULL,
     balance BIGINT NOT NULL DEFAULT 0,
     deposit_address VARCHAR(255),
+    deposit_index BIGINT UNIQUE,
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
@@ -40,24 +41,143 @@ CREATE TABLE matches (
     player_b_id UUID NOT NULL REFERENCES users(id),
     wager_amount BIGINT NOT NULL,
     currency VARCHAR(10) NOT NULL,
-    server_seed VARCHAR(128) NOT NULL,
+    rake BIGINT NOT NULL DEFAULT 0, -- House cut of the pot
+    is_test BOOLEAN NOT NULL DEFAULT FALSE, -- Free fight against a bot
+    server_seed TEXT NOT NULL, -- Encrypted envelope until the seed is revealed
     server_seed_hashed VARCHAR(128) NOT NULL,
+    seed_revealed BOOLEAN NOT NULL DEFAULT FALSE,
     client_seed_a VARCHAR(128),
     client_seed_b VARCHAR(128),
     nonce BIGINT DEFAULT 0,
+    fairness_version INT NOT NULL DEFAULT 1,
+    beacon_round BIGINT,
+    beacon_randomness VARCHAR(128),
     winner_id UUID REFERENCES users(id),
     status VARCHAR(20) DEFAULT 'WAITING',
     fight_script JSONB,
+    receipt TEXT,
+    receipt_signature VARCHAR(128),
+    receipt_key_id VARCHAR(16),
     created_at TIMESTAMPTZ DEFAULT NOW(),
     finished_at TIMESTAMPTZ
 );
 
+-- Server seeds table (per-user committed seed pairs)
+CREATE TABLE server_seeds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seed TEXT NOT NULL, -- Encrypted envelope until revealed
+    seed_hashed VARCHAR(128) UNIQUE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    revealed_at TIMESTAMPTZ
+);
+
+-- Merkle roots table (daily commitment over completed matches)
+CREATE TABLE merkle_roots (
+    day VARCHAR(10) PRIMARY KEY,
+    root VARCHAR(64) NOT NULL,
+    leaf_count INT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Ledger: balances of system accounts (escrow, house, external, faucet)
+CREATE TABLE ledger_accounts (
+    account VARCHAR(20) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (account, currency)
+);
+
+-- Ledger: journal entries, each a balanced set of postings
+CREATE TABLE journal_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    type VARCHAR(20) NOT NULL,
+    match_id UUID REFERENCES matches(id),
+    description VARCHAR(255),
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Ledger: postings against a user wallet or a system account
+CREATE TABLE postings (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    wallet_id UUID REFERENCES wallets(id),
+    account VARCHAR(20),
+    currency VARCHAR(10) NOT NULL,
+    amount BIGINT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK ((wallet_id IS NULL) <> (account IS NULL))
+);
+
+-- Cached responses of money-moving requests, keyed by Idempotency-Key
+CREATE TABLE idempotency_records (
+    user_id UUID NOT NULL REFERENCES users(id),
+    key VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_body TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, key)
+);
+
+-- Newest processed on-chain transaction per watched address
+CREATE TABLE chain_cursors (
+    chain VARCHAR(20) NOT NULL,
+    address VARCHAR(128) NOT NULL,
+    signature VARCHAR(128) NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chain, address)
+);
+
+-- Withdrawals, held in the PAYOUTS ledger account until confirmed on chain
+CREATE TABLE withdrawals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    currency VARCHAR(10) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    destination VARCHAR(128) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    tx_hash VARCHAR(128),
+    raw_tx TEXT,
+    reviewed_by UUID REFERENCES users(id),
+    review_note VARCHAR(255),
+    failure_reason VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    broadcast_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+-- Proposals to move funds between the hot wallet and cold storage
+CREATE TABLE treasury_proposals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    currency VARCHAR(10) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    from_address VARCHAR(128),
+    to_address VARCHAR(128),
+    hot_balance BIGINT NOT NULL,
+    resolved_by UUID REFERENCES users(id),
+    note VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ
+);
+
 -- Transactions table
 CREATE TABLE transactions (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id),
     wallet_id UUID NOT NULL REFERENCES wallets(id),
     match_id UUID REFERENCES matches(id),
+    entry_id UUID REFERENCES journal_entries(id),
     type VARCHAR(20) NOT NULL,
     amount BIGINT NOT NULL,
     currency VARCHAR(10) NOT NULL,
@@ -74,8 +194,20 @@ CREATE INDEX idx_matches_player_a ON matches(player_a_id);
 CREATE INDEX idx_matches_player_b ON matches(player_b_id);
 CREATE INDEX idx_matches_winner ON matches(winner_id);
 CREATE INDEX idx_matches_status ON matches(status);
+CREATE INDEX idx_matches_server_seed_hashed ON matches(server_seed_hashed);
+CREATE INDEX idx_server_seeds_user ON server_seeds(user_id);
+CREATE INDEX idx_server_seeds_status ON server_seeds(status);
 CREATE INDEX idx_transactions_user ON transactions(user_id);
 CREATE INDEX idx_transactions_match ON transactions(match_id);
+CREATE INDEX idx_journal_entries_match ON journal_entries(match_id);
+CREATE INDEX idx_transactions_tx_hash ON transactions(tx_hash);
+CREATE INDEX idx_withdrawals_user_id ON withdrawals(user_id);
+CREATE INDEX idx_withdrawals_status ON withdrawals(status);
+CREATE INDEX idx_withdrawals_tx_hash ON withdrawals(tx_hash);
+CREATE INDEX idx_treasury_proposals_currency ON treasury_proposals(currency);
+CREATE INDEX idx_treasury_proposals_status ON treasury_proposals(status);
+CREATE INDEX idx_postings_entry ON postings(entry_id);
+CREATE INDEX idx_postings_wallet ON postings(wallet_id);
 
 -- Updated_at trigger function
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -0,0 +1,57 @@
+// Runs the shared fairness test vectors against the WebAssembly build of
+// server/pkg/fairness, so the browser verifier is proven to match the server.
+//
+//   make wasm && node web/scripts/check-fairness-wasm.mjs
+import { createHash, webcrypto } from 'node:crypto';
+import { readFileSync } from 'node:fs';
+import { dirname, join } from 'node:path';
+import { fileURLToPath } from 'node:url';
+
+const here = dirname(fileURLToPath(import.meta.url));
+const publicDir = join(here, '..', 'public');
+const vectorsPath = join(here, '..', '..', 'server', 'pkg', 'fairness', 'testdata', 'vectors.json');
+
+if (!globalThis.crypto) {
+  globalThis.crypto = webcrypto;
+}
+
+// wasm_exec.js defines globalThis.Go
+await import(join(publicDir, 'wasm_exec.js'));
+
+const go = new globalThis.Go();
+const { instance } = await WebAssembly.instantiate(readFileSync(join(publicDir, 'fairness.wasm')), go.importObject);
+go.run(instance);
+
+const fairness = globalThis.gamblefightsFairness;
+const vectors = JSON.parse(readFileSync(vectorsPath, 'utf8'));
+
+let failures = 0;
+vectors.forEach((vector, i) => {
+  const input = JSON.stringify(vector);
+  const outcome = JSON.parse(fairness.outcome(input));
+  const script = fairness.fightScript(input);
+  const scriptSha256 = createHash('sha256').update(script).digest('hex');
+
+  const checks = {
+    message: outcome.message === vector.expected.message,
+    hash: outcome.hash === vector.expected.hash,
+    decValue: outcome.decValue === vector.expected.decValue,
+    isPlayerAWin: outcome.isPlayerAWin === vector.expected.isPlayerAWin,
+    fightScript: scriptSha256 === vector.expected.fightScriptSha256,
+  };
+
+  const failed = Object.entries(checks).filter(([, ok]) => !ok).map(([name]) => name);
+  if (failed.length > 0) {
+    failures++;
+    console.error(`FAIL vector ${i + 1} (v${vector.version}, nonce ${vector.nonce}): ${failed.join(', ')}`);
+  } else {
+    console.log(`PASS vector ${i + 1} (v${vector.version}, nonce ${vector.nonce})`);
+  }
+});
+
+if (failures > 0) {
+  console.error(`${failures} of ${vectors.length} vectors failed`);
+  process.exit(1);
+}
+console.log(`All ${vectors.length} vectors match the server implementation`);
+process.exit(0);
@@ -1,28 +1,46 @@
 'use client';
 
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import { useAuth } from '../context/AuthContext';
 import { useGame, MatchResult } from '../context/GameContext';
-import { parseSOL, formatSOL } from '@/lib/api';
+import { Currency, getCurrencies, formatAmount, parseAmount, rakeBps } from '@/lib/api';
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 const DEMO_WAGER = 100000000; // 0.1 SOL in lamports for demo
 
-const WAGER_PRESETS = [
-  { label: '0.05 SOL', value: 0.05 },
-  { label: '0.1 SOL', value: 0.1 },
-  { label: '0.25 SOL', value: 0.25 },
-  { label: '0.5 SOL', value: 0.5 },
-  { label: '1 SOL', value: 1 },
-];
-
 export function BettingPanel() {
   const { balances, isGuest, user } = useAuth();
   const { gameState, joinQueue, leaveQueue, isConnected, setTestMatch, startDemoFight } = useGame();
-  const [selectedWager, setSelectedWager] = useState(0.1);
+  const [currencies, setCurrencies] = useState<Currency[]>([]);
+  const [currencyCode, setCurrencyCode] = useState('SOL');
+  const [selectedWager, setSelectedWager] = useState<number | null>(null); // Atomic units; null for the default
   const [customWager, setCustomWager] = useState('');
   const [isTestingFight, setIsTestingFight] = useState(false);
 
+  // Wager limits and preset tiers come from the server
+  useEffect(() => {
+    getCurrencies()
+      .then(({ currencies }) => setCurrencies(currencies.filter((c) => c.wagering)))
+      .catch((err) => console.error('Failed to fetch currencies:', err));
+  }, []);
+
+  const currency = currencies.find((c) => c.code === currencyCode) || currencies[0];
+  const wagerAmount = !currency
+    ? 0
+    : customWager
+    ? parseAmount(customWager, currency)
+    : selectedWager ?? currency.defaultWager;
+  const validWager =
+    !!currency && Number.isFinite(wagerAmount) && wagerAmount >= currency.minWager && wagerAmount <= currency.maxWager;
+  const rake = currency && validWager ? rakeBps(currency, wagerAmount) : 0;
+  const format = (amount: number) => (currency ? formatAmount(amount, currency) : '');
+
+  const selectCurrency = (code: string) => {
+    setCurrencyCode(code);
+    setSelectedWager(null);
+    setCustomWager('');
+  };
+
   // Handle fight - uses demo mode for guests, real API for authenticated users
   const handleFight = () => {
     if (isGuest) {
@@ -30,8 +48,9 @@ export function BettingPanel() {
       startDemoFight(user?.username || 'Guest', DEMO_WAGER);
     } else {
       // Use real queue for authenticated users
-      const amount = customWager ? parseFloat(customWager) : selectedWager;
-      joinQueue(parseSOL(amount));
+      if (currency && validWager) {
+        joinQueue(wagerAmount, currency.code);
+      }
     }
   };
 
@@ -73,10 +92,8 @@ export function BettingPanel() {
     }
   };
 
-  const solBalance = balances.find((b) => b.currency === 'SOL');
-  const balanceLamports = solBalance?.balance || 0;
-  const wagerLamports = parseSOL(customWager ? parseFloat(customWager) : selectedWager);
-  const hasEnoughBalance = isGuest || balanceLamports >= wagerLamports;
+  const balance = balances.find((b) => b.currency === currency?.code);
+  const hasEnoughBalance = isGuest || (balance?.balance || 0) >= (validWager ? wagerAmount : 0);
 
   const isQueuing = gameState === 'queuing' || gameState === 'matched';
 
@@ -86,24 +103,43 @@ export function BettingPanel() {
 
       {/* Wager Selection */}
       <div className="space-y-4">
+        {/* Currency Selection */}
+        {currencies.length > 1 && (
+          <div className="flex gap-2">
+            {currencies.map((c) => (
+              <button
+                key={c.code}
+                onClick={() => selectCurrency(c.code)}
+                disabled={isQueuing}
+                className={`flex-1 py-1 px-2 rounded text-xs font-bold transition-all border font-[Cinzel] ${c.code === currency?.code
+                    ? 'bg-[#8b0000] text-[#ffd700] border-[#ffd700]'
+                    : 'bg-[#eecfa1] text-[#5a3a22] border-[#8b6b45] hover:bg-[#d4b483]'
+                  } disabled:opacity-50`}
+              >
+                {c.code}
+              </button>
+            ))}
+          </div>
+        )}
+
         <label className="block text-sm text-[#5a3a22] font-bold uppercase tracking-wide">Select Wager Amount</label>
 
         {/* Preset Buttons */}
         <div className="grid grid-cols-3 gap-2">
-          {WAGER_PRESETS.map((preset) => (
+          {currency?.presets.map((preset) => (
             <button
-              key={preset.value}
+              key={preset.amount}
               onClick={() => {
-                setSelectedWager(preset.value);
+                setSelectedWager(preset.amount);
                 setCustomWager('');
               }}
               disabled={isQueuing}
-              className={`py-2 px-3 rounded text-sm font-bold transition-all border font-[Cinzel] ${selectedWager === preset.value && !customWager
+              className={`py-2 px-3 rounded text-sm font-bold transition-all border font-[Cinzel] ${wagerAmount === preset.amount && !customWager
                   ? 'bg-[#8b0000] text-[#ffd700] border-[#ffd700]'
                   : 'bg-[#eecfa1] text-[#5a3a22] border-[#8b6b45] hover:bg-[#d4b483]'
                 } disabled:opacity-50`}
             >
-              {preset.label}
+              {preset.display}
             </button>
           ))}
         </div>
@@ -118,10 +154,10 @@ export function BettingPanel() {
             disabled={isQueuing}
             className="w-full px-4 py-3 bg-[#eecfa1] border-2 border-[#8b6b45] rounded text-[#3b3b3b] placeholder-[#8b6b45]/50 focus:outline-none focus:border-[#8b0000] font-[Cinzel] disabled:opacity-50"
             step="0.01"
-            min="0.01"
+            min="0"
           />
           <span className="absolute right-4 top-1/2 -translate-y-1/2 text-[#5a3a22] font-bold">
-            SOL
+            {currency?.code}
           </span>
         </div>
 
@@ -129,11 +165,17 @@ export function BettingPanel() {
         <div className="flex justify-between text-sm font-mono">
           <span className="text-[#5a3a22]">Your Balance:</span>
           <span className={hasEnoughBalance ? 'text-[#3b8b00] font-bold' : 'text-[#8b0000] font-bold'}>
-            {solBalance?.display || '0 SOL'}
+            {balance?.display || format(0)}
           </span>
         </div>
 
-        {!hasEnoughBalance && wagerLamports > 0 && (
+        {currency && customWager && !validWager && (
+          <p className="text-[#8b0000] text-sm text-center font-bold">
+            Wager between {format(currency.minWager)} and {format(currency.maxWager)}
+          </p>
+        )}
+
+        {!hasEnoughBalance && validWager && (
           <p className="text-[#8b0000] text-sm text-center font-bold">
             Insufficient funds, beggar.
           </p>
@@ -145,14 +187,14 @@ export function BettingPanel() {
         {!isQueuing ? (
           <button
             onClick={handleFight}
-            disabled={(!isGuest && !isConnected) || !hasEnoughBalance || (!isGuest && wagerLamports === 0)}
+            disabled={(!isGuest && !isConnected) || !hasEnoughBalance || (!isGuest && !validWager)}
             className="w-full py-4 bg-[#8b0000] text-[#ffd700] border-4 border-[#ffd700] rounded text-lg font-bold font-[Cinzel] uppercase disabled:opacity-50 disabled:cursor-not-allowed transition-all transform hover:scale-[1.02] active:scale-[0.98] shadow-lg"
           >
             {isGuest
               ? 'FIGHT NOW (Demo)'
               : !isConnected
               ? 'Connecting to Arena...'
-              : `FIGHT FOR ${formatSOL(wagerLamports)}`}
+              : `FIGHT FOR ${format(wagerAmount)}`}
           </button>
         ) : (
           <div className="space-y-3">
@@ -164,7 +206,7 @@ export function BettingPanel() {
                 </span>
               </div>
               <p className="text-[#5a3a22] text-sm mt-2 font-mono">
-                Wager: {formatSOL(wagerLamports)}
+                Wager: {format(wagerAmount)}
               </p>
             </div>
             <button
@@ -191,7 +233,11 @@ export function BettingPanel() {
       {/* Info */}
       <div className="mt-4 pt-4 border-t border-[#8b6b45]/30">
         <div className="text-[10px] text-[#8b6b45] space-y-1 text-center font-serif italic">
-          <p>Winner takes all gold (0% tax for Gladiators)</p>
+          <p>
+            {rake > 0
+              ? `Winner takes the pot, less a ${rake / 100}% house rake`
+              : 'Winner takes all gold (0% tax for Gladiators)'}
+          </p>
           <p>Matched with warriors of equal wealth</p>
         </div>
       </div>
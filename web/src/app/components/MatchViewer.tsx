@@ -494,7 +494,7 @@ export function MatchViewer() {
             <div className="border-t-2 border-b-2 border-[#dbb086] py-4 mb-8">
               <p className="text-2xl text-[#5a3a22] font-[Cinzel]">
                 {isWinner
-                  ? `You claim +${formatSOL(currentMatch.totalPot)}`
+                  ? `You claim +${formatSOL(currentMatch.payout ?? currentMatch.totalPot)}`
                   : `You lost -${formatSOL(currentMatch.wagerAmount)}`}
               </p>
             </div>
@@ -521,11 +521,17 @@ export function MatchViewer() {
           </div>
         </div>
 
+        {!!currentMatch.rake && (
+          <p className="mt-2 text-[#8b6b45] text-xs">
+            House rake: {formatSOL(currentMatch.rake)} · Winner receives {formatSOL(currentMatch.payout ?? currentMatch.totalPot)}
+          </p>
+        )}
+
         {showResult && (
           <div className="mt-4 pt-4 border-t border-[#5a3a22]/30">
             <p className="text-[10px] text-[#5a3a22] mb-1 uppercase tracking-wider">Provable Fairness Data</p>
             <div className="space-y-1 text-[10px] text-[#8b6b45] opacity-60 hover:opacity-100 transition-opacity cursor-help">
-              <p className="truncate">S.Seed: {currentMatch.serverSeed}</p>
+              <p className="truncate">S.Seed: {currentMatch.serverSeed || 'Hidden until seed rotation'}</p>
               <p className="truncate">Hash: {currentMatch.serverSeedHashed}</p>
               <p>Nonce: {currentMatch.nonce}</p>
             </div>
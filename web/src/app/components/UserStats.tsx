@@ -170,6 +170,15 @@ function HistoryItem({ match, userId }: { match: Match; userId: string }) {
           {isWinner ? '+' : '-'}
           {match.wagerDisplay}
         </p>
+        {isWinner && match.rake > 0 && (
+          <p className="text-[10px] text-[#8b6b45] font-mono">rake {match.rakeDisplay}</p>
+        )}
+        <a
+          href={`/fairness?match=${match.id}`}
+          className="text-[10px] text-[#8b0000] hover:text-[#a60000] underline decoration-[#8b0000]/30"
+        >
+          {match.seedRevealed ? 'verify' : 'reveal & verify'}
+        </a>
       </div>
     </div>
   );
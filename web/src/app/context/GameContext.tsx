@@ -2,6 +2,8 @@
 
 import React, { createContext, useContext, useEffect, useRef, useState, ReactNode, useCallback } from 'react';
 import { generateDemoFight } from '@/lib/demoFight';
+import type { SignedReceipt } from '@/lib/api';
+import { commitClientSeed, generateClientSeed } from '@/lib/fairness';
 
 const WS_URL = process.env.NEXT_PUBLIC_WS_URL || 'ws://localhost:8080/ws';
 
@@ -37,15 +39,20 @@ export interface MatchResult {
     matchId: string;
     winner: string;
     winnerId: string;
-    serverSeed: string;
+    serverSeed?: string; // Only present once the seed pair is rotated
     serverSeedHashed: string;
     clientSeedA: string;
     clientSeedB: string;
     nonce: number;
+    fairnessVersion?: number;
     outcomeHash: string;
     fightScript: FightScript;
     wagerAmount: number;
+    currency?: string;
     totalPot: number;
+    rake?: number;   // House cut of totalPot
+    payout?: number; // totalPot minus rake, paid to the winner
+    receipt?: SignedReceipt;
 }
 
 export type GameState = 'idle' | 'queuing' | 'matched' | 'fighting' | 'result';
@@ -65,7 +72,7 @@ interface GameContextType {
     queueWager: number;
     messages: GameMessage[];
     sendMessage: (type: string, payload?: Record<string, unknown>) => void;
-    joinQueue: (wagerAmount: number) => void;
+    joinQueue: (wagerAmount: number, currency?: string) => void;
     leaveQueue: () => void;
     resetGame: () => void;
     setTestMatch: (match: MatchResult) => void;
@@ -83,6 +90,8 @@ export function GameProvider({ children }: { children: ReactNode }) {
     const ws = useRef<WebSocket | null>(null);
     const reconnectTimeout = useRef<NodeJS.Timeout | null>(null);
     const connectRef = useRef<(() => void) | undefined>(undefined);
+    // Fresh client seed for the match being set up, revealed after both commits
+    const matchClientSeed = useRef<{ matchId: string; seed: string } | null>(null);
 
     const connect = useCallback(() => {
         if (ws.current?.readyState === WebSocket.OPEN) return;
@@ -122,7 +131,25 @@ export function GameProvider({ children }: { children: ReactNode }) {
                     case 'MATCH_FOUND':
                         setGameState('matched');
                         break;
+                    case 'SEED_EXCHANGE_START': {
+                        const matchId = msg.matchId as string;
+                        const seed = generateClientSeed();
+                        matchClientSeed.current = { matchId, seed };
+                        commitClientSeed(seed).then((commitment) => {
+                            socket.send(JSON.stringify({ type: 'CLIENT_SEED_COMMIT', payload: { matchId, commitment } }));
+                        });
+                        break;
+                    }
+                    case 'CLIENT_SEEDS_COMMITTED':
+                        if (matchClientSeed.current?.matchId === msg.matchId) {
+                            socket.send(JSON.stringify({
+                                type: 'CLIENT_SEED_REVEAL',
+                                payload: { matchId: msg.matchId, clientSeed: matchClientSeed.current.seed },
+                            }));
+                        }
+                        break;
                     case 'MATCH_RESULT':
+                        matchClientSeed.current = null;
                         setCurrentMatch(msg as unknown as MatchResult);
                         setGameState('result');
                         break;
@@ -163,9 +190,9 @@ export function GameProvider({ children }: { children: ReactNode }) {
         }
     }, []);
 
-    const joinQueue = useCallback((wagerAmount: number) => {
+    const joinQueue = useCallback((wagerAmount: number, currency: string = 'SOL') => {
         setQueueWager(wagerAmount);
-        sendMessage('JOIN_QUEUE', { wagerAmount });
+        sendMessage('JOIN_QUEUE', { wagerAmount, currency });
     }, [sendMessage]);
 
     const leaveQueue = useCallback(() => {
@@ -216,6 +243,8 @@ export function GameProvider({ children }: { children: ReactNode }) {
                     fightScript: demoFight,
                     wagerAmount: wagerAmount,
                     totalPot: wagerAmount * 2,
+                    rake: 0,
+                    payout: wagerAmount * 2,
                 };
 
                 setCurrentMatch(matchResult);
@@ -1,19 +1,64 @@
 'use client';
 
-import { useState } from 'react';
-import { verifyOutcome } from '@/lib/fairness';
+import { useEffect, useState } from 'react';
+import { verifyOutcome, matchInputs, CURRENT_FAIRNESS_VERSION } from '@/lib/fairness';
+import { getMatch, revealMatchSeed } from '@/lib/api';
 import Link from 'next/link';
 
 export default function FairnessPage() {
     const [serverSeed, setServerSeed] = useState('');
     const [clientSeed, setClientSeed] = useState('');
     const [nonce, setNonce] = useState('0');
+    const [version, setVersion] = useState(String(CURRENT_FAIRNESS_VERSION));
+    const [beacon, setBeacon] = useState('');
+    const [loadError, setLoadError] = useState<string | null>(null);
+    const [unrevealedMatch, setUnrevealedMatch] = useState<string | null>(null);
+    const [verifyError, setVerifyError] = useState<string | null>(null);
     const [result, setResult] = useState<{ isPlayerAWin: boolean; hash: string } | null>(null);
 
+    // Prefill from ?match=<id>, e.g. when linked from the match history
+    useEffect(() => {
+        const matchId = new URLSearchParams(window.location.search).get('match');
+        if (!matchId) return;
+        getMatch(matchId)
+            .then((match) => {
+                const inputs = matchInputs(match);
+                setServerSeed(inputs.serverSeed);
+                setClientSeed(inputs.clientSeed);
+                setNonce(String(inputs.nonce));
+                setVersion(String(inputs.version));
+                setBeacon(inputs.beacon);
+                if (!match.seedRevealed) {
+                    setLoadError('The server seed is revealed once the seed pair is rotated.');
+                    setUnrevealedMatch(match.id);
+                }
+            })
+            .catch((err) => setLoadError(err instanceof Error ? err.message : 'Failed to load match'));
+    }, []);
+
+    // Either player may rotate the pair the match drew from
+    const handleReveal = async () => {
+        if (!unrevealedMatch) return;
+        try {
+            const { revealedServerSeed } = await revealMatchSeed(unrevealedMatch);
+            setServerSeed(revealedServerSeed);
+            setLoadError(null);
+            setUnrevealedMatch(null);
+        } catch (err) {
+            setLoadError(err instanceof Error ? err.message : 'Failed to reveal server seed');
+        }
+    };
+
     const handleVerify = async () => {
         if (!serverSeed || !clientSeed) return;
-        const res = await verifyOutcome(serverSeed, clientSeed, parseInt(nonce));
-        setResult(res);
+        setResult(null);
+        try {
+            const res = await verifyOutcome(serverSeed, clientSeed, parseInt(nonce), parseInt(version), beacon || undefined);
+            setVerifyError(null);
+            setResult(res);
+        } catch (err) {
+            setVerifyError(err instanceof Error ? err.message : 'Verification failed');
+        }
     };
 
     return (
@@ -28,8 +73,20 @@ export default function FairnessPage() {
 
                 <div className="text-sm text-[#5a3a22] font-mono bg-[#eecfa1]/50 p-4 rounded border border-[#8b6b45]">
                     Paste the Server Seed (revealed after a match), your Client Seed, and the Nonce to verify the game outcome.
+                    Matches mixed with a public beacon (version 2) also need the Beacon Randomness.
                 </div>
 
+                {loadError && (
+                    <div className="text-sm text-[#8b0000] font-mono">
+                        {loadError}
+                        {unrevealedMatch && (
+                            <button onClick={handleReveal} className="ml-2 underline decoration-[#8b0000]/30 hover:text-[#a60000]">
+                                Reveal it now
+                            </button>
+                        )}
+                    </div>
+                )}
+
                 <div className="space-y-6">
                     <div>
                         <label className="block text-sm font-bold mb-1 text-[#5a3a22] uppercase">Server Seed</label>
@@ -42,7 +99,7 @@ export default function FairnessPage() {
                         />
                     </div>
 
-                    <div className="grid grid-cols-2 gap-4">
+                    <div className="grid grid-cols-3 gap-4">
                         <div>
                             <label className="block text-sm font-bold mb-1 text-[#5a3a22] uppercase">Client Seed</label>
                             <input
@@ -62,6 +119,26 @@ export default function FairnessPage() {
                                 className="w-full bg-[#eecfa1] border-2 border-[#8b6b45] rounded p-2 font-mono text-[#3b3b3b]"
                             />
                         </div>
+                        <div>
+                            <label className="block text-sm font-bold mb-1 text-[#5a3a22] uppercase">Version</label>
+                            <input
+                                type="number"
+                                value={version}
+                                onChange={(e) => setVersion(e.target.value)}
+                                className="w-full bg-[#eecfa1] border-2 border-[#8b6b45] rounded p-2 font-mono text-[#3b3b3b]"
+                            />
+                        </div>
+                    </div>
+
+                    <div>
+                        <label className="block text-sm font-bold mb-1 text-[#5a3a22] uppercase">Beacon Randomness</label>
+                        <input
+                            type="text"
+                            value={beacon}
+                            onChange={(e) => setBeacon(e.target.value)}
+                            className="w-full bg-[#eecfa1] border-2 border-[#8b6b45] rounded p-2 focus:border-[#8b0000] outline-none font-mono text-[#3b3b3b]"
+                            placeholder="Only for beacon matches (version 2)"
+                        />
                     </div>
 
                     <button
@@ -72,6 +149,10 @@ export default function FairnessPage() {
                     </button>
                 </div>
 
+                {verifyError && (
+                    <div className="text-sm text-[#8b0000] font-mono">{verifyError}</div>
+                )}
+
                 {result && (
                     <div className="bg-[#eecfa1] p-6 rounded border-2 border-[#8b6b45] space-y-4">
                         <h2 className="text-xl font-bold text-[#8b0000] border-b border-[#8b6b45] pb-2">Oracle&apos;s Verdict</h2>
@@ -61,14 +61,36 @@ export interface Match {
   wagerAmount: number;
   wagerDisplay: string;
   currency: string;
+  totalPot: number;
+  rake: number;        // House cut of the pot
+  rakeDisplay: string;
+  isTest: boolean;     // Free fight against a bot
+  payout?: number;     // Paid to the winner once completed
   winner: string | null;
   winnerUsername: string | null;
   status: string;
   serverSeedHashed: string;
   serverSeed?: string;
+  seedRevealed: boolean;
+  clientSeedA: string;
+  clientSeedB: string;
+  nonce: number;
+  fairnessVersion: number;
+  beaconRound?: number;
+  beaconRandomness?: string;
   fightScript?: string;
   createdAt: string;
   finishedAt?: string;
+  receipt?: SignedReceipt;
+}
+
+// Settlement receipt signed by the server's ed25519 key over the compact JSON
+// of `receipt`. Keys are published at /.well-known/gamblefights-keys.
+export interface SignedReceipt {
+  receipt: Record<string, unknown>;
+  signature: string;
+  keyId: string;
+  alg: string;
 }
 
 // API Functions
@@ -118,9 +140,13 @@ export async function getBalances(): Promise<{ balances: WalletBalance[] }> {
   return res.json();
 }
 
-export async function addTestDeposit(): Promise<{ message: string; amount: number; newBalance: number }> {
+// Pass the same idempotencyKey when retrying so the deposit is applied once
+export async function addTestDeposit(
+  idempotencyKey: string = crypto.randomUUID()
+): Promise<{ message: string; amount: number; newBalance: number }> {
   const res = await authFetch('/api/wallet/test-deposit', {
     method: 'POST',
+    headers: { 'Idempotency-Key': idempotencyKey },
   });
   if (!res.ok) {
     throw new Error('Failed to add test deposit');
@@ -128,6 +154,72 @@ export async function addTestDeposit(): Promise<{ message: string; amount: numbe
   return res.json();
 }
 
+export interface DepositAddress {
+  currency: string;
+  depositAddress: string;
+  memo?: string;
+  comment?: string;
+  mint?: string;         // SPL tokens such as USDT
+  tokenAccount?: string; // Associated token account of depositAddress
+  note: string;
+}
+
+// SOL and USDT deposits may need a memo and TON deposits a comment; show whichever is set
+export async function getDepositAddress(currency: string = 'SOL'): Promise<DepositAddress> {
+  const res = await authFetch('/api/wallet/deposit-address', {
+    method: 'POST',
+    body: JSON.stringify({ currency }),
+  });
+  if (!res.ok) {
+    const error = await res.json();
+    throw new Error(error.error || 'Failed to fetch deposit address');
+  }
+  return res.json();
+}
+
+export interface Withdrawal {
+  id: string;
+  userId: string;
+  currency: string;
+  amount: number;
+  amountDisplay: string;
+  destination: string;
+  status: 'PENDING' | 'PENDING_REVIEW' | 'APPROVED' | 'BROADCAST' | 'CONFIRMED' | 'FAILED';
+  txHash?: string;
+  reviewNote?: string;
+  failureReason?: string;
+  createdAt: string;
+  finishedAt?: string;
+}
+
+// Amount is in atomic units. Pass the same idempotencyKey when retrying so
+// funds are held once.
+export async function withdraw(
+  currency: string,
+  amount: number,
+  destination: string,
+  idempotencyKey: string = crypto.randomUUID()
+): Promise<Withdrawal> {
+  const res = await authFetch('/api/wallet/withdraw', {
+    method: 'POST',
+    headers: { 'Idempotency-Key': idempotencyKey },
+    body: JSON.stringify({ currency, amount, destination }),
+  });
+  if (!res.ok) {
+    const error = await res.json();
+    throw new Error(error.error || 'Withdrawal failed');
+  }
+  return res.json();
+}
+
+export async function getWithdrawals(): Promise<{ withdrawals: Withdrawal[] }> {
+  const res = await authFetch('/api/wallet/withdrawals');
+  if (!res.ok) {
+    throw new Error('Failed to fetch withdrawals');
+  }
+  return res.json();
+}
+
 export async function updateClientSeed(clientSeed: string): Promise<{ message: string; clientSeed: string }> {
   const res = await authFetch('/api/user/client-seed', {
     method: 'PUT',
@@ -163,6 +255,109 @@ export async function getMatch(id: string): Promise<Match> {
   return res.json();
 }
 
+// Rotates the seed pair a finished match drew from (player A's), so either
+// player can verify it
+export async function revealMatchSeed(id: string): Promise<{ revealedServerSeed: string; revealedServerSeedHashed: string }> {
+  const res = await authFetch(`/api/matches/${id}/reveal-seed`, { method: 'POST' });
+  if (!res.ok) {
+    const error = await res.json();
+    throw new Error(error.error || 'Failed to reveal server seed');
+  }
+  return res.json();
+}
+
+export interface VerificationCheck {
+  name: string;
+  passed: boolean;
+  skipped?: boolean;
+  detail: string;
+}
+
+export interface MatchVerification {
+  proof: Record<string, unknown>;
+  report: {
+    matchId: string;
+    fairnessVersion: number;
+    message: string;
+    outcomeHash: string;
+    decValue: number;
+    derivedWinner: string;
+    winnerMatches: boolean;
+    checks: VerificationCheck[];
+    passed: boolean;
+  };
+  winnerId: string;
+}
+
+export async function verifyMatch(id: string): Promise<MatchVerification> {
+  const res = await fetch(`${API_URL}/api/matches/${id}/verify`);
+  if (!res.ok) {
+    const error = await res.json();
+    throw new Error(error.error || 'Failed to verify match');
+  }
+  return res.json();
+}
+
+export interface WagerPreset {
+  amount: number;  // In atomic units
+  display: string; // e.g. "0.10 SOL"
+}
+
+export interface RakeTier {
+  minWager: number; // Per player, atomic units
+  bps: number;      // Basis points of the pot
+}
+
+export interface Currency {
+  code: string;
+  name: string;
+  decimals: number;
+  minWager: number;
+  maxWager: number;
+  defaultWager: number;
+  presets: WagerPreset[];
+  deposits: boolean;
+  withdrawals: boolean;
+  wagering: boolean;
+  rakeTiers: RakeTier[];
+  rakeCap?: number; // Most taken from one match
+}
+
+// Rake rate in basis points for a wager: that of the highest tier it reaches
+export function rakeBps(currency: Currency, wager: number): number {
+  let bps = 0;
+  for (const tier of currency.rakeTiers) {
+    if (wager >= tier.minWager) {
+      bps = tier.bps;
+    }
+  }
+  return bps;
+}
+
+export async function getCurrencies(): Promise<{ currencies: Currency[] }> {
+  const res = await fetch(`${API_URL}/api/currencies`);
+  if (!res.ok) {
+    throw new Error('Failed to fetch currencies');
+  }
+  return res.json();
+}
+
+// Format atomic units of a currency, e.g. 1500000 USDT units to "1.50 USDT"
+export function formatAmount(amount: number, currency: Currency): string {
+  const value = amount / 10 ** currency.decimals;
+  return value.toFixed(value !== 0 && Math.abs(value) < 0.01 ? 4 : 2) + ' ' + currency.code;
+}
+
+// Parse a decimal string into atomic units of a currency; NaN if invalid
+export function parseAmount(value: string, currency: Currency): number {
+  const match = value.trim().match(/^(\d*)(?:\.(\d*))?$/);
+  if (!match || (!match[1] && !match[2]) || (match[2] || '').length > currency.decimals) {
+    return NaN;
+  }
+  const frac = (match[2] || '').padEnd(currency.decimals, '0');
+  return Number((match[1] || '0') + frac);
+}
+
 // Format lamports to SOL
 export function formatSOL(lamports: number): string {
   const sol = lamports / 1_000_000_000;
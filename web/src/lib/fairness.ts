@@ -1,38 +1,90 @@
-
-
 /**
  * Calculates the game outcome based on seeds.
  * Returns { isPlayerAWin: boolean, hash: string }
- * NOTE: reliable crypto module is needed. In browser, use Web Crypto API or a library.
- * This example uses Node's crypto for simplicity if running SS, but for client-side we need Web Crypto.
+ *
+ * Verification only runs the WASM build of server/pkg/fairness, the same code
+ * the server derives matches with, for every fairness version it has used.
+ * There is deliberately no hand-written fallback: a port that drifts from the
+ * server would report wrong results with nothing to flag them. When the
+ * module cannot be loaded, verifyOutcome throws VerifierUnavailableError.
  */
 
-export async function verifyOutcome(serverSeed: string, clientSeed: string, nonce: number) {
-    const message = `${clientSeed}-${nonce}`;
-    const encoder = new TextEncoder();
-    const keyData = encoder.encode(serverSeed);
-    const msgData = encoder.encode(message);
-
-    const key = await crypto.subtle.importKey(
-        'raw',
-        keyData as BufferSource,
-        { name: 'HMAC', hash: 'SHA-256' },
-        false,
-        ['sign']
-    );
-
-    const signature = await crypto.subtle.sign('HMAC', key, msgData as BufferSource);
-    const hashArray = Array.from(new Uint8Array(signature));
-    const hashHex = hashArray.map(b => b.toString(16).padStart(2, '0')).join('');
-
-    // Logic: First 8 chars -> int -> % 2
-    const subHash = hashHex.substring(0, 8);
-    const decValue = parseInt(subHash, 16);
-    const isPlayerAWin = decValue % 2 === 0;
+import { loadFairnessWasm } from './fairnessWasm';
+import type { Match } from './api';
+
+export const CURRENT_FAIRNESS_VERSION = 1;
+
+export class VerifierUnavailableError extends Error {
+    constructor() {
+        super('Verifier unavailable: the fairness module could not be loaded');
+        this.name = 'VerifierUnavailableError';
+    }
+}
+
+export async function verifyOutcome(
+    serverSeed: string,
+    clientSeed: string,
+    nonce: number,
+    version: number = CURRENT_FAIRNESS_VERSION,
+    beacon?: string
+) {
+    const wasm = await loadFairnessWasm();
+    if (!wasm) {
+        throw new VerifierUnavailableError();
+    }
+
+    // clientSeed is already combined (clientSeedA-clientSeedB)
+    const result = JSON.parse(wasm.outcome(JSON.stringify({
+        version,
+        serverSeed,
+        clientSeed,
+        nonce,
+        beacon,
+    })));
+    if (result.error) {
+        throw new Error(result.error);
+    }
 
     return {
-        isPlayerAWin,
-        hash: hashHex,
-        decValue
+        isPlayerAWin: result.isPlayerAWin as boolean,
+        hash: result.hash as string,
+        decValue: result.decValue as number,
+        version
     };
 }
+
+export interface VerifyInputs {
+    serverSeed: string;
+    clientSeed: string; // Combined clientSeedA-clientSeedB
+    nonce: number;
+    version: number;
+    beacon: string;     // Beacon randomness, only used by beacon versions
+}
+
+/**
+ * The verifier inputs recorded on a match, as verifyOutcome expects them.
+ * The server seed is empty until the seed pair has been rotated.
+ */
+export function matchInputs(match: Match): VerifyInputs {
+    return {
+        serverSeed: match.serverSeed ?? '',
+        clientSeed: `${match.clientSeedA}-${match.clientSeedB}`,
+        nonce: match.nonce,
+        version: match.fairnessVersion || 1,
+        beacon: match.beaconRandomness ?? '',
+    };
+}
+
+/**
+ * Per-match client seed handshake: a fresh random seed is committed as
+ * SHA256(seed) and only revealed once both players have committed.
+ */
+export function generateClientSeed(): string {
+    const bytes = crypto.getRandomValues(new Uint8Array(16));
+    return Array.from(bytes).map(b => b.toString(16).padStart(2, '0')).join('');
+}
+
+export async function commitClientSeed(clientSeed: string): Promise<string> {
+    const digest = await crypto.subtle.digest('SHA-256', new TextEncoder().encode(clientSeed) as BufferSource);
+    return Array.from(new Uint8Array(digest)).map(b => b.toString(16).padStart(2, '0')).join('');
+}
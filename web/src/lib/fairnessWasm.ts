@@ -0,0 +1,62 @@
+/**
+ * Loads the WebAssembly build of server/pkg/fairness (see `make wasm`).
+ * Verification runs the exact Go code the server uses; without the module
+ * there is no verifier.
+ */
+
+export interface FairnessWasm {
+    version: number;
+    hashServerSeed: (seed: string) => string;
+    outcome: (inputJSON: string) => string;
+    fightScript: (inputJSON: string) => string;
+    verify: (proofJSON: string) => string;
+}
+
+interface GoRuntime {
+    importObject: WebAssembly.Imports;
+    run: (instance: WebAssembly.Instance) => Promise<void>;
+}
+
+declare global {
+    interface Window {
+        Go?: new () => GoRuntime;
+        gamblefightsFairness?: FairnessWasm;
+    }
+}
+
+let loading: Promise<FairnessWasm | null> | null = null;
+
+function loadScript(src: string): Promise<void> {
+    return new Promise((resolve, reject) => {
+        const script = document.createElement('script');
+        script.src = src;
+        script.onload = () => resolve();
+        script.onerror = () => reject(new Error(`Failed to load ${src}`));
+        document.head.appendChild(script);
+    });
+}
+
+async function instantiate(): Promise<FairnessWasm | null> {
+    if (typeof window === 'undefined') return null;
+
+    try {
+        if (!window.Go) {
+            await loadScript('/wasm_exec.js');
+        }
+        const go = new window.Go!();
+        const { instance } = await WebAssembly.instantiateStreaming(fetch('/fairness.wasm'), go.importObject);
+        go.run(instance);
+        return window.gamblefightsFairness ?? null;
+    } catch (err) {
+        console.warn('Fairness WASM unavailable, using JS verifier', err);
+        return null;
+    }
+}
+
+// Returns the WASM verifier, or null if it could not be loaded
+export function loadFairnessWasm(): Promise<FairnessWasm | null> {
+    if (!loading) {
+        loading = instantiate();
+    }
+    return loading;
+}